@@ -0,0 +1,54 @@
+package adaptor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/michaelyusak/go-auth/config"
+)
+
+type smtpMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSmtpMailer(config config.SmtpConfig) *smtpMailer {
+	var auth smtp.Auth
+	if config.Username != "" {
+		auth = smtp.PlainAuth("", config.Username, config.Password, config.Host)
+	}
+
+	return &smtpMailer{
+		addr: net.JoinHostPort(config.Host, config.Port),
+		auth: auth,
+		from: config.From,
+	}
+}
+
+// SendMail sends a plain text email. net/smtp has no context support, so
+// ctx is only checked before sending.
+func (m *smtpMailer) SendMail(ctx context.Context, to, subject, body string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var msg strings.Builder
+
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+	msg.WriteString(body)
+
+	err := smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(msg.String()))
+	if err != nil {
+		return fmt.Errorf("[adaptor][smtpMailer][SendMail][smtp.SendMail] Error: %w", err)
+	}
+
+	return nil
+}
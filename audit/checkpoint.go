@@ -0,0 +1,102 @@
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// checkpointer periodically signs the head of the auth event chain. The key
+// is kept out of the database, so someone able to rewrite the table cannot
+// recompute the chain up to a checkpoint without it being detected.
+type checkpointer struct {
+	transaction repository.Transaction
+	key         []byte
+	interval    time.Duration
+	log         *logrus.Logger
+}
+
+type CheckpointerOpt struct {
+	Transaction repository.Transaction
+	Key         string
+	Interval    time.Duration
+	Log         *logrus.Logger
+}
+
+func NewCheckpointer(opt CheckpointerOpt) *checkpointer {
+	return &checkpointer{
+		transaction: opt.Transaction,
+		key:         []byte(opt.Key),
+		interval:    opt.Interval,
+		log:         opt.Log,
+	}
+}
+
+// Run writes a checkpoint every interval until ctx ends.
+func (c *checkpointer) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := c.checkpoint(ctx)
+		if err != nil {
+			c.log.WithFields(logrus.Fields{
+				"error": err.Error(),
+			}).Error("[audit][checkpointer][Run][checkpoint]")
+		}
+	}
+}
+
+// checkpoint signs the current chain head unless it is already signed.
+func (c *checkpointer) checkpoint(ctx context.Context) error {
+	return c.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		head, err := repos.AuthEvent.GetChainHead(ctx)
+		if err != nil {
+			return fmt.Errorf("[audit][checkpointer][checkpoint][authEventRepo.GetChainHead] Error: %w", err)
+		}
+
+		if head == nil || head.Hash == "" {
+			return nil
+		}
+
+		latest, err := repos.AuthEvent.GetLatestCheckpoint(ctx)
+		if err != nil {
+			return fmt.Errorf("[audit][checkpointer][checkpoint][authEventRepo.GetLatestCheckpoint] Error: %w", err)
+		}
+
+		if latest != nil && latest.AuthEventId >= head.AuthEventId {
+			return nil
+		}
+
+		err = repos.AuthEvent.InsertCheckpoint(ctx, entity.AuthEventCheckpoint{
+			AuthEventId: head.AuthEventId,
+			Hash:        head.Hash,
+			Signature:   signCheckpoint(c.key, head.AuthEventId, head.Hash),
+		})
+		if err != nil {
+			return fmt.Errorf("[audit][checkpointer][checkpoint][authEventRepo.InsertCheckpoint] Error: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func signCheckpoint(key []byte, authEventId int64, hash string) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%d:%s", authEventId, hash)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
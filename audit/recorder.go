@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/repository"
+	"github.com/sirupsen/logrus"
+)
+
+type Recorder interface {
+	// Record writes an event that comes with no change to commit it with,
+	// such as a failed login.
+	Record(ctx context.Context, event entity.AuthEvent)
+	// RecordTx writes the event in the transaction of the change it records,
+	// so the two are committed together or not at all.
+	RecordTx(ctx context.Context, repos repository.TxRepositories, event entity.AuthEvent) error
+}
+
+// outboxRecorder writes auth events to the outbox, from which the relay
+// appends them to the hash chain in the background. Recording is a single
+// insert: the request path neither waits for the chain lock nor retries.
+type outboxRecorder struct {
+	authEventRepo repository.AuthEventRepository
+	log           *logrus.Logger
+}
+
+type OutboxRecorderOpt struct {
+	AuthEventRepo repository.AuthEventRepository
+	Log           *logrus.Logger
+}
+
+func NewOutboxRecorder(opt OutboxRecorderOpt) *outboxRecorder {
+	return &outboxRecorder{
+		authEventRepo: opt.AuthEventRepo,
+		log:           opt.Log,
+	}
+}
+
+func (r *outboxRecorder) Record(ctx context.Context, event entity.AuthEvent) {
+	event = fillEvent(ctx, event)
+
+	err := r.authEventRepo.QueueEvent(ctx, event)
+	if err != nil {
+		// The event is logged in full so it can still be recovered.
+		r.log.WithFields(logrus.Fields{
+			"error":            err.Error(),
+			"event_type":       event.EventType,
+			"account_id":       event.AccountId,
+			"device_id":        event.DeviceId,
+			"actor_account_id": event.ActorAccountId,
+			"ip_address":       event.IpAddress,
+			"user_agent":       event.UserAgent,
+			"request_id":       event.RequestId,
+			"outcome":          event.Outcome,
+			"reason":           event.Reason,
+			"created_at":       event.CreatedAt,
+		}).Error("[audit][outboxRecorder][Record][authEventRepo.QueueEvent] auth event lost")
+	}
+}
+
+func (r *outboxRecorder) RecordTx(ctx context.Context, repos repository.TxRepositories, event entity.AuthEvent) error {
+	err := repos.AuthEvent.QueueEvent(ctx, fillEvent(ctx, event))
+	if err != nil {
+		return fmt.Errorf("[audit][outboxRecorder][RecordTx][authEventRepo.QueueEvent] Error: %w", err)
+	}
+
+	return nil
+}
+
+// fillEvent fills in the request id, client IP, user agent and acting admin
+// from ctx when the event does not carry them.
+func fillEvent(ctx context.Context, event entity.AuthEvent) entity.AuthEvent {
+	if event.RequestId == "" {
+		event.RequestId, _ = ctx.Value(constant.RequestIdCtxKey).(string)
+	}
+	if event.IpAddress == "" {
+		event.IpAddress, _ = ctx.Value(constant.ClientIpCtxKey).(string)
+	}
+	if event.UserAgent == "" {
+		event.UserAgent, _ = ctx.Value(constant.UserAgentCtxKey).(string)
+	}
+	if event.ActorAccountId == nil {
+		if actorAccountId, ok := ctx.Value(constant.ActorAccountIdCtxKey).(int64); ok {
+			event.ActorAccountId = &actorAccountId
+		}
+	}
+	if event.CreatedAt == 0 {
+		event.CreatedAt = time.Now().UnixMilli()
+	}
+
+	return event
+}
@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/michaelyusak/go-auth/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// relay appends the events waiting in the outbox to the hash chain. Events
+// that fail to be appended stay in the outbox and are tried again on the
+// next run. Its transaction must be READ COMMITTED, see InsertEvent.
+type relay struct {
+	transaction repository.Transaction
+	interval    time.Duration
+	batchSize   int
+	log         *logrus.Logger
+}
+
+type RelayOpt struct {
+	Transaction repository.Transaction
+	Interval    time.Duration
+	BatchSize   int
+	Log         *logrus.Logger
+}
+
+func NewRelay(opt RelayOpt) *relay {
+	return &relay{
+		transaction: opt.Transaction,
+		interval:    opt.Interval,
+		batchSize:   opt.BatchSize,
+		log:         opt.Log,
+	}
+}
+
+// Run flushes the outbox every interval until ctx ends.
+func (r *relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := r.Flush(ctx)
+		if err != nil {
+			r.log.WithFields(logrus.Fields{
+				"error": err.Error(),
+			}).Error("[audit][relay][Run][Flush]")
+		}
+	}
+}
+
+// Flush appends the queued events to the chain, batchSize per transaction,
+// until the outbox is empty.
+func (r *relay) Flush(ctx context.Context) error {
+	for {
+		var appended int
+
+		err := r.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+			var err error
+
+			appended, err = repos.AuthEvent.AppendQueuedEvents(ctx, r.batchSize)
+			if err != nil {
+				return fmt.Errorf("[audit][relay][Flush][authEventRepo.AppendQueuedEvents] Error: %w", err)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if appended < r.batchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"fmt"
+
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/repository"
+)
+
+const verifyBatchSize = 1000
+
+// BrokenLink is the first place the auth event chain stops verifying.
+type BrokenLink struct {
+	AuthEventId int64
+	Reason      string
+}
+
+// VerifyReport summarizes a walk of the chain. Unchained counts the events
+// recorded before the log was chained, which cannot be verified.
+type VerifyReport struct {
+	Events      int64
+	Unchained   int64
+	Checkpoints int
+	Broken      *BrokenLink
+}
+
+type Verifier struct {
+	authEventRepo repository.AuthEventRepository
+	key           []byte
+}
+
+func NewVerifier(authEventRepo repository.AuthEventRepository, key string) *Verifier {
+	return &Verifier{
+		authEventRepo: authEventRepo,
+		key:           []byte(key),
+	}
+}
+
+// Verify walks every auth event in order, checking that each links to the
+// one before it and that its hash matches its contents, and that every
+// checkpoint is validly signed and matches the event it points to. It stops
+// at the first broken link.
+func (v *Verifier) Verify(ctx context.Context) (*VerifyReport, error) {
+	checkpoints, err := v.authEventRepo.GetCheckpoints(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("[audit][Verifier][Verify][authEventRepo.GetCheckpoints] Error: %w", err)
+	}
+
+	report := &VerifyReport{
+		Checkpoints: len(checkpoints),
+	}
+
+	var (
+		afterId  int64
+		prevHash string
+		chained  bool
+	)
+
+	next := 0
+
+	for {
+		events, err := v.authEventRepo.GetEventsAfter(ctx, afterId, verifyBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("[audit][Verifier][Verify][authEventRepo.GetEventsAfter] Error: %w", err)
+		}
+
+		for _, event := range events {
+			for ; next < len(checkpoints) && checkpoints[next].AuthEventId <= event.AuthEventId; next++ {
+				report.Broken = v.verifyCheckpoint(checkpoints[next], event)
+				if report.Broken != nil {
+					return report, nil
+				}
+			}
+
+			report.Events++
+
+			if !chained && event.Hash == "" {
+				report.Unchained++
+			} else {
+				chained = true
+
+				report.Broken = verifyLink(prevHash, event)
+				if report.Broken != nil {
+					return report, nil
+				}
+			}
+
+			prevHash = event.Hash
+			afterId = event.AuthEventId
+		}
+
+		if len(events) < verifyBatchSize {
+			break
+		}
+	}
+
+	if next < len(checkpoints) {
+		report.Broken = &BrokenLink{
+			AuthEventId: checkpoints[next].AuthEventId,
+			Reason:      fmt.Sprintf("checkpoint %d points to a missing event", checkpoints[next].CheckpointId),
+		}
+	}
+
+	return report, nil
+}
+
+func (v *Verifier) verifyCheckpoint(checkpoint entity.AuthEventCheckpoint, event entity.AuthEvent) *BrokenLink {
+	if !hmac.Equal([]byte(checkpoint.Signature), []byte(signCheckpoint(v.key, checkpoint.AuthEventId, checkpoint.Hash))) {
+		return &BrokenLink{
+			AuthEventId: checkpoint.AuthEventId,
+			Reason:      fmt.Sprintf("checkpoint %d has an invalid signature", checkpoint.CheckpointId),
+		}
+	}
+
+	if checkpoint.AuthEventId != event.AuthEventId {
+		return &BrokenLink{
+			AuthEventId: checkpoint.AuthEventId,
+			Reason:      fmt.Sprintf("checkpoint %d points to a missing event", checkpoint.CheckpointId),
+		}
+	}
+
+	if checkpoint.Hash != event.Hash {
+		return &BrokenLink{
+			AuthEventId: event.AuthEventId,
+			Reason:      fmt.Sprintf("event hash does not match checkpoint %d", checkpoint.CheckpointId),
+		}
+	}
+
+	return nil
+}
+
+func verifyLink(prevHash string, event entity.AuthEvent) *BrokenLink {
+	if event.PrevHash != prevHash {
+		return &BrokenLink{
+			AuthEventId: event.AuthEventId,
+			Reason:      "prev_hash does not match the hash of the event before it",
+		}
+	}
+
+	if event.Hash != repository.AuthEventHash(event.PrevHash, event) {
+		return &BrokenLink{
+			AuthEventId: event.AuthEventId,
+			Reason:      "hash does not match the event",
+		}
+	}
+
+	return nil
+}
@@ -2,10 +2,12 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/helper"
 	hHelper "github.com/michaelyusak/go-helper/helper"
 	"github.com/sirupsen/logrus"
 )
@@ -18,21 +20,130 @@ type DBConfig struct {
 	DbName   string `json:"db_name"`
 }
 
+type TransactionConfig struct {
+	IsolationLevel  string          `json:"isolation_level"`
+	MaxRetries      int             `json:"max_retries"`
+	RetryBackoff    entity.Duration `json:"retry_backoff"`
+	MaxRetryBackoff entity.Duration `json:"max_retry_backoff"`
+}
+
 type JwtConfig struct {
 	Secret               hHelper.JwtConfig `json:"secret"`
 	AccessTokenDuration  entity.Duration   `json:"access_token_duration"`
 	RefreshTokenDuration entity.Duration   `json:"refresh_token_duration"`
+	RefreshTokenPepper   string            `json:"refresh_token_pepper"`
+}
+
+type SessionConfig struct {
+	MaxPerAccount int  `json:"max_per_account"`
+	SingleSession bool `json:"single_session"`
+}
+
+type SmtpConfig struct {
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	From     string `json:"from"`
+}
+
+type NewDeviceConfig struct {
+	Notifier        string          `json:"notifier"`
+	WebhookUrl      string          `json:"webhook_url"`
+	WebhookTimeout  entity.Duration `json:"webhook_timeout"`
+	RequireApproval bool            `json:"require_approval"`
+	ApprovalTtl     entity.Duration `json:"approval_ttl"`
+	ApprovalUrl     string          `json:"approval_url"`
+}
+
+type ContactChangeConfig struct {
+	Notifier       string          `json:"notifier"`
+	WebhookUrl     string          `json:"webhook_url"`
+	WebhookTimeout entity.Duration `json:"webhook_timeout"`
+	CodeTtl        entity.Duration `json:"code_ttl"`
+	MaxAttempts    int             `json:"max_attempts"`
+	CancelUrl      string          `json:"cancel_url"`
+}
+
+type AccountDeletionConfig struct {
+	GracePeriod    entity.Duration `json:"grace_period"`
+	PurgeInterval  entity.Duration `json:"purge_interval"`
+	PurgeBatchSize int             `json:"purge_batch_size"`
+}
+
+type PasswordResetConfig struct {
+	Notifier       string          `json:"notifier"`
+	WebhookUrl     string          `json:"webhook_url"`
+	WebhookTimeout entity.Duration `json:"webhook_timeout"`
+	TokenTtl       entity.Duration `json:"token_ttl"`
+	ResetUrl       string          `json:"reset_url"`
+}
+
+type InvitationConfig struct {
+	Notifier       string          `json:"notifier"`
+	WebhookUrl     string          `json:"webhook_url"`
+	WebhookTimeout entity.Duration `json:"webhook_timeout"`
+	TokenTtl       entity.Duration `json:"token_ttl"`
+	AcceptUrl      string          `json:"accept_url"`
+}
+
+type OAuthConfig struct {
+	LoginUrl string          `json:"login_url"`
+	CodeTtl  entity.Duration `json:"code_ttl"`
+}
+
+type DeviceConfig struct {
+	TokenSecret  string          `json:"token_secret"`
+	TokenMaxAge  entity.Duration `json:"token_max_age"`
+	SecureCookie bool            `json:"secure_cookie"`
+	LegacyHash   bool            `json:"legacy_hash"`
+}
+
+type AuditConfig struct {
+	RelayInterval      entity.Duration `json:"relay_interval"`
+	RelayBatchSize     int             `json:"relay_batch_size"`
+	CheckpointKey      string          `json:"checkpoint_key"`
+	CheckpointInterval entity.Duration `json:"checkpoint_interval"`
+}
+
+// TenantConfig describes a tenant: the hosts and client ids its requests
+// are recognised by, and the settings it overrides. Settings left out fall
+// back to the service wide ones.
+type TenantConfig struct {
+	Id                   string                 `json:"id"`
+	Hosts                []string               `json:"hosts"`
+	ClientIds            []string               `json:"client_ids"`
+	PasswordPolicy       *helper.PasswordPolicy `json:"password_policy"`
+	AccessTokenDuration  *entity.Duration       `json:"access_token_duration"`
+	RefreshTokenDuration *entity.Duration       `json:"refresh_token_duration"`
+	AllowedOrigins       []string               `json:"allowed_origins"`
 }
 
 type ServiceConfig struct {
-	Port                     string             `json:"port"`
-	GracefulPeriod           entity.Duration    `json:"graceful_period"`
-	ContextTimeout           entity.Duration    `json:"context_timeout"`
-	SubRoutineContextTimeout entity.Duration    `json:"sub_routine_context_timeout"`
-	Postgres                 DBConfig           `json:"postgres"`
-	Jwt                      JwtConfig          `json:"jwt"`
-	Hash                     hHelper.HashConfig `json:"hash"`
-	AllowedOrigins           []string           `json:"allowed_origins"`
+	Port                     string                 `json:"port"`
+	GracefulPeriod           entity.Duration        `json:"graceful_period"`
+	ContextTimeout           entity.Duration        `json:"context_timeout"`
+	SubRoutineContextTimeout entity.Duration        `json:"sub_routine_context_timeout"`
+	Postgres                 DBConfig               `json:"postgres"`
+	Transaction              TransactionConfig      `json:"transaction"`
+	Jwt                      JwtConfig              `json:"jwt"`
+	Session                  SessionConfig          `json:"session"`
+	Device                   DeviceConfig           `json:"device"`
+	NewDevice                NewDeviceConfig        `json:"new_device"`
+	ContactChange            ContactChangeConfig    `json:"contact_change"`
+	AccountDeletion          AccountDeletionConfig  `json:"account_deletion"`
+	PasswordReset            PasswordResetConfig    `json:"password_reset"`
+	Invitation               InvitationConfig       `json:"invitation"`
+	OAuth                    OAuthConfig            `json:"oauth"`
+	Smtp                     SmtpConfig             `json:"smtp"`
+	Audit                    AuditConfig            `json:"audit"`
+	Hash                     hHelper.HashConfig     `json:"hash"`
+	PasswordPolicy           *helper.PasswordPolicy `json:"password_policy"`
+	AllowedOrigins           []string               `json:"allowed_origins"`
+	TrustedProxies           []string               `json:"trusted_proxies"`
+	AdminAccountIds          []int64                `json:"admin_account_ids"`
+	Tenants                  []TenantConfig         `json:"tenants"`
+	AutoMigrate              bool                   `json:"auto_migrate"`
 }
 
 func Init(log *logrus.Logger) ServiceConfig {
@@ -58,5 +169,45 @@ func Init(log *logrus.Logger) ServiceConfig {
 		return config
 	}
 
+	err = config.validate()
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error": fmt.Sprintf("[config][Init][config.validate] error: %s", err.Error()),
+		}).Fatal("error initiating config file")
+
+		return config
+	}
+
 	return config
 }
+
+// validate refuses settings the service cannot run with, rather than
+// letting the background jobs panic or silently do nothing.
+func (c ServiceConfig) validate() error {
+	if c.AccountDeletion.PurgeInterval <= 0 {
+		return errors.New("account_deletion.purge_interval must be positive")
+	}
+
+	if c.AccountDeletion.PurgeBatchSize <= 0 {
+		return errors.New("account_deletion.purge_batch_size must be positive")
+	}
+
+	if c.Audit.RelayInterval <= 0 {
+		return errors.New("audit.relay_interval must be positive")
+	}
+
+	if c.Audit.RelayBatchSize <= 0 {
+		return errors.New("audit.relay_batch_size must be positive")
+	}
+
+	// Checkpoints signed with an empty key can be forged by anyone.
+	if c.Audit.CheckpointKey == "" {
+		return errors.New("audit.checkpoint_key must be set")
+	}
+
+	if c.Audit.CheckpointInterval <= 0 {
+		return errors.New("audit.checkpoint_interval must be positive")
+	}
+
+	return nil
+}
@@ -0,0 +1,9 @@
+package constant
+
+const (
+	AccountStatusPendingVerification = "pending_verification"
+	AccountStatusActive              = "active"
+	AccountStatusLocked              = "locked"
+	AccountStatusSuspended           = "suspended"
+	AccountStatusDisabled            = "disabled"
+)
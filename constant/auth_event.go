@@ -0,0 +1,71 @@
+package constant
+
+const (
+	// Auth event type
+	AuthEventRegister                  = "register"
+	AuthEventLogin                     = "login"
+	AuthEventTokenRefresh              = "token_refresh"
+	AuthEventSessionRevoked            = "session_revoked"
+	AuthEventDeviceRevoked             = "device_revoked"
+	AuthEventDeviceApproved            = "device_approved"
+	AuthEventProfileUpdated            = "profile_updated"
+	AuthEventContactChange             = "contact_change"
+	AuthEventAccountDeleted            = "account_deleted"
+	AuthEventAccountRestored           = "account_restored"
+	AuthEventAccountPurged             = "account_purged"
+	AuthEventAccountStatus             = "account_status_changed"
+	AuthEventPasswordReset             = "password_reset"
+	AuthEventRoleAssigned              = "role_assigned"
+	AuthEventRoleUnassigned            = "role_unassigned"
+	AuthEventRoleCreated               = "role_created"
+	AuthEventRoleUpdated               = "role_updated"
+	AuthEventRoleDeleted               = "role_deleted"
+	AuthEventPermissionCreated         = "permission_created"
+	AuthEventPermissionDeleted         = "permission_deleted"
+	AuthEventOrganizationCreated       = "organization_created"
+	AuthEventOrganizationMemberAdded   = "organization_member_added"
+	AuthEventOrganizationMemberUpdated = "organization_member_role_updated"
+	AuthEventOrganizationMemberRemoved = "organization_member_removed"
+	AuthEventOrganizationInvited       = "organization_invitation_sent"
+	AuthEventOrganizationInviteRevoked = "organization_invitation_revoked"
+	AuthEventOrganizationSwitched      = "organization_switched"
+	AuthEventOAuthClientCreated        = "oauth_client_created"
+	AuthEventOAuthClientUpdated        = "oauth_client_updated"
+	AuthEventOAuthClientDeleted        = "oauth_client_deleted"
+	AuthEventOAuthAuthorized           = "oauth_authorized"
+	AuthEventOAuthTokenIssued          = "oauth_token_issued"
+
+	// Auth event outcome
+	AuthEventOutcomeSuccess = "success"
+	AuthEventOutcomeFailure = "failure"
+
+	// Auth event reason
+	AuthEventReasonInternalError      = "internal_error"
+	AuthEventReasonWeakPassword       = "weak_password"
+	AuthEventReasonEmailTaken         = "email_taken"
+	AuthEventReasonPhoneNumberTaken   = "phone_number_taken"
+	AuthEventReasonNameTaken          = "name_taken"
+	AuthEventReasonAccountNotFound    = "account_not_found"
+	AuthEventReasonInvalidCredentials = "invalid_credentials"
+	AuthEventReasonNewDevice          = "new_device"
+	AuthEventReasonDevicePending      = "device_pending_approval"
+	AuthEventReasonInvalidToken       = "invalid_token"
+	AuthEventReasonTokenExpired       = "token_expired"
+	AuthEventReasonTokenReused        = "token_reused"
+	AuthEventReasonSessionLimit       = "session_limit"
+	AuthEventReasonUserRequest        = "user_request"
+	AuthEventReasonApprovalLink       = "approval_link"
+	AuthEventReasonContactChanged     = "contact_changed"
+	AuthEventReasonRequested          = "requested"
+	AuthEventReasonConfirmed          = "confirmed"
+	AuthEventReasonCancelled          = "cancelled"
+	AuthEventReasonInvalidCode        = "invalid_code"
+	AuthEventReasonGracePeriodEnded   = "grace_period_ended"
+	AuthEventReasonAccountInactive    = "account_inactive"
+	AuthEventReasonAdminAction        = "admin_action"
+	AuthEventReasonResetRequired      = "password_reset_required"
+	AuthEventReasonNotMember          = "not_organization_member"
+	AuthEventReasonInvalidInvitation  = "invalid_invitation"
+
+	DefaultAuthEventPageSize = 20
+)
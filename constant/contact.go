@@ -0,0 +1,6 @@
+package constant
+
+const (
+	ContactTypeEmail       = "email"
+	ContactTypePhoneNumber = "phone_number"
+)
@@ -0,0 +1,7 @@
+package constant
+
+const (
+	ExportRedacted           = "[redacted]"
+	ExportAuthEventBatchSize = 500
+	ExportFileName           = "account-export"
+)
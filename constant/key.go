@@ -2,13 +2,41 @@ package constant
 
 const (
 	// Context key
-	UserAgentCtxKey  = userAgentKey("user-agent")
-	DeviceInfoCtxKey = deviceInfoKey("device-info")
+	UserAgentCtxKey     = userAgentKey("user-agent")
+	DeviceInfoCtxKey    = deviceInfoKey("device-info")
+	RequestIdCtxKey     = requestIdKey("request-id")
+	AccountIdCtxKey     = accountIdKey("account-id")
+	DeviceIdCtxKey      = deviceIdKey("device-id")
+	ClientIpCtxKey      = clientIpKey("client-ip")
+	DeviceKeyCtxKey     = deviceKeyKey("device-key")
+	DeviceDetailsCtxKey = deviceDetailsKey("device-details")
+	PermissionsCtxKey   = permissionsKey("permissions")
+	TenantCtxKey        = tenantKey("tenant")
+
+	// ActorAccountIdCtxKey is the admin acting on another account.
+	ActorAccountIdCtxKey = actorAccountIdKey("actor-account-id")
 
 	// Header key
-	UserAgentHeaderKey  = "User-Agent"
-	DeviceInfoHeaderKey = "Device-Info"
+	UserAgentHeaderKey     = "User-Agent"
+	DeviceInfoHeaderKey    = "Device-Info"
+	RequestIdHeaderKey     = "X-Request-Id"
+	AuthorizationHeaderKey = "Authorization"
+	DeviceTokenHeaderKey   = "X-Device-Token"
+	TenantIdHeaderKey      = "X-Tenant-Id"
+	ClientIdHeaderKey      = "X-Client-Id"
+
+	// Cookie name
+	DeviceTokenCookieName = "go_auth_device"
 )
 
 type userAgentKey string
 type deviceInfoKey string
+type requestIdKey string
+type accountIdKey string
+type deviceIdKey string
+type clientIpKey string
+type deviceKeyKey string
+type deviceDetailsKey string
+type actorAccountIdKey string
+type permissionsKey string
+type tenantKey string
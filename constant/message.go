@@ -1,7 +1,51 @@
 package constant
 
 const (
-	MsgInvalidPassword = "invalid password"
-	MsgAccountNotFound = "account not found"
-	MsgInvalidLogin    = "wrong email, name, or password"
+	MsgInvalidPassword        = "invalid password"
+	MsgAccountNotFound        = "account not found"
+	MsgInvalidLogin           = "wrong email, name, or password"
+	MsgInvalidRefreshToken    = "invalid refresh token"
+	MsgUnauthorized           = "unauthorized"
+	MsgDeviceNotFound         = "device not found"
+	MsgInvalidDeviceId        = "invalid device id"
+	MsgDeviceApprovalRequired = "new device requires approval, check your email"
+	MsgInvalidApprovalToken   = "invalid or expired approval token"
+	MsgForbidden              = "forbidden"
+	MsgInvalidCursor          = "invalid cursor"
+	MsgContactUnchanged       = "new value is the same as the current one"
+	MsgInvalidContactCode     = "invalid or expired confirmation code"
+	MsgInvalidCancelToken     = "invalid or expired cancel token"
+	MsgAccountPending         = "account is pending verification"
+	MsgAccountLocked          = "account is locked"
+	MsgAccountSuspended       = "account is suspended"
+	MsgAccountDisabled        = "account is disabled"
+	MsgInvalidAccountId       = "invalid account id"
+	MsgInvalidStatusChange    = "account status cannot be changed that way"
+	MsgAccountNotLocked       = "account is not locked"
+	MsgAccountNotSuspended    = "account is not suspended"
+	MsgPasswordResetRequired  = "password reset required, check your email"
+	MsgInvalidResetToken      = "invalid or expired password reset token"
+	MsgRoleNotFound           = "role not found"
+	MsgPermissionNotFound     = "permission not found"
+	MsgInvalidRoleId          = "invalid role id"
+	MsgInvalidPermissionId    = "invalid permission id"
+	MsgRoleNameTaken          = "role name already exists"
+	MsgPermissionNameTaken    = "permission name already exists"
+	MsgInvalidPermissionName  = "permission names cannot contain whitespace"
+	MsgUnknownPermission      = "unknown permission"
+	MsgBuiltinRole            = "built-in roles cannot be changed"
+	MsgBuiltinPermission      = "built-in permissions cannot be deleted"
+	MsgUnknownTenant          = "unknown tenant"
+	MsgOrganizationNotFound   = "organization not found"
+	MsgInvalidOrganizationId  = "invalid organization id"
+	MsgNotOrganizationMember  = "not a member of the organization"
+	MsgMemberNotFound         = "member not found"
+	MsgAlreadyMember          = "already a member of the organization"
+	MsgLastOwner              = "an organization must keep at least one owner"
+	MsgInvalidInvitationId    = "invalid invitation id"
+	MsgInvitationNotFound     = "invitation not found"
+	MsgInvalidInvitation      = "invalid or expired invitation"
+	MsgOAuthClientNotFound    = "oauth client not found"
+	MsgInvalidRedirectUri     = "redirect uri is not registered for the client"
+	MsgMalformedRedirectUri   = "redirect uris cannot contain whitespace or fragments"
 )
@@ -0,0 +1,17 @@
+package constant
+
+const (
+	OAuthResponseTypeCode       = "code"
+	OAuthGrantAuthorizationCode = "authorization_code"
+	OAuthGrantRefreshToken      = "refresh_token"
+	OAuthCodeChallengeS256      = "S256"
+	OAuthTokenTypeBearer        = "Bearer"
+
+	// OAuth error codes, RFC 6749 section 4.1.2.1 and 5.2
+	OAuthErrorInvalidRequest          = "invalid_request"
+	OAuthErrorInvalidClient           = "invalid_client"
+	OAuthErrorInvalidGrant            = "invalid_grant"
+	OAuthErrorUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthErrorUnsupportedResponseType = "unsupported_response_type"
+	OAuthErrorInvalidScope            = "invalid_scope"
+)
@@ -0,0 +1,8 @@
+package constant
+
+const (
+	// Organization member role, from most to least privileged.
+	OrganizationRoleOwner  = "owner"
+	OrganizationRoleAdmin  = "admin"
+	OrganizationRoleMember = "member"
+)
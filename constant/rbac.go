@@ -0,0 +1,14 @@
+package constant
+
+const (
+	// RoleAdmin is the built-in role holding every built-in permission.
+	RoleAdmin = "admin"
+
+	PermissionAccountsRead      = "accounts:read"
+	PermissionAccountsWrite     = "accounts:write"
+	PermissionAuthEventsRead    = "auth_events:read"
+	PermissionRolesRead         = "roles:read"
+	PermissionRolesWrite        = "roles:write"
+	PermissionOAuthClientsRead  = "oauth_clients:read"
+	PermissionOAuthClientsWrite = "oauth_clients:write"
+)
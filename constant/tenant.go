@@ -0,0 +1,5 @@
+package constant
+
+// DefaultTenantId is the tenant of requests that name no tenant, and of
+// every account created before tenants were introduced.
+const DefaultTenantId = "default"
@@ -1,28 +1,93 @@
 package entity
 
+// Account doubles as the registration request. An InvitationToken given on
+// registration joins the organization the email was invited to.
 type Account struct {
-	Id          int64  `json:"id,omitempty"`
-	Name        string `json:"name" binding:"required"`
-	Email       string `json:"email" binding:"required,email"`
-	PhoneNumber string `json:"phone_number" binding:"required"`
-	Password    string `json:"password,omitempty" binding:"required"`
-	CreatedAt   int64  `json:"-"`
-	UpdatedAt   int64  `json:"-"`
-	DeletedAt   *int64 `json:"-"`
+	Id                     int64  `json:"id,omitempty"`
+	TenantId               string `json:"-"`
+	Name                   string `json:"name" binding:"required"`
+	Email                  string `json:"email" binding:"required,email"`
+	PhoneNumber            string `json:"phone_number" binding:"required"`
+	Password               string `json:"password,omitempty" binding:"required"`
+	InvitationToken        string `json:"invitation_token,omitempty"`
+	Status                 string `json:"-"`
+	StatusReason           string `json:"-"`
+	StatusUntil            *int64 `json:"-"`
+	PasswordResetRequired  bool   `json:"-"`
+	PasswordResetExpiredAt *int64 `json:"-"`
+	CreatedAt              int64  `json:"-"`
+	UpdatedAt              int64  `json:"-"`
+	DeletedAt              *int64 `json:"-"`
 }
 
-type LoginReq struct {
-	Name     string `json:"name"`
-	Email    string `json:"email" binding:"omitempty,email"`
+type AccountProfileRes struct {
+	Id          int64  `json:"id"`
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	PhoneNumber string `json:"phone_number"`
+	Status      string `json:"status"`
+	CreatedAt   int64  `json:"created_at"`
+	UpdatedAt   int64  `json:"updated_at"`
+}
+
+type UpdateProfileReq struct {
+	Name string `json:"name" binding:"required,max=64"`
+}
+
+// UpdateAccountStatusReq moves an account to another status. Until, in unix
+// millis, makes a lock or suspension lift by itself.
+type UpdateAccountStatusReq struct {
+	Status string `json:"status" binding:"required,oneof=pending_verification active locked suspended disabled"`
+	Reason string `json:"reason" binding:"max=255"`
+	Until  *int64 `json:"until"`
+}
+
+type AccountStatusRes struct {
+	AccountId    int64  `json:"account_id"`
+	Status       string `json:"status"`
+	StatusReason string `json:"status_reason"`
+	StatusUntil  *int64 `json:"status_until"`
+}
+
+// ResetPasswordReq completes a password reset forced by an admin, with the
+// token sent to the account's email.
+type ResetPasswordReq struct {
+	Token    string `json:"token" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
 
+// PasswordResetEvent carries the link sent out when an admin forces a
+// password reset.
+type PasswordResetEvent struct {
+	AccountId int64  `json:"account_id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	ResetUrl  string `json:"reset_url"`
+	ExpiredAt int64  `json:"expired_at"`
+}
+
+type DeleteAccountReq struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// LoginReq logs in with an email or name. A non zero OrganizationId issues
+// the tokens for that organization of the account.
+type LoginReq struct {
+	Name           string `json:"name"`
+	Email          string `json:"email" binding:"omitempty,email"`
+	Password       string `json:"password" binding:"required"`
+	OrganizationId int64  `json:"organization_id"`
+}
+
 type Token struct {
 	Token     string `json:"token"`
 	ExpiredAt int64  `json:"expired_at"`
 }
 
+// TokenData are the tokens of a session. Scope is only set for tokens
+// issued to an OAuth client.
 type TokenData struct {
-	AccessToken  Token `json:"access_token"`
-	RefreshToken Token `json:"refresh_token"`
+	AccessToken  Token  `json:"access_token"`
+	RefreshToken Token  `json:"refresh_token"`
+	Scope        string `json:"scope,omitempty"`
 }
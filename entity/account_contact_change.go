@@ -0,0 +1,58 @@
+package entity
+
+// AccountContactChange is a requested change of an account's email or phone
+// number. It only takes effect once the code sent to the new value is
+// confirmed, and can be cancelled from the old one. Only hashes of the code
+// and cancel token are kept.
+type AccountContactChange struct {
+	ContactChangeId int64
+	AccountId       int64
+	ContactType     string
+	OldValue        string
+	NewValue        string
+	CodeHash        string
+	CancelTokenHash string
+	Attempts        int
+	ExpiredAt       int64
+	ConfirmedAt     *int64
+	CancelledAt     *int64
+	CreatedAt       int64
+	UpdatedAt       int64
+}
+
+type ChangeEmailReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ChangePhoneNumberReq struct {
+	PhoneNumber string `json:"phone_number" binding:"required"`
+}
+
+type ConfirmContactChangeReq struct {
+	ContactChangeId int64  `json:"contact_change_id" binding:"required"`
+	Code            string `json:"code" binding:"required"`
+}
+
+type CancelContactChangeReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ContactChangeRes struct {
+	ContactChangeId int64  `json:"contact_change_id"`
+	ContactType     string `json:"contact_type"`
+	NewValue        string `json:"new_value"`
+	ExpiredAt       int64  `json:"expired_at"`
+}
+
+// ContactChangeEvent carries what is sent out for a contact change: the
+// code to the new value and the cancel link to the old one.
+type ContactChangeEvent struct {
+	AccountId   int64  `json:"account_id"`
+	Name        string `json:"name"`
+	ContactType string `json:"contact_type"`
+	OldValue    string `json:"old_value"`
+	NewValue    string `json:"new_value"`
+	Code        string `json:"code"`
+	CancelUrl   string `json:"cancel_url"`
+	ExpiredAt   int64  `json:"expired_at"`
+}
@@ -1,12 +1,87 @@
 package entity
 
 type AccountDevice struct {
-	DeviceId   int64
-	AccountId  int64
-	DeviceHash string
-	UserAgent  string
-	DeviceInfo string
-	CreatedAt  int64
-	UpdatedAt  int64
-	DeletedAt  *int64
+	DeviceId          int64
+	AccountId         int64
+	DeviceHash        string
+	DeviceName        string
+	UserAgent         string
+	DeviceInfo        string
+	Browser           string
+	Os                string
+	DeviceType        string
+	Platform          string
+	OsVersion         string
+	AppVersion        string
+	DeviceModel       string
+	PushToken         string
+	CreatedIp         string
+	LastIp            string
+	LastSeenAt        int64
+	ApprovedAt        *int64
+	ApprovalTokenHash *string
+	ApprovalExpiredAt *int64
+	CreatedAt         int64
+	UpdatedAt         int64
+	DeletedAt         *int64
+}
+
+type AccountDeviceRes struct {
+	DeviceId    int64  `json:"device_id"`
+	DeviceName  string `json:"device_name"`
+	Browser     string `json:"browser"`
+	Os          string `json:"os"`
+	DeviceType  string `json:"device_type"`
+	Platform    string `json:"platform"`
+	OsVersion   string `json:"os_version"`
+	AppVersion  string `json:"app_version"`
+	DeviceModel string `json:"device_model"`
+	UserAgent   string `json:"user_agent"`
+	LastIp      string `json:"last_ip"`
+	IsCurrent   bool   `json:"is_current"`
+	IsApproved  bool   `json:"is_approved"`
+	LastSeenAt  int64  `json:"last_seen_at"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
+// DeviceInfo is the JSON object clients send in the Device-Info header.
+type DeviceInfo struct {
+	Platform    string `json:"platform" binding:"required,oneof=web ios android windows macos linux"`
+	OsVersion   string `json:"os_version" binding:"max=32"`
+	AppVersion  string `json:"app_version" binding:"max=32"`
+	DeviceModel string `json:"device_model" binding:"max=64"`
+	PushToken   string `json:"push_token" binding:"max=512"`
+}
+
+type RenameDeviceReq struct {
+	DeviceName string `json:"device_name" binding:"required,max=64"`
+}
+
+type ApproveDeviceReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// NewDeviceEvent describes a login from a device the account has not used
+// before. ApprovalUrl is set when the device is waiting for approval.
+type NewDeviceEvent struct {
+	AccountId   int64  `json:"account_id"`
+	Email       string `json:"email"`
+	Name        string `json:"name"`
+	DeviceId    int64  `json:"device_id"`
+	UserAgent   string `json:"user_agent"`
+	Browser     string `json:"browser"`
+	Os          string `json:"os"`
+	DeviceType  string `json:"device_type"`
+	Platform    string `json:"platform"`
+	DeviceModel string `json:"device_model"`
+	DeviceInfo  string `json:"device_info"`
+	IpAddress   string `json:"ip_address"`
+	ApprovalUrl string `json:"approval_url,omitempty"`
+	OccurredAt  int64  `json:"occurred_at"`
+}
+
+type UserAgentInfo struct {
+	Browser    string
+	Os         string
+	DeviceType string
 }
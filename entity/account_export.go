@@ -0,0 +1,50 @@
+package entity
+
+type AccountExportQuery struct {
+	Format string `form:"format" binding:"omitempty,oneof=json zip"`
+}
+
+// AccountExport is everything kept about an account, apart from its audit
+// events, which are streamed after it. Secrets are left out or redacted.
+type AccountExport struct {
+	ExportedAt int64                  `json:"exported_at"`
+	Account    AccountProfileRes      `json:"account"`
+	Mfa        AccountExportMfa       `json:"mfa"`
+	Devices    []AccountExportDevice  `json:"devices"`
+	Sessions   []AccountExportSession `json:"sessions"`
+}
+
+type AccountExportMfa struct {
+	Enrolled bool     `json:"enrolled"`
+	Methods  []string `json:"methods"`
+}
+
+type AccountExportDevice struct {
+	DeviceId    int64  `json:"device_id"`
+	DeviceName  string `json:"device_name"`
+	Browser     string `json:"browser"`
+	Os          string `json:"os"`
+	DeviceType  string `json:"device_type"`
+	Platform    string `json:"platform"`
+	OsVersion   string `json:"os_version"`
+	AppVersion  string `json:"app_version"`
+	DeviceModel string `json:"device_model"`
+	PushToken   string `json:"push_token"`
+	UserAgent   string `json:"user_agent"`
+	DeviceInfo  string `json:"device_info"`
+	CreatedIp   string `json:"created_ip"`
+	LastIp      string `json:"last_ip"`
+	ApprovedAt  *int64 `json:"approved_at"`
+	LastSeenAt  int64  `json:"last_seen_at"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
+type AccountExportSession struct {
+	SessionId  int64  `json:"session_id"`
+	DeviceId   int64  `json:"device_id"`
+	IpAddress  string `json:"ip_address"`
+	ExpiredAt  int64  `json:"expired_at"`
+	LastUsedAt int64  `json:"last_used_at"`
+	RevokedAt  *int64 `json:"revoked_at"`
+	CreatedAt  int64  `json:"created_at"`
+}
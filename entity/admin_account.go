@@ -0,0 +1,64 @@
+package entity
+
+// AccountFilter selects accounts that are not deleted, newest first. An
+// empty tenant or prefix does not filter; Before is the id cursor to
+// continue from.
+type AccountFilter struct {
+	TenantId          string
+	EmailPrefix       string
+	PhoneNumberPrefix string
+	NamePrefix        string
+	Before            int64
+	Limit             int
+}
+
+// AdminAccountQuery searches the accounts of the request's tenant by the
+// start of their email, phone number or name.
+type AdminAccountQuery struct {
+	Email       string `form:"email"`
+	PhoneNumber string `form:"phone_number"`
+	Name        string `form:"name"`
+	Cursor      string `form:"cursor"`
+	Limit       int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+type AdminAccountRes struct {
+	Id                    int64  `json:"id"`
+	TenantId              string `json:"tenant_id"`
+	Name                  string `json:"name"`
+	Email                 string `json:"email"`
+	PhoneNumber           string `json:"phone_number"`
+	Status                string `json:"status"`
+	StatusReason          string `json:"status_reason"`
+	StatusUntil           *int64 `json:"status_until"`
+	PasswordResetRequired bool   `json:"password_reset_required"`
+	CreatedAt             int64  `json:"created_at"`
+	UpdatedAt             int64  `json:"updated_at"`
+}
+
+type AdminAccountPage struct {
+	Accounts   []AdminAccountRes `json:"accounts"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+type AdminAccountDetailRes struct {
+	Account AdminAccountRes    `json:"account"`
+	Roles   []string           `json:"roles"`
+	Devices []AccountDeviceRes `json:"devices"`
+}
+
+// SuspendAccountReq suspends an account, until the given unix millis when
+// set, or until it is unsuspended.
+type SuspendAccountReq struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+	Until  *int64 `json:"until"`
+}
+
+type RevokeSessionsRes struct {
+	AccountId int64 `json:"account_id"`
+}
+
+type ForcePasswordResetRes struct {
+	AccountId int64 `json:"account_id"`
+	ExpiredAt int64 `json:"expired_at"`
+}
@@ -0,0 +1,85 @@
+package entity
+
+// AuthEvent is an audit record of an authentication related action. The
+// account and device are nil when they are not known, e.g. for a login with
+// an unknown email. ActorAccountId is the admin who acted on the account,
+// nil when the account holder acted themselves. Hash covers the record,
+// except the IP address and user agent, and PrevHash, the hash of the
+// record before it, chaining the log together.
+type AuthEvent struct {
+	AuthEventId    int64
+	EventType      string
+	AccountId      *int64
+	DeviceId       *int64
+	ActorAccountId *int64
+	IpAddress      string
+	UserAgent      string
+	RequestId      string
+	Outcome        string
+	Reason         string
+	PrevHash       string
+	Hash           string
+	CreatedAt      int64
+}
+
+// AuthEventCheckpoint is a signed record of the chain head at some point,
+// so the chain cannot be rewritten up to it without the signing key.
+type AuthEventCheckpoint struct {
+	CheckpointId int64
+	AuthEventId  int64
+	Hash         string
+	Signature    string
+	CreatedAt    int64
+}
+
+type AuthEventRes struct {
+	AuthEventId    int64  `json:"auth_event_id"`
+	EventType      string `json:"event_type"`
+	AccountId      *int64 `json:"account_id"`
+	DeviceId       *int64 `json:"device_id"`
+	ActorAccountId *int64 `json:"actor_account_id,omitempty"`
+	IpAddress      string `json:"ip_address"`
+	UserAgent      string `json:"user_agent"`
+	RequestId      string `json:"request_id"`
+	Outcome        string `json:"outcome"`
+	Reason         string `json:"reason"`
+	CreatedAt      int64  `json:"created_at"`
+}
+
+// AuthEventFilter selects audit events, newest first. Zero values do not
+// filter; Before is the id cursor to continue from. TenantId selects the
+// events of the tenant's accounts, as the subject or, failing one, the
+// actor.
+type AuthEventFilter struct {
+	TenantId       string
+	AccountId      *int64
+	ActorAccountId *int64
+	EventType      string
+	Outcome        string
+	IpAddress      string
+	From           int64
+	To             int64
+	Before         int64
+	Limit          int
+}
+
+type AuthEventQuery struct {
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+type AdminAuthEventQuery struct {
+	AuthEventQuery
+	AccountId      *int64 `form:"account_id"`
+	ActorAccountId *int64 `form:"actor_account_id"`
+	EventType      string `form:"event_type"`
+	Outcome        string `form:"outcome" binding:"omitempty,oneof=success failure"`
+	IpAddress      string `form:"ip_address"`
+	From           int64  `form:"from"`
+	To             int64  `form:"to"`
+}
+
+type AuthEventPage struct {
+	Events     []AuthEventRes `json:"events"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,19 @@
+package entity
+
+// AccessTokenClaims are the custom claims signed into access tokens. Scope
+// lists the permissions granted by the account's roles, space separated as
+// in OAuth 2.0, and Tenant is the tenant the account belongs to. OrgId and
+// OrgRole are set when the token was issued for one of the account's
+// organizations, and ClientId when it was issued to an OAuth client.
+type AccessTokenClaims struct {
+	AccountId int64    `json:"account_id"`
+	DeviceId  int64    `json:"device_id"`
+	Email     string   `json:"email"`
+	Name      string   `json:"name"`
+	Roles     []string `json:"roles"`
+	Scope     string   `json:"scope"`
+	Tenant    string   `json:"tenant"`
+	OrgId     int64    `json:"org_id,omitempty"`
+	OrgRole   string   `json:"org_role,omitempty"`
+	ClientId  string   `json:"client_id,omitempty"`
+}
@@ -0,0 +1,108 @@
+package entity
+
+// OAuthClient is an application registered to sign accounts in through
+// the OAuth authorization code flow. Public clients, such as single page
+// and mobile apps, have no secret and rely on PKCE alone.
+type OAuthClient struct {
+	ClientId     string
+	TenantId     string
+	Name         string
+	SecretHash   *string
+	RedirectUris []string
+	Scopes       []string
+	CreatedAt    int64
+	UpdatedAt    int64
+}
+
+type OAuthAuthorizationCode struct {
+	AuthorizationCodeId int64
+	CodeHash            string
+	ClientId            string
+	AccountId           int64
+	DeviceId            int64
+	RedirectUri         string
+	Scope               string
+	CodeChallenge       string
+	ExpiredAt           int64
+	UsedAt              *int64
+	CreatedAt           int64
+}
+
+// ClientGrant binds the tokens issued to an OAuth client. Their scope is
+// limited to the permissions in Scope the account holds.
+type ClientGrant struct {
+	ClientId string
+	Scope    []string
+}
+
+// CreateOAuthClientReq registers a client in the tenant of the request.
+// Confidential clients are given a secret.
+type CreateOAuthClientReq struct {
+	Name         string   `json:"name" binding:"required,max=128"`
+	RedirectUris []string `json:"redirect_uris" binding:"required,min=1,dive,url"`
+	Scopes       []string `json:"scopes" binding:"dive,required"`
+	Confidential bool     `json:"confidential"`
+}
+
+type UpdateOAuthClientReq struct {
+	Name         string   `json:"name" binding:"required,max=128"`
+	RedirectUris []string `json:"redirect_uris" binding:"required,min=1,dive,url"`
+	Scopes       []string `json:"scopes" binding:"dive,required"`
+}
+
+// OAuthClientRes describes a client. ClientSecret is only filled in when
+// the client is created.
+type OAuthClientRes struct {
+	ClientId     string   `json:"client_id"`
+	TenantId     string   `json:"tenant_id"`
+	Name         string   `json:"name"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	Confidential bool     `json:"confidential"`
+	RedirectUris []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+	CreatedAt    int64    `json:"created_at"`
+	UpdatedAt    int64    `json:"updated_at"`
+}
+
+// AuthorizeReq is the authorization request of RFC 6749 section 4.1.1 with
+// the PKCE parameters of RFC 7636.
+type AuthorizeReq struct {
+	ResponseType        string `form:"response_type" json:"response_type"`
+	ClientId            string `form:"client_id" json:"client_id" binding:"required"`
+	RedirectUri         string `form:"redirect_uri" json:"redirect_uri" binding:"required"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+}
+
+// AuthorizeRes is where the browser is to be sent next, back to the client
+// with either a code or an error.
+type AuthorizeRes struct {
+	RedirectUri string `json:"redirect_uri"`
+}
+
+// OAuthTokenReq is the token request of RFC 6749 sections 4.1.3 and 6.
+// Client credentials may also come in the Authorization header.
+type OAuthTokenReq struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectUri  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	ClientId     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+type OAuthTokenRes struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+type OAuthErrorRes struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
@@ -0,0 +1,98 @@
+package entity
+
+type Organization struct {
+	OrganizationId int64
+	TenantId       string
+	Name           string
+	CreatedBy      int64
+	CreatedAt      int64
+	UpdatedAt      int64
+}
+
+// OrganizationMember is an account's membership of an organization, with
+// the account's name and email.
+type OrganizationMember struct {
+	OrganizationId int64
+	AccountId      int64
+	Role           string
+	Name           string
+	Email          string
+	CreatedAt      int64
+	UpdatedAt      int64
+}
+
+// OrganizationMembership is an organization an account belongs to, with the
+// account's role in it.
+type OrganizationMembership struct {
+	Organization Organization
+	Role         string
+}
+
+type OrganizationInvitation struct {
+	InvitationId   int64
+	OrganizationId int64
+	Email          string
+	Role           string
+	TokenHash      string
+	InvitedBy      int64
+	ExpiredAt      int64
+	AcceptedBy     *int64
+	AcceptedAt     *int64
+	RevokedAt      *int64
+	CreatedAt      int64
+	UpdatedAt      int64
+}
+
+type CreateOrganizationReq struct {
+	Name string `json:"name" binding:"required,max=128"`
+}
+
+type OrganizationRes struct {
+	OrganizationId int64  `json:"organization_id"`
+	Name           string `json:"name"`
+	Role           string `json:"role"`
+	CreatedAt      int64  `json:"created_at"`
+}
+
+type OrganizationMemberRes struct {
+	AccountId int64  `json:"account_id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	JoinedAt  int64  `json:"joined_at"`
+}
+
+type UpdateMemberRoleReq struct {
+	Role string `json:"role" binding:"required,oneof=owner admin member"`
+}
+
+type InviteMemberReq struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=owner admin member"`
+}
+
+type OrganizationInvitationRes struct {
+	InvitationId   int64  `json:"invitation_id"`
+	OrganizationId int64  `json:"organization_id"`
+	Email          string `json:"email"`
+	Role           string `json:"role"`
+	InvitedBy      int64  `json:"invited_by"`
+	ExpiredAt      int64  `json:"expired_at"`
+	CreatedAt      int64  `json:"created_at"`
+}
+
+type AcceptInvitationReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// InvitationEvent carries the link sent out when someone is invited to an
+// organization.
+type InvitationEvent struct {
+	OrganizationId   int64  `json:"organization_id"`
+	OrganizationName string `json:"organization_name"`
+	InviterName      string `json:"inviter_name"`
+	Email            string `json:"email"`
+	Role             string `json:"role"`
+	AcceptUrl        string `json:"accept_url"`
+	ExpiredAt        int64  `json:"expired_at"`
+}
@@ -0,0 +1,34 @@
+package entity
+
+// RefreshToken is the server side record of an opaque refresh token. Only
+// the token hash is kept; the plaintext token is never persisted. Tokens
+// rotated from the same login share a FamilyId. Tokens issued to an OAuth
+// client carry its ClientId and the Scope it was granted.
+type RefreshToken struct {
+	RefreshTokenId int64
+	TokenHash      string
+	AccountId      int64
+	DeviceId       int64
+	FamilyId       string
+	OrganizationId *int64
+	ClientId       *string
+	Scope          *string
+	IpAddress      string
+	ExpiredAt      int64
+	LastUsedAt     int64
+	RevokedAt      *int64
+	CreatedAt      int64
+	UpdatedAt      int64
+}
+
+type RefreshTokenReq struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// SwitchOrganizationReq exchanges a refresh token for tokens issued for
+// another of the account's organizations, or for none with a zero
+// OrganizationId.
+type SwitchOrganizationReq struct {
+	RefreshToken   string `json:"refresh_token" binding:"required"`
+	OrganizationId int64  `json:"organization_id"`
+}
@@ -0,0 +1,66 @@
+package entity
+
+// Role and Permission belong to TenantId, except the builtin ones, which
+// belong to no tenant and are shared by all.
+type Role struct {
+	RoleId      int64
+	TenantId    string
+	Name        string
+	Description string
+	IsBuiltin   bool
+	CreatedAt   int64
+	UpdatedAt   int64
+}
+
+type Permission struct {
+	PermissionId int64
+	TenantId     string
+	Name         string
+	Description  string
+	IsBuiltin    bool
+	CreatedAt    int64
+	UpdatedAt    int64
+}
+
+// AccountGrants are the names of an account's roles and of the permissions
+// they add up to.
+type AccountGrants struct {
+	Roles       []string
+	Permissions []string
+}
+
+type RoleRes struct {
+	RoleId      int64    `json:"role_id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	IsBuiltin   bool     `json:"is_builtin"`
+	Permissions []string `json:"permissions"`
+	CreatedAt   int64    `json:"created_at"`
+	UpdatedAt   int64    `json:"updated_at"`
+}
+
+type PermissionRes struct {
+	PermissionId int64  `json:"permission_id"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	IsBuiltin    bool   `json:"is_builtin"`
+	CreatedAt    int64  `json:"created_at"`
+	UpdatedAt    int64  `json:"updated_at"`
+}
+
+type CreateRoleReq struct {
+	Name        string   `json:"name" binding:"required,max=64"`
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions"`
+}
+
+// UpdateRoleReq replaces the role's description and permissions.
+type UpdateRoleReq struct {
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions"`
+}
+
+type CreatePermissionReq struct {
+	Name        string `json:"name" binding:"required,max=64"`
+	Description string `json:"description" binding:"max=255"`
+}
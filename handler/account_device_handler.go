@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/service"
+	"github.com/michaelyusak/go-helper/apperror"
+	"github.com/michaelyusak/go-helper/helper"
+)
+
+type AccountDeviceHandler struct {
+	timeout              time.Duration
+	accountDeviceService service.AccountDeviceService
+}
+
+func NewAccountDeviceHandler(timeout time.Duration, accountDeviceService service.AccountDeviceService) *AccountDeviceHandler {
+	return &AccountDeviceHandler{
+		timeout:              timeout,
+		accountDeviceService: accountDeviceService,
+	}
+}
+
+func (h *AccountDeviceHandler) GetDevices(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.accountDeviceService.GetDevices(ctxWithTimeout)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *AccountDeviceHandler) RenameDevice(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	deviceId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidDeviceId,
+		}))
+		return
+	}
+
+	var req entity.RenameDeviceReq
+
+	err = ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	err = h.accountDeviceService.RenameDevice(ctxWithTimeout, deviceId, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, nil)
+}
+
+func (h *AccountDeviceHandler) DeleteDevice(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	deviceId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidDeviceId,
+		}))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	err = h.accountDeviceService.DeleteDevice(ctxWithTimeout, deviceId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, nil)
+}
+
+func (h *AccountDeviceHandler) ApproveDeviceByToken(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	var req entity.ApproveDeviceReq
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	err = h.accountDeviceService.ApproveDeviceByToken(ctxWithTimeout, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, nil)
+}
+
+func (h *AccountDeviceHandler) ApproveDevice(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	deviceId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidDeviceId,
+		}))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	err = h.accountDeviceService.ApproveDevice(ctxWithTimeout, deviceId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, nil)
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/service"
+)
+
+type AccountExportHandler struct {
+	timeout              time.Duration
+	accountExportService service.AccountExportService
+}
+
+func NewAccountExportHandler(timeout time.Duration, accountExportService service.AccountExportService) *AccountExportHandler {
+	return &AccountExportHandler{
+		timeout:              timeout,
+		accountExportService: accountExportService,
+	}
+}
+
+func (h *AccountExportHandler) ExportAccount(ctx *gin.Context) {
+	var query entity.AccountExportQuery
+
+	err := ctx.ShouldBindQuery(&query)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	w := &exportWriter{
+		ctx: ctx,
+		zip: query.Format == "zip",
+	}
+
+	err = h.accountExportService.ExportAccount(ctxWithTimeout, w)
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		// Once the download has started the error can no longer be sent;
+		// the client is left with a truncated file.
+		if w.started {
+			ctx.Abort()
+			return
+		}
+
+		ctx.Error(err)
+		return
+	}
+}
+
+// exportWriter sends the response headers, and opens the zip archive, only
+// when the export starts writing, so errors raised before that are still
+// rendered as regular error responses.
+type exportWriter struct {
+	ctx     *gin.Context
+	zip     bool
+	started bool
+	archive *zip.Writer
+	w       io.Writer
+}
+
+func (w *exportWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		err := w.start()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return w.w.Write(p)
+}
+
+func (w *exportWriter) start() error {
+	w.started = true
+
+	if !w.zip {
+		w.ctx.Header("Content-Type", "application/json")
+		w.ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, constant.ExportFileName))
+		w.w = w.ctx.Writer
+
+		return nil
+	}
+
+	w.ctx.Header("Content-Type", "application/zip")
+	w.ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, constant.ExportFileName))
+
+	w.archive = zip.NewWriter(w.ctx.Writer)
+
+	entry, err := w.archive.Create(constant.ExportFileName + ".json")
+	if err != nil {
+		return err
+	}
+
+	w.w = entry
+
+	return nil
+}
+
+// Close finishes the zip archive, if one was opened.
+func (w *exportWriter) Close() error {
+	if w.archive == nil {
+		return nil
+	}
+
+	return w.archive.Close()
+}
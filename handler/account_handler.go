@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -15,12 +16,14 @@ import (
 type AccountHandler struct {
 	timeout        time.Duration
 	accountService service.AccountService
+	deviceToken    DeviceTokenOpt
 }
 
-func NewAccountHandler(timeout time.Duration, accountService service.AccountService) *AccountHandler {
+func NewAccountHandler(timeout time.Duration, accountService service.AccountService, deviceToken DeviceTokenOpt) *AccountHandler {
 	return &AccountHandler{
 		timeout:        timeout,
 		accountService: accountService,
+		deviceToken:    deviceToken,
 	}
 }
 
@@ -47,17 +50,33 @@ func (h *AccountHandler) Register(ctx *gin.Context) {
 		return
 	}
 
+	deviceDetails, err := parseDeviceInfo(deviceInfo)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
 	var newAccount entity.Account
 
-	err := ctx.ShouldBindJSON(&newAccount)
+	err = ctx.ShouldBindJSON(&newAccount)
 	if err != nil {
 		ctx.Error(err)
 		return
 	}
 
+	deviceKey, err := resolveDeviceKey(ctx, h.deviceToken)
+	if err != nil {
+		ctx.Error(apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[account_handler][Register][resolveDeviceKey] Error: %s", err.Error()),
+		}))
+		return
+	}
+
 	c := helper.InjectValues(ctx.Request.Context(), map[any]any{
 		constant.UserAgentCtxKey: userAgent,
 		constant.DeviceInfoCtxKey: deviceInfo,
+		constant.DeviceDetailsCtxKey: deviceDetails,
+		constant.DeviceKeyCtxKey: deviceKey,
 	})
 
 	ctxWithTimeout, cancel := context.WithTimeout(c, h.timeout)
@@ -95,17 +114,33 @@ func (h *AccountHandler) Login(ctx *gin.Context) {
 		return
 	}
 
+	deviceDetails, err := parseDeviceInfo(deviceInfo)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
 	var req entity.LoginReq
 
-	err := ctx.ShouldBindJSON(&req)
+	err = ctx.ShouldBindJSON(&req)
 	if err != nil {
 		ctx.Error(err)
 		return
 	}
 
+	deviceKey, err := resolveDeviceKey(ctx, h.deviceToken)
+	if err != nil {
+		ctx.Error(apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[account_handler][Login][resolveDeviceKey] Error: %s", err.Error()),
+		}))
+		return
+	}
+
 	c := helper.InjectValues(ctx.Request.Context(), map[any]any{
 		constant.UserAgentCtxKey: userAgent,
 		constant.DeviceInfoCtxKey: deviceInfo,
+		constant.DeviceDetailsCtxKey: deviceDetails,
+		constant.DeviceKeyCtxKey: deviceKey,
 	})
 
 	ctxWithTimeout, cancel := context.WithTimeout(c, h.timeout)
@@ -119,3 +154,133 @@ func (h *AccountHandler) Login(ctx *gin.Context) {
 
 	helper.ResponseOK(ctx, data)
 }
+
+func (h *AccountHandler) RefreshToken(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	var req entity.RefreshTokenReq
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.accountService.RefreshToken(ctxWithTimeout, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *AccountHandler) SwitchOrganization(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	var req entity.SwitchOrganizationReq
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.accountService.SwitchOrganization(ctxWithTimeout, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *AccountHandler) GetProfile(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.accountService.GetProfile(ctxWithTimeout)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *AccountHandler) UpdateProfile(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	var req entity.UpdateProfileReq
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.accountService.UpdateProfile(ctxWithTimeout, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *AccountHandler) DeleteAccount(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	var req entity.DeleteAccountReq
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	err = h.accountService.DeleteAccount(ctxWithTimeout, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, nil)
+}
+
+func (h *AccountHandler) ResetPassword(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	var req entity.ResetPasswordReq
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	err = h.accountService.ResetPassword(ctxWithTimeout, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, nil)
+}
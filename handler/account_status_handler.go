@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/service"
+	"github.com/michaelyusak/go-helper/apperror"
+	"github.com/michaelyusak/go-helper/helper"
+)
+
+type AccountStatusHandler struct {
+	timeout              time.Duration
+	accountStatusService service.AccountStatusService
+}
+
+func NewAccountStatusHandler(timeout time.Duration, accountStatusService service.AccountStatusService) *AccountStatusHandler {
+	return &AccountStatusHandler{
+		timeout:              timeout,
+		accountStatusService: accountStatusService,
+	}
+}
+
+func (h *AccountStatusHandler) UpdateAccountStatus(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	accountId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidAccountId,
+		}))
+		return
+	}
+
+	var req entity.UpdateAccountStatusReq
+
+	err = ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.accountStatusService.UpdateAccountStatus(ctxWithTimeout, accountId, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/service"
+	"github.com/michaelyusak/go-helper/apperror"
+	"github.com/michaelyusak/go-helper/helper"
+)
+
+type AdminAccountHandler struct {
+	timeout             time.Duration
+	adminAccountService service.AdminAccountService
+}
+
+func NewAdminAccountHandler(timeout time.Duration, adminAccountService service.AdminAccountService) *AdminAccountHandler {
+	return &AdminAccountHandler{
+		timeout:             timeout,
+		adminAccountService: adminAccountService,
+	}
+}
+
+func (h *AdminAccountHandler) SearchAccounts(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	var query entity.AdminAccountQuery
+
+	err := ctx.ShouldBindQuery(&query)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.adminAccountService.SearchAccounts(ctxWithTimeout, query)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *AdminAccountHandler) GetAccount(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	accountId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidAccountId,
+		}))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.adminAccountService.GetAccount(ctxWithTimeout, accountId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *AdminAccountHandler) SuspendAccount(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	accountId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidAccountId,
+		}))
+		return
+	}
+
+	var req entity.SuspendAccountReq
+
+	err = ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.adminAccountService.SuspendAccount(ctxWithTimeout, accountId, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *AdminAccountHandler) UnsuspendAccount(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	accountId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidAccountId,
+		}))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.adminAccountService.UnsuspendAccount(ctxWithTimeout, accountId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *AdminAccountHandler) UnlockAccount(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	accountId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidAccountId,
+		}))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.adminAccountService.UnlockAccount(ctxWithTimeout, accountId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *AdminAccountHandler) RevokeSessions(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	accountId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidAccountId,
+		}))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.adminAccountService.RevokeSessions(ctxWithTimeout, accountId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *AdminAccountHandler) ForcePasswordReset(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	accountId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidAccountId,
+		}))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.adminAccountService.ForcePasswordReset(ctxWithTimeout, accountId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/service"
+	"github.com/michaelyusak/go-helper/helper"
+)
+
+type AuthEventHandler struct {
+	timeout          time.Duration
+	authEventService service.AuthEventService
+}
+
+func NewAuthEventHandler(timeout time.Duration, authEventService service.AuthEventService) *AuthEventHandler {
+	return &AuthEventHandler{
+		timeout:          timeout,
+		authEventService: authEventService,
+	}
+}
+
+func (h *AuthEventHandler) GetActivity(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	var query entity.AuthEventQuery
+
+	err := ctx.ShouldBindQuery(&query)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.authEventService.GetActivity(ctxWithTimeout, query)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *AuthEventHandler) SearchEvents(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	var query entity.AdminAuthEventQuery
+
+	err := ctx.ShouldBindQuery(&query)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.authEventService.SearchEvents(ctxWithTimeout, query)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/service"
+	"github.com/michaelyusak/go-helper/helper"
+)
+
+type ContactChangeHandler struct {
+	timeout              time.Duration
+	contactChangeService service.ContactChangeService
+}
+
+func NewContactChangeHandler(timeout time.Duration, contactChangeService service.ContactChangeService) *ContactChangeHandler {
+	return &ContactChangeHandler{
+		timeout:              timeout,
+		contactChangeService: contactChangeService,
+	}
+}
+
+func (h *ContactChangeHandler) RequestEmailChange(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	var req entity.ChangeEmailReq
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.contactChangeService.RequestEmailChange(ctxWithTimeout, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *ContactChangeHandler) RequestPhoneNumberChange(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	var req entity.ChangePhoneNumberReq
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.contactChangeService.RequestPhoneNumberChange(ctxWithTimeout, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *ContactChangeHandler) ConfirmContactChange(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	var req entity.ConfirmContactChangeReq
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.contactChangeService.ConfirmContactChange(ctxWithTimeout, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *ContactChangeHandler) CancelContactChange(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	var req entity.CancelContactChangeReq
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	err = h.contactChangeService.CancelContactChange(ctxWithTimeout, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, nil)
+}
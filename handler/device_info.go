@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"encoding/json"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-helper/apperror"
+)
+
+// parseDeviceInfo decodes and validates the Device-Info header, which must
+// be a JSON object matching entity.DeviceInfo.
+func parseDeviceInfo(header string) (entity.DeviceInfo, error) {
+	var deviceInfo entity.DeviceInfo
+
+	err := json.Unmarshal([]byte(header), &deviceInfo)
+	if err != nil {
+		return deviceInfo, apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: "Device-Info must be a JSON object",
+		})
+	}
+
+	err = binding.Validator.ValidateStruct(&deviceInfo)
+	if err != nil {
+		return deviceInfo, err
+	}
+
+	return deviceInfo, nil
+}
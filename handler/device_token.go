@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/helper"
+)
+
+type DeviceTokenOpt struct {
+	Signer       helper.DeviceTokenSigner
+	MaxAge       time.Duration
+	SecureCookie bool
+}
+
+// resolveDeviceKey returns the device key from the signed device token sent
+// as a cookie or X-Device-Token header. When there is no valid token a new
+// one is minted and handed back through both, so the client is seen as a new
+// device.
+func resolveDeviceKey(ctx *gin.Context, opt DeviceTokenOpt) (string, error) {
+	token, err := ctx.Cookie(constant.DeviceTokenCookieName)
+	if err != nil || token == "" {
+		token = ctx.Request.Header.Get(constant.DeviceTokenHeaderKey)
+	}
+
+	if token != "" {
+		if deviceKey, ok := opt.Signer.Verify(token); ok {
+			return deviceKey, nil
+		}
+	}
+
+	token, deviceKey, err := opt.Signer.Mint()
+	if err != nil {
+		return "", err
+	}
+
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(constant.DeviceTokenCookieName, token, int(opt.MaxAge.Seconds()), "/", "", opt.SecureCookie, true)
+	ctx.Header(constant.DeviceTokenHeaderKey, token)
+
+	return deviceKey, nil
+}
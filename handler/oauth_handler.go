@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/service"
+	"github.com/michaelyusak/go-helper/helper"
+)
+
+type OAuthHandler struct {
+	timeout      time.Duration
+	oauthService service.OAuthService
+}
+
+func NewOAuthHandler(timeout time.Duration, oauthService service.OAuthService) *OAuthHandler {
+	return &OAuthHandler{
+		timeout:      timeout,
+		oauthService: oauthService,
+	}
+}
+
+func (h *OAuthHandler) GetClients(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.oauthService.GetClients(ctxWithTimeout)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *OAuthHandler) CreateClient(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	var req entity.CreateOAuthClientReq
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.oauthService.CreateClient(ctxWithTimeout, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *OAuthHandler) UpdateClient(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	var req entity.UpdateOAuthClientReq
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.oauthService.UpdateClient(ctxWithTimeout, ctx.Param("id"), req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *OAuthHandler) DeleteClient(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	err := h.oauthService.DeleteClient(ctxWithTimeout, ctx.Param("id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, nil)
+}
+
+// AuthorizeRedirect is where clients send the browser to. It redirects to
+// the login page, or back to the client when the request is invalid.
+func (h *OAuthHandler) AuthorizeRedirect(ctx *gin.Context) {
+	var req entity.AuthorizeReq
+
+	err := ctx.ShouldBindQuery(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	location, err := h.oauthService.LoginRedirect(ctxWithTimeout, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Redirect(http.StatusFound, location)
+}
+
+// Authorize is called by the login page once the account is signed in. It
+// answers with the URI to send the browser back to the client with.
+func (h *OAuthHandler) Authorize(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	var req entity.AuthorizeReq
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.oauthService.Authorize(ctxWithTimeout, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+// Token is the token endpoint of RFC 6749. Its requests are form encoded
+// and it answers in the format of the RFC rather than the service's own.
+func (h *OAuthHandler) Token(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+
+	var req entity.OAuthTokenReq
+
+	err := ctx.ShouldBind(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// Client credentials sent with basic authentication are form encoded
+	// before being base64 encoded, as per RFC 6749 section 2.3.1.
+	if clientId, clientSecret, ok := ctx.Request.BasicAuth(); ok {
+		req.ClientId, err = url.QueryUnescape(clientId)
+		if err == nil {
+			req.ClientSecret, err = url.QueryUnescape(clientSecret)
+		}
+		if err != nil {
+			oauthErrorResponse(ctx, &service.OAuthError{
+				Code:        constant.OAuthErrorInvalidClient,
+				Description: "malformed client credentials",
+			})
+			return
+		}
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.oauthService.Token(ctxWithTimeout, req)
+	if err != nil {
+		var oauthErr *service.OAuthError
+		if errors.As(err, &oauthErr) {
+			oauthErrorResponse(ctx, oauthErr)
+			return
+		}
+
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, data)
+}
+
+func oauthErrorResponse(ctx *gin.Context, oauthErr *service.OAuthError) {
+	status := http.StatusBadRequest
+
+	if oauthErr.Code == constant.OAuthErrorInvalidClient {
+		status = http.StatusUnauthorized
+		ctx.Header("WWW-Authenticate", "Basic")
+	}
+
+	ctx.AbortWithStatusJSON(status, entity.OAuthErrorRes{
+		Error:            oauthErr.Code,
+		ErrorDescription: oauthErr.Description,
+	})
+}
@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/service"
+	"github.com/michaelyusak/go-helper/apperror"
+	"github.com/michaelyusak/go-helper/helper"
+)
+
+type OrganizationHandler struct {
+	timeout             time.Duration
+	organizationService service.OrganizationService
+}
+
+func NewOrganizationHandler(timeout time.Duration, organizationService service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{
+		timeout:             timeout,
+		organizationService: organizationService,
+	}
+}
+
+func (h *OrganizationHandler) CreateOrganization(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	var req entity.CreateOrganizationReq
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.organizationService.CreateOrganization(ctxWithTimeout, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *OrganizationHandler) GetOrganizations(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.organizationService.GetOrganizations(ctxWithTimeout)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *OrganizationHandler) GetMembers(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	organizationId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidOrganizationId,
+		}))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.organizationService.GetMembers(ctxWithTimeout, organizationId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *OrganizationHandler) UpdateMemberRole(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	organizationId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidOrganizationId,
+		}))
+		return
+	}
+
+	accountId, err := strconv.ParseInt(ctx.Param("account_id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidAccountId,
+		}))
+		return
+	}
+
+	var req entity.UpdateMemberRoleReq
+
+	err = ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.organizationService.UpdateMemberRole(ctxWithTimeout, organizationId, accountId, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *OrganizationHandler) RemoveMember(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	organizationId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidOrganizationId,
+		}))
+		return
+	}
+
+	accountId, err := strconv.ParseInt(ctx.Param("account_id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidAccountId,
+		}))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	err = h.organizationService.RemoveMember(ctxWithTimeout, organizationId, accountId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, nil)
+}
+
+func (h *OrganizationHandler) InviteMember(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	organizationId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidOrganizationId,
+		}))
+		return
+	}
+
+	var req entity.InviteMemberReq
+
+	err = ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.organizationService.InviteMember(ctxWithTimeout, organizationId, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *OrganizationHandler) GetInvitations(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	organizationId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidOrganizationId,
+		}))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.organizationService.GetInvitations(ctxWithTimeout, organizationId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *OrganizationHandler) RevokeInvitation(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	organizationId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidOrganizationId,
+		}))
+		return
+	}
+
+	invitationId, err := strconv.ParseInt(ctx.Param("invitation_id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidInvitationId,
+		}))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	err = h.organizationService.RevokeInvitation(ctxWithTimeout, organizationId, invitationId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, nil)
+}
+
+func (h *OrganizationHandler) AcceptInvitation(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	var req entity.AcceptInvitationReq
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.organizationService.AcceptInvitation(ctxWithTimeout, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/service"
+	"github.com/michaelyusak/go-helper/apperror"
+	"github.com/michaelyusak/go-helper/helper"
+)
+
+type RoleHandler struct {
+	timeout     time.Duration
+	roleService service.RoleService
+}
+
+func NewRoleHandler(timeout time.Duration, roleService service.RoleService) *RoleHandler {
+	return &RoleHandler{
+		timeout:     timeout,
+		roleService: roleService,
+	}
+}
+
+func (h *RoleHandler) GetRoles(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.roleService.GetRoles(ctxWithTimeout)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *RoleHandler) CreateRole(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	var req entity.CreateRoleReq
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.roleService.CreateRole(ctxWithTimeout, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *RoleHandler) UpdateRole(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	roleId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidRoleId,
+		}))
+		return
+	}
+
+	var req entity.UpdateRoleReq
+
+	err = ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.roleService.UpdateRole(ctxWithTimeout, roleId, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *RoleHandler) DeleteRole(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	roleId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidRoleId,
+		}))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	err = h.roleService.DeleteRole(ctxWithTimeout, roleId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, nil)
+}
+
+func (h *RoleHandler) GetPermissions(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.roleService.GetPermissions(ctxWithTimeout)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *RoleHandler) CreatePermission(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	var req entity.CreatePermissionReq
+
+	err := ctx.ShouldBindJSON(&req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.roleService.CreatePermission(ctxWithTimeout, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *RoleHandler) DeletePermission(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	permissionId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidPermissionId,
+		}))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	err = h.roleService.DeletePermission(ctxWithTimeout, permissionId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, nil)
+}
+
+func (h *RoleHandler) GetAccountRoles(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	accountId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidAccountId,
+		}))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	data, err := h.roleService.GetAccountRoles(ctxWithTimeout, accountId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, data)
+}
+
+func (h *RoleHandler) AssignRole(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	accountId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidAccountId,
+		}))
+		return
+	}
+
+	roleId, err := strconv.ParseInt(ctx.Param("role_id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidRoleId,
+		}))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	err = h.roleService.AssignRole(ctxWithTimeout, accountId, roleId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, nil)
+}
+
+func (h *RoleHandler) UnassignRole(ctx *gin.Context) {
+	ctx.Header("Content-Type", "application/json")
+
+	accountId, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidAccountId,
+		}))
+		return
+	}
+
+	roleId, err := strconv.ParseInt(ctx.Param("role_id"), 10, 64)
+	if err != nil {
+		ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+			ResponseMessage: constant.MsgInvalidRoleId,
+		}))
+		return
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx.Request.Context(), h.timeout)
+	defer cancel()
+
+	err = h.roleService.UnassignRole(ctxWithTimeout, accountId, roleId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	helper.ResponseOK(ctx, nil)
+}
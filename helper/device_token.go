@@ -0,0 +1,57 @@
+package helper
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+const deviceKeyBytes = 32
+
+type DeviceTokenSigner interface {
+	Mint() (token string, deviceKey string, err error)
+	Verify(token string) (deviceKey string, ok bool)
+}
+
+// deviceTokenSigner issues long lived device tokens of the form
+// <device key>.<HMAC-SHA256 of the key>, so a device key cannot be made up
+// by a client.
+type deviceTokenSigner struct {
+	secret []byte
+}
+
+func NewDeviceTokenSigner(secret string) *deviceTokenSigner {
+	return &deviceTokenSigner{
+		secret: []byte(secret),
+	}
+}
+
+func (s *deviceTokenSigner) Mint() (string, string, error) {
+	deviceKey, err := GenerateOpaqueToken(deviceKeyBytes)
+	if err != nil {
+		return "", "", err
+	}
+
+	return deviceKey + "." + s.sign(deviceKey), deviceKey, nil
+}
+
+func (s *deviceTokenSigner) Verify(token string) (string, bool) {
+	deviceKey, signature, found := strings.Cut(token, ".")
+	if !found || deviceKey == "" {
+		return "", false
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(s.sign(deviceKey))) {
+		return "", false
+	}
+
+	return deviceKey, true
+}
+
+func (s *deviceTokenSigner) sign(deviceKey string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(deviceKey))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
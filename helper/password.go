@@ -2,7 +2,26 @@ package helper
 
 import "unicode"
 
-func ValidatePassword(password string) bool {
+// PasswordPolicy is what a password must contain to be accepted.
+type PasswordPolicy struct {
+	MinLength      int  `json:"min_length"`
+	RequireUpper   bool `json:"require_upper"`
+	RequireLower   bool `json:"require_lower"`
+	RequireNumber  bool `json:"require_number"`
+	RequireSpecial bool `json:"require_special"`
+}
+
+// DefaultPasswordPolicy asks for at least 8 characters mixing upper and
+// lower case letters, numbers and symbols.
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:      8,
+	RequireUpper:   true,
+	RequireLower:   true,
+	RequireNumber:  true,
+	RequireSpecial: true,
+}
+
+func (p PasswordPolicy) Validate(password string) bool {
 	var (
 		hasUpper   bool
 		hasLower   bool
@@ -10,7 +29,7 @@ func ValidatePassword(password string) bool {
 		hasSpecial bool
 	)
 
-	if len(password) < 8 {
+	if len(password) < p.MinLength {
 		return false
 	}
 
@@ -27,5 +46,8 @@ func ValidatePassword(password string) bool {
 		}
 	}
 
-	return hasUpper && hasLower && hasNumber && hasSpecial
+	return (hasUpper || !p.RequireUpper) &&
+		(hasLower || !p.RequireLower) &&
+		(hasNumber || !p.RequireNumber) &&
+		(hasSpecial || !p.RequireSpecial)
 }
@@ -0,0 +1,68 @@
+package helper
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+)
+
+type TokenHasher interface {
+	HashToken(token string) string
+}
+
+type tokenHasher struct {
+	pepper []byte
+}
+
+// NewTokenHasher returns a hasher for tokens stored server side. With a
+// pepper the hash is HMAC-SHA256 keyed by it, otherwise plain SHA-256.
+func NewTokenHasher(pepper string) *tokenHasher {
+	return &tokenHasher{
+		pepper: []byte(pepper),
+	}
+}
+
+func (h *tokenHasher) HashToken(token string) string {
+	if len(h.pepper) == 0 {
+		sum := sha256.Sum256([]byte(token))
+		return hex.EncodeToString(sum[:])
+	}
+
+	mac := hmac.New(sha256.New, h.pepper)
+	mac.Write([]byte(token))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateOpaqueToken returns a random, URL safe token with nBytes of
+// entropy.
+func GenerateOpaqueToken(nBytes int) (string, error) {
+	b := make([]byte, nBytes)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// GenerateNumericCode returns a random code of the given number of decimal
+// digits, for codes people type in.
+func GenerateNumericCode(digits int) (string, error) {
+	code := make([]byte, digits)
+
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+
+		code[i] = byte('0' + n.Int64())
+	}
+
+	return string(code), nil
+}
@@ -0,0 +1,83 @@
+package helper
+
+import (
+	"strings"
+
+	"github.com/michaelyusak/go-auth/entity"
+)
+
+const unknownUserAgentPart = "Unknown"
+
+type userAgentRule struct {
+	token string
+	name  string
+}
+
+// Order matters: Chromium based browsers also send "Chrome/" and "Safari/",
+// Android user agents also contain "Linux", and Android tablets are the ones
+// without "Mobile".
+var (
+	browserRules = []userAgentRule{
+		{token: "Edg/", name: "Edge"},
+		{token: "EdgA/", name: "Edge"},
+		{token: "EdgiOS/", name: "Edge"},
+		{token: "OPR/", name: "Opera"},
+		{token: "SamsungBrowser/", name: "Samsung Internet"},
+		{token: "CriOS/", name: "Chrome"},
+		{token: "Chrome/", name: "Chrome"},
+		{token: "FxiOS/", name: "Firefox"},
+		{token: "Firefox/", name: "Firefox"},
+		{token: "Safari/", name: "Safari"},
+	}
+
+	osRules = []userAgentRule{
+		{token: "Windows", name: "Windows"},
+		{token: "iPhone", name: "iOS"},
+		{token: "iPad", name: "iPadOS"},
+		{token: "iPod", name: "iOS"},
+		{token: "Android", name: "Android"},
+		{token: "CrOS", name: "ChromeOS"},
+		{token: "Mac OS X", name: "macOS"},
+		{token: "Macintosh", name: "macOS"},
+		{token: "Linux", name: "Linux"},
+	}
+
+	deviceTypeRules = []userAgentRule{
+		{token: "bot", name: "Bot"},
+		{token: "Bot", name: "Bot"},
+		{token: "crawler", name: "Bot"},
+		{token: "spider", name: "Bot"},
+		{token: "iPad", name: "Tablet"},
+		{token: "Tablet", name: "Tablet"},
+		{token: "Mobile", name: "Mobile"},
+		{token: "iPhone", name: "Mobile"},
+		{token: "iPod", name: "Mobile"},
+		{token: "Android", name: "Tablet"},
+		{token: "Windows", name: "Desktop"},
+		{token: "Macintosh", name: "Desktop"},
+		{token: "CrOS", name: "Desktop"},
+		{token: "X11", name: "Desktop"},
+		{token: "Linux", name: "Desktop"},
+	}
+)
+
+// ParseUserAgent classifies a User-Agent header into browser, operating
+// system and device type names. Parts that cannot be recognized are reported
+// as "Unknown".
+func ParseUserAgent(userAgent string) entity.UserAgentInfo {
+	return entity.UserAgentInfo{
+		Browser:    matchUserAgent(userAgent, browserRules),
+		Os:         matchUserAgent(userAgent, osRules),
+		DeviceType: matchUserAgent(userAgent, deviceTypeRules),
+	}
+}
+
+func matchUserAgent(userAgent string, rules []userAgentRule) string {
+	for _, rule := range rules {
+		if strings.Contains(userAgent, rule.token) {
+			return rule.name
+		}
+	}
+
+	return unknownUserAgentPart
+}
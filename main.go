@@ -1,7 +1,21 @@
 package main
 
-import "github.com/michaelyusak/go-auth/server"
+import (
+	"os"
+
+	"github.com/michaelyusak/go-auth/server"
+)
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		server.Migrate(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "verify-audit" {
+		server.VerifyAudit()
+		return
+	}
+
 	server.Init()
 }
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-helper/apperror"
+	hHelper "github.com/michaelyusak/go-helper/helper"
+)
+
+const bearerPrefix = "Bearer "
+
+// AccountStatusChecker fails for accounts that may not be used, e.g. deleted
+// or suspended ones.
+type AccountStatusChecker interface {
+	CheckAccountStatus(ctx context.Context, accountId int64) error
+}
+
+// AuthMiddleware verifies the bearer access token and puts the account and
+// device it was issued to, along with the permissions in its scope claim,
+// into the request context. The account's status is
+// checked on every request, so access tokens stop working as soon as the
+// account is suspended rather than when they expire.
+func AuthMiddleware(jwtHelper hHelper.JWTHelper, statusChecker AccountStatusChecker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authorization := ctx.Request.Header.Get(constant.AuthorizationHeaderKey)
+		if !strings.HasPrefix(authorization, bearerPrefix) {
+			ctx.Error(apperror.UnauthorizedError(apperror.AppErrorOpt{
+				Message:         "[middleware][AuthMiddleware] missing bearer token",
+				ResponseMessage: constant.MsgUnauthorized,
+			}))
+			ctx.Abort()
+			return
+		}
+
+		claims, err := parseAccessToken(jwtHelper, strings.TrimPrefix(authorization, bearerPrefix))
+		if err != nil {
+			ctx.Error(apperror.UnauthorizedError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("[middleware][AuthMiddleware][parseAccessToken] Error: %s", err.Error()),
+				ResponseMessage: constant.MsgUnauthorized,
+			}))
+			ctx.Abort()
+			return
+		}
+
+		// Tokens issued to OAuth clients are for the clients' own resource
+		// servers, not for this API.
+		if claims.ClientId != "" {
+			ctx.Error(apperror.UnauthorizedError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("[middleware][AuthMiddleware] token issued to an oauth client | account_id: %v | client_id: %s", claims.AccountId, claims.ClientId),
+				ResponseMessage: constant.MsgUnauthorized,
+			}))
+			ctx.Abort()
+			return
+		}
+
+		// A token only works for the tenant it was issued in.
+		tenantId, _ := ctx.Request.Context().Value(constant.TenantCtxKey).(string)
+		if claims.Tenant != tenantId {
+			ctx.Error(apperror.UnauthorizedError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("[middleware][AuthMiddleware] token issued for another tenant | account_id: %v | tenant: %s", claims.AccountId, claims.Tenant),
+				ResponseMessage: constant.MsgUnauthorized,
+			}))
+			ctx.Abort()
+			return
+		}
+
+		err = statusChecker.CheckAccountStatus(ctx.Request.Context(), claims.AccountId)
+		if err != nil {
+			ctx.Error(err)
+			ctx.Abort()
+			return
+		}
+
+		c := hHelper.InjectValues(ctx.Request.Context(), map[any]any{
+			constant.AccountIdCtxKey:   claims.AccountId,
+			constant.DeviceIdCtxKey:    claims.DeviceId,
+			constant.PermissionsCtxKey: strings.Fields(claims.Scope),
+		})
+		ctx.Request = ctx.Request.WithContext(c)
+
+		ctx.Next()
+	}
+}
+
+// RequirePermission only lets requests through whose access token grants
+// the permission, and puts the account into the request context as the
+// actor of the audit events recorded while serving the request. Permissions
+// come from the token, so role changes apply once a new token is issued. It
+// must be registered after AuthMiddleware.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		accountId, _ := ctx.Request.Context().Value(constant.AccountIdCtxKey).(int64)
+		permissions, _ := ctx.Request.Context().Value(constant.PermissionsCtxKey).([]string)
+
+		if !slices.Contains(permissions, permission) {
+			ctx.Error(apperror.NewAppError(apperror.AppErrorOpt{
+				Code:            http.StatusForbidden,
+				Message:         fmt.Sprintf("[middleware][RequirePermission] missing permission | account_id: %v | permission: %s", accountId, permission),
+				ResponseMessage: constant.MsgForbidden,
+			}))
+			ctx.Abort()
+			return
+		}
+
+		c := hHelper.InjectValues(ctx.Request.Context(), map[any]any{
+			constant.ActorAccountIdCtxKey: accountId,
+		})
+		ctx.Request = ctx.Request.WithContext(c)
+
+		ctx.Next()
+	}
+}
+
+func parseAccessToken(jwtHelper hHelper.JWTHelper, token string) (*entity.AccessTokenClaims, error) {
+	parsed, err := jwtHelper.ParseAndVerify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Round trip through JSON so the claims land in typed fields regardless
+	// of how the helper represents them.
+	b, err := json.Marshal(parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims entity.AccessTokenClaims
+
+	err = json.Unmarshal(b, &claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.AccountId == 0 {
+		return nil, fmt.Errorf("token has no account_id claim")
+	}
+
+	return &claims, nil
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/michaelyusak/go-auth/constant"
+)
+
+const (
+	forwardedHeaderKey    = "Forwarded"
+	forwardedForHeaderKey = "X-Forwarded-For"
+)
+
+// ClientIpMiddleware puts the caller's IP into the request context. The
+// Forwarded and X-Forwarded-For headers are only honoured when the request
+// comes from one of the trusted proxies, and are read from the right so a
+// client cannot pick its own address by prepending to them. Trusted proxies
+// are CIDRs or single IPs.
+func ClientIpMiddleware(trustedProxies []string) (gin.HandlerFunc, error) {
+	trusted, err := parsePrefixes(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx *gin.Context) {
+		clientIp := resolveClientIp(ctx, trusted)
+
+		c := context.WithValue(ctx.Request.Context(), constant.ClientIpCtxKey, clientIp)
+		ctx.Request = ctx.Request.WithContext(c)
+
+		ctx.Next()
+	}, nil
+}
+
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+			}
+
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", value, err)
+		}
+
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+func resolveClientIp(ctx *gin.Context, trusted []netip.Prefix) string {
+	remoteIp, ok := parseIp(ctx.RemoteIP())
+	if !ok {
+		return ctx.RemoteIP()
+	}
+
+	if !isTrusted(remoteIp, trusted) {
+		return remoteIp.String()
+	}
+
+	hops := forwardedHops(ctx)
+
+	clientIp := remoteIp
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip, ok := parseIp(hops[i])
+		if !ok {
+			break
+		}
+
+		clientIp = ip
+		if !isTrusted(ip, trusted) {
+			break
+		}
+	}
+
+	return clientIp.String()
+}
+
+// forwardedHops lists the addresses a request was forwarded for, from the
+// original client to the last proxy. Forwarded takes precedence over
+// X-Forwarded-For.
+func forwardedHops(ctx *gin.Context) []string {
+	hops := []string{}
+
+	if values := ctx.Request.Header.Values(forwardedHeaderKey); len(values) > 0 {
+		for _, element := range strings.Split(strings.Join(values, ","), ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if found && strings.EqualFold(key, "for") {
+					hops = append(hops, value)
+				}
+			}
+		}
+
+		return hops
+	}
+
+	for _, value := range ctx.Request.Header.Values(forwardedForHeaderKey) {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+
+	return hops
+}
+
+// parseIp accepts a bare address or one with a port, optionally quoted and
+// bracketed as in the Forwarded header. Obfuscated identifiers such as
+// "unknown" are rejected.
+func parseIp(value string) (netip.Addr, bool) {
+	value = strings.Trim(strings.TrimSpace(value), `"`)
+
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+
+	addr, err := netip.ParseAddr(strings.Trim(value, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
+
+func isTrusted(ip netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/michaelyusak/go-auth/constant"
+)
+
+// RequestIdContextMiddleware copies the request id assigned by the helper
+// RequestIdHandlerMiddleware into the request context, so services and
+// repositories can log it. It must be registered after that middleware.
+func RequestIdContextMiddleware(ctx *gin.Context) {
+	requestId := ctx.Writer.Header().Get(constant.RequestIdHeaderKey)
+	if requestId == "" {
+		requestId = ctx.Request.Header.Get(constant.RequestIdHeaderKey)
+	}
+
+	c := context.WithValue(ctx.Request.Context(), constant.RequestIdCtxKey, requestId)
+	ctx.Request = ctx.Request.WithContext(c)
+
+	ctx.Next()
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/tenant"
+	"github.com/michaelyusak/go-helper/apperror"
+)
+
+// TenantMiddleware puts the tenant the request is made for into the request
+// context, resolved from the X-Tenant-Id header, the X-Client-Id header or
+// the host. Requests naming a tenant or client that is not configured are
+// refused.
+func TenantMiddleware(tenants tenant.Registry) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		tenantIdHeader := ctx.Request.Header.Get(constant.TenantIdHeaderKey)
+		clientIdHeader := ctx.Request.Header.Get(constant.ClientIdHeaderKey)
+
+		tenantId, ok := tenants.Resolve(tenantIdHeader, clientIdHeader, ctx.Request.Host)
+		if !ok {
+			ctx.Error(apperror.BadRequestError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("[middleware][TenantMiddleware] unknown tenant | tenant_id: %s | client_id: %s", tenantIdHeader, clientIdHeader),
+				ResponseMessage: constant.MsgUnknownTenant,
+			}))
+			ctx.Abort()
+			return
+		}
+
+		c := context.WithValue(ctx.Request.Context(), constant.TenantCtxKey, tenantId)
+		ctx.Request = ctx.Request.WithContext(c)
+
+		ctx.Next()
+	}
+}
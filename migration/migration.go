@@ -0,0 +1,106 @@
+package migration
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+const (
+	upSuffix   = ".up.sql"
+	downSuffix = ".down.sql"
+)
+
+// Migration is a single versioned schema change. File names follow
+// <version>_<name>.up.sql and <version>_<name>.down.sql.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+func loadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("[migration][loadMigrations][fs.ReadDir] Error: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+
+	for _, entry := range entries {
+		fileName := entry.Name()
+
+		var (
+			base   string
+			isDown bool
+		)
+
+		switch {
+		case strings.HasSuffix(fileName, upSuffix):
+			base = strings.TrimSuffix(fileName, upSuffix)
+		case strings.HasSuffix(fileName, downSuffix):
+			base = strings.TrimSuffix(fileName, downSuffix)
+			isDown = true
+		default:
+			return nil, fmt.Errorf("[migration][loadMigrations] unexpected file: %s", fileName)
+		}
+
+		versionStr, name, found := strings.Cut(base, "_")
+		if !found {
+			return nil, fmt.Errorf("[migration][loadMigrations] missing migration name: %s", fileName)
+		}
+
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("[migration][loadMigrations][strconv.ParseInt] Error: %w | file: %s", err, fileName)
+		}
+
+		content, err := fs.ReadFile(files, path.Join("sql", fileName))
+		if err != nil {
+			return nil, fmt.Errorf("[migration][loadMigrations][fs.ReadFile] Error: %w | file: %s", err, fileName)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{
+				Version: version,
+				Name:    name,
+			}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != name {
+			return nil, fmt.Errorf("[migration][loadMigrations] conflicting names for version %d: %s and %s", version, migration.Name, name)
+		}
+
+		if isDown {
+			migration.Down = string(content)
+		} else {
+			migration.Up = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("[migration][loadMigrations] version %d must have both up and down files", migration.Version)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
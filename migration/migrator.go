@@ -0,0 +1,249 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// advisoryLockKey is the pg_advisory_lock key held while migrating so that
+// replicas starting at the same time do not apply migrations concurrently.
+const advisoryLockKey int64 = 7310598233411
+
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *int64
+}
+
+type Migrator struct {
+	db         *sql.DB
+	log        *logrus.Logger
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, log *logrus.Logger) (*Migrator, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		log:        log,
+		migrations: migrations,
+	}, nil
+}
+
+// Up applies every pending migration in version order and returns how many
+// were applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	var count int
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			err = m.apply(ctx, conn, migration, true)
+			if err != nil {
+				return err
+			}
+
+			count++
+		}
+
+		return nil
+	})
+
+	return count, err
+}
+
+// Down rolls back the last n applied migrations, newest first, and returns
+// how many were rolled back.
+func (m *Migrator) Down(ctx context.Context, n int) (int, error) {
+	var count int
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && count < n; i-- {
+			migration := m.migrations[i]
+
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+
+			err = m.apply(ctx, conn, migration, false)
+			if err != nil {
+				return err
+			}
+
+			count++
+		}
+
+		return nil
+	})
+
+	return count, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := Status{
+				Version: migration.Version,
+				Name:    migration.Name,
+			}
+
+			if appliedAt, ok := applied[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+			}
+
+			statuses = append(statuses, status)
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("[migration][withLock][db.Conn] Error: %w", err)
+	}
+	defer conn.Close()
+
+	// Session level advisory locks belong to the connection, so the lock,
+	// the migrations and the unlock must all run on the same conn.
+	_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockKey)
+	if err != nil {
+		return fmt.Errorf("[migration][withLock][pg_advisory_lock] Error: %w", err)
+	}
+
+	defer func() {
+		_, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, advisoryLockKey)
+		if err != nil {
+			m.log.WithFields(logrus.Fields{
+				"error": err.Error(),
+			}).Error("[migration][withLock][pg_advisory_unlock]")
+		}
+	}()
+
+	q := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR NOT NULL,
+			applied_at BIGINT NOT NULL
+		)
+	`
+
+	_, err = conn.ExecContext(ctx, q)
+	if err != nil {
+		return fmt.Errorf("[migration][withLock][ExecContext] create schema_migrations | Error: %w", err)
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]int64, error) {
+	q := `
+		SELECT version, applied_at
+		FROM schema_migrations
+	`
+
+	rows, err := conn.QueryContext(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("[migration][appliedVersions][QueryContext] Error: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]int64)
+
+	for rows.Next() {
+		var version, appliedAt int64
+
+		err = rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, fmt.Errorf("[migration][appliedVersions][rows.Scan] Error: %w", err)
+		}
+
+		applied[version] = appliedAt
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("[migration][appliedVersions][rows.Err] Error: %w", err)
+	}
+
+	return applied, nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration, up bool) (err error) {
+	direction := "down"
+	script := migration.Down
+
+	if up {
+		direction = "up"
+		script = migration.Up
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("[migration][apply][BeginTx] Error: %w | version: %d", err, migration.Version)
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, script)
+	if err != nil {
+		return fmt.Errorf("[migration][apply][ExecContext] %s | Error: %w | version: %d", direction, err, migration.Version)
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`,
+			migration.Version, migration.Name, time.Now().UnixMilli())
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("[migration][apply][ExecContext] schema_migrations | Error: %w | version: %d", err, migration.Version)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("[migration][apply][Commit] Error: %w | version: %d", err, migration.Version)
+	}
+
+	m.log.WithFields(logrus.Fields{
+		"version":   migration.Version,
+		"name":      migration.Name,
+		"direction": direction,
+	}).Info("migration applied")
+
+	return nil
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS account_devices;
+
+DROP TABLE IF EXISTS refresh_tokens;
+
+DROP TABLE IF EXISTS accounts;
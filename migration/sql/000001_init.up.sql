@@ -1,10 +1,4 @@
-CREATE DATABASE go_auth_db;
-
-\c go_auth_db;
-
-DROP TABLE IF EXISTS accounts;
-
-CREATE TABLE accounts (
+CREATE TABLE IF NOT EXISTS accounts (
     account_id BIGSERIAL PRIMARY KEY,
     account_name VARCHAR NOT NULL DEFAULT '',
     account_email VARCHAR NOT NULL DEFAULT '',
@@ -15,7 +9,7 @@ CREATE TABLE accounts (
     deleted_at BIGINT
 );
 
-CREATE TABLE refresh_tokens (
+CREATE TABLE IF NOT EXISTS refresh_tokens (
     refresh_token_id BIGSERIAL PRIMARY KEY,
     refresh_token VARCHAR NOT NULL DEFAULT '',
     account_id BIGINT NOT NULL,
@@ -25,7 +19,7 @@ CREATE TABLE refresh_tokens (
     updated_at BIGINT NOT NULL
 );
 
-CREATE TABLE account_devices (
+CREATE TABLE IF NOT EXISTS account_devices (
     device_id BIGSERIAL PRIMARY KEY,
     account_id BIGINT NOT NULL,
     device_hash VARCHAR NOT NULL,
@@ -34,4 +28,4 @@ CREATE TABLE account_devices (
     created_at BIGINT NOT NULL,
     updated_at BIGINT NOT NULL,
     deleted_at BIGINT
-);
\ No newline at end of file
+);
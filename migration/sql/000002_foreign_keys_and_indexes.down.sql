@@ -0,0 +1,17 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_expired_at;
+
+DROP INDEX IF EXISTS idx_refresh_tokens_device_id;
+
+DROP INDEX IF EXISTS idx_refresh_tokens_account_id;
+
+DROP INDEX IF EXISTS uq_refresh_tokens_refresh_token;
+
+DROP INDEX IF EXISTS idx_account_devices_account_id;
+
+DROP INDEX IF EXISTS uq_account_devices_device_hash;
+
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS fk_refresh_tokens_device_id;
+
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS fk_refresh_tokens_account_id;
+
+ALTER TABLE account_devices DROP CONSTRAINT IF EXISTS fk_account_devices_account_id;
@@ -0,0 +1,50 @@
+-- Drop rows that would violate the new constraints.
+DELETE FROM account_devices d
+WHERE NOT EXISTS (SELECT 1 FROM accounts a WHERE a.account_id = d.account_id);
+
+DELETE FROM refresh_tokens rt
+WHERE NOT EXISTS (SELECT 1 FROM accounts a WHERE a.account_id = rt.account_id)
+    OR NOT EXISTS (SELECT 1 FROM account_devices d WHERE d.device_id = rt.device_id);
+
+UPDATE account_devices d
+SET deleted_at = (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT
+WHERE d.deleted_at IS NULL
+    AND EXISTS (
+        SELECT 1
+        FROM account_devices newer
+        WHERE newer.device_hash = d.device_hash
+            AND newer.deleted_at IS NULL
+            AND newer.device_id > d.device_id
+    );
+
+DELETE FROM refresh_tokens rt
+WHERE EXISTS (
+    SELECT 1
+    FROM refresh_tokens newer
+    WHERE newer.refresh_token = rt.refresh_token
+        AND newer.refresh_token_id > rt.refresh_token_id
+);
+
+ALTER TABLE account_devices
+    ADD CONSTRAINT fk_account_devices_account_id
+    FOREIGN KEY (account_id) REFERENCES accounts (account_id) ON DELETE CASCADE;
+
+ALTER TABLE refresh_tokens
+    ADD CONSTRAINT fk_refresh_tokens_account_id
+    FOREIGN KEY (account_id) REFERENCES accounts (account_id) ON DELETE CASCADE;
+
+ALTER TABLE refresh_tokens
+    ADD CONSTRAINT fk_refresh_tokens_device_id
+    FOREIGN KEY (device_id) REFERENCES account_devices (device_id) ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX uq_account_devices_device_hash ON account_devices (device_hash) WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_account_devices_account_id ON account_devices (account_id);
+
+CREATE UNIQUE INDEX uq_refresh_tokens_refresh_token ON refresh_tokens (refresh_token);
+
+CREATE INDEX idx_refresh_tokens_account_id ON refresh_tokens (account_id);
+
+CREATE INDEX idx_refresh_tokens_device_id ON refresh_tokens (device_id);
+
+CREATE INDEX idx_refresh_tokens_expired_at ON refresh_tokens (expired_at);
@@ -0,0 +1,7 @@
+DELETE FROM refresh_tokens;
+
+ALTER INDEX uq_refresh_tokens_token_hash RENAME TO uq_refresh_tokens_refresh_token;
+
+ALTER TABLE refresh_tokens ALTER COLUMN token_hash SET DEFAULT '';
+
+ALTER TABLE refresh_tokens RENAME COLUMN token_hash TO refresh_token;
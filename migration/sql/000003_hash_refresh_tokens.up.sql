@@ -0,0 +1,9 @@
+-- Existing rows hold plaintext tokens, so they are invalidated rather than
+-- converted. Affected clients have to log in again.
+DELETE FROM refresh_tokens;
+
+ALTER TABLE refresh_tokens RENAME COLUMN refresh_token TO token_hash;
+
+ALTER TABLE refresh_tokens ALTER COLUMN token_hash DROP DEFAULT;
+
+ALTER INDEX uq_refresh_tokens_refresh_token RENAME TO uq_refresh_tokens_token_hash;
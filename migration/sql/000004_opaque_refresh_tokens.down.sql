@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_family_id;
+
+ALTER TABLE refresh_tokens DROP COLUMN revoked_at;
+
+ALTER TABLE refresh_tokens DROP COLUMN last_used_at;
+
+ALTER TABLE refresh_tokens DROP COLUMN family_id;
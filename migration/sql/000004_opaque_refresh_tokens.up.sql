@@ -0,0 +1,15 @@
+ALTER TABLE refresh_tokens ADD COLUMN family_id VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE refresh_tokens ADD COLUMN last_used_at BIGINT;
+
+ALTER TABLE refresh_tokens ADD COLUMN revoked_at BIGINT;
+
+UPDATE refresh_tokens
+SET family_id = 'legacy-' || refresh_token_id,
+    last_used_at = updated_at;
+
+ALTER TABLE refresh_tokens ALTER COLUMN family_id DROP DEFAULT;
+
+ALTER TABLE refresh_tokens ALTER COLUMN last_used_at SET NOT NULL;
+
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens (family_id);
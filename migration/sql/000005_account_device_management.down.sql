@@ -0,0 +1,3 @@
+ALTER TABLE account_devices DROP COLUMN last_seen_at;
+
+ALTER TABLE account_devices DROP COLUMN device_name;
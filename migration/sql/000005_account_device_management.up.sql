@@ -0,0 +1,7 @@
+ALTER TABLE account_devices ADD COLUMN device_name VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE account_devices ADD COLUMN last_seen_at BIGINT;
+
+UPDATE account_devices SET last_seen_at = updated_at;
+
+ALTER TABLE account_devices ALTER COLUMN last_seen_at SET NOT NULL;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS uq_account_devices_approval_token_hash;
+
+ALTER TABLE account_devices DROP COLUMN approval_expired_at;
+
+ALTER TABLE account_devices DROP COLUMN approval_token_hash;
+
+ALTER TABLE account_devices DROP COLUMN approved_at;
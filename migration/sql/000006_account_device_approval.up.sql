@@ -0,0 +1,9 @@
+ALTER TABLE account_devices ADD COLUMN approved_at BIGINT;
+
+ALTER TABLE account_devices ADD COLUMN approval_token_hash VARCHAR;
+
+ALTER TABLE account_devices ADD COLUMN approval_expired_at BIGINT;
+
+UPDATE account_devices SET approved_at = created_at;
+
+CREATE UNIQUE INDEX uq_account_devices_approval_token_hash ON account_devices (approval_token_hash) WHERE approval_token_hash IS NOT NULL;
@@ -0,0 +1,15 @@
+ALTER TABLE account_devices DROP COLUMN push_token;
+
+ALTER TABLE account_devices DROP COLUMN device_model;
+
+ALTER TABLE account_devices DROP COLUMN app_version;
+
+ALTER TABLE account_devices DROP COLUMN os_version;
+
+ALTER TABLE account_devices DROP COLUMN platform;
+
+ALTER TABLE account_devices DROP COLUMN device_type;
+
+ALTER TABLE account_devices DROP COLUMN os;
+
+ALTER TABLE account_devices DROP COLUMN browser;
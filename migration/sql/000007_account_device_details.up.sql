@@ -0,0 +1,15 @@
+ALTER TABLE account_devices ADD COLUMN browser VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE account_devices ADD COLUMN os VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE account_devices ADD COLUMN device_type VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE account_devices ADD COLUMN platform VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE account_devices ADD COLUMN os_version VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE account_devices ADD COLUMN app_version VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE account_devices ADD COLUMN device_model VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE account_devices ADD COLUMN push_token VARCHAR NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+ALTER TABLE refresh_tokens DROP COLUMN ip_address;
+
+ALTER TABLE account_devices DROP COLUMN last_ip;
+
+ALTER TABLE account_devices DROP COLUMN created_ip;
@@ -0,0 +1,5 @@
+ALTER TABLE account_devices ADD COLUMN created_ip VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE account_devices ADD COLUMN last_ip VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE refresh_tokens ADD COLUMN ip_address VARCHAR NOT NULL DEFAULT '';
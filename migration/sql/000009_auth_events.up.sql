@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS auth_events (
+    auth_event_id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR NOT NULL,
+    account_id BIGINT,
+    device_id BIGINT,
+    ip_address VARCHAR NOT NULL DEFAULT '',
+    user_agent VARCHAR NOT NULL DEFAULT '',
+    request_id VARCHAR NOT NULL DEFAULT '',
+    outcome VARCHAR NOT NULL,
+    reason VARCHAR NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_events_account_id ON auth_events (account_id, auth_event_id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_auth_events_event_type ON auth_events (event_type, auth_event_id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_auth_events_created_at ON auth_events (created_at);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS auth_event_checkpoints;
+
+ALTER TABLE auth_events DROP COLUMN hash;
+
+ALTER TABLE auth_events DROP COLUMN prev_hash;
@@ -0,0 +1,13 @@
+ALTER TABLE auth_events ADD COLUMN prev_hash VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE auth_events ADD COLUMN hash VARCHAR NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS auth_event_checkpoints (
+    checkpoint_id BIGSERIAL PRIMARY KEY,
+    auth_event_id BIGINT NOT NULL,
+    hash VARCHAR NOT NULL,
+    signature VARCHAR NOT NULL,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_event_checkpoints_auth_event_id ON auth_event_checkpoints (auth_event_id);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS account_contact_changes (
+    contact_change_id BIGSERIAL PRIMARY KEY,
+    account_id BIGINT NOT NULL,
+    contact_type VARCHAR NOT NULL,
+    old_value VARCHAR NOT NULL,
+    new_value VARCHAR NOT NULL,
+    code_hash VARCHAR NOT NULL,
+    cancel_token_hash VARCHAR NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expired_at BIGINT NOT NULL,
+    confirmed_at BIGINT,
+    cancelled_at BIGINT,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+ALTER TABLE account_contact_changes
+    ADD CONSTRAINT fk_account_contact_changes_account_id
+    FOREIGN KEY (account_id) REFERENCES accounts (account_id) ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_account_contact_changes_cancel_token_hash ON account_contact_changes (cancel_token_hash);
+
+CREATE INDEX IF NOT EXISTS idx_account_contact_changes_account_id ON account_contact_changes (account_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_accounts_deleted_at;
+
+ALTER TABLE accounts DROP COLUMN purged_at;
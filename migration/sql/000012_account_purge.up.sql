@@ -0,0 +1,3 @@
+ALTER TABLE accounts ADD COLUMN purged_at BIGINT;
+
+CREATE INDEX IF NOT EXISTS idx_accounts_deleted_at ON accounts (deleted_at) WHERE deleted_at IS NOT NULL AND purged_at IS NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE accounts DROP COLUMN status_until;
+
+ALTER TABLE accounts DROP COLUMN status_reason;
+
+ALTER TABLE accounts DROP COLUMN account_status;
@@ -0,0 +1,5 @@
+ALTER TABLE accounts ADD COLUMN account_status VARCHAR NOT NULL DEFAULT 'active';
+
+ALTER TABLE accounts ADD COLUMN status_reason VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE accounts ADD COLUMN status_until BIGINT;
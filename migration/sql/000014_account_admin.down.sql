@@ -0,0 +1,19 @@
+DROP INDEX IF EXISTS idx_auth_events_actor_account_id;
+
+ALTER TABLE auth_events DROP COLUMN actor_account_id;
+
+DROP INDEX IF EXISTS idx_accounts_name_prefix;
+
+DROP INDEX IF EXISTS idx_accounts_phone_number_prefix;
+
+DROP INDEX IF EXISTS idx_accounts_email_prefix;
+
+DROP INDEX IF EXISTS uq_accounts_password_reset_token_hash;
+
+ALTER TABLE accounts DROP COLUMN password_reset_expired_at;
+
+ALTER TABLE accounts DROP COLUMN password_reset_token_hash;
+
+ALTER TABLE accounts DROP COLUMN password_reset_required;
+
+ALTER TABLE accounts DROP COLUMN account_role;
@@ -0,0 +1,19 @@
+ALTER TABLE accounts ADD COLUMN account_role VARCHAR NOT NULL DEFAULT 'user';
+
+ALTER TABLE accounts ADD COLUMN password_reset_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE accounts ADD COLUMN password_reset_token_hash VARCHAR;
+
+ALTER TABLE accounts ADD COLUMN password_reset_expired_at BIGINT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_accounts_password_reset_token_hash ON accounts (password_reset_token_hash);
+
+CREATE INDEX IF NOT EXISTS idx_accounts_email_prefix ON accounts (account_email text_pattern_ops);
+
+CREATE INDEX IF NOT EXISTS idx_accounts_phone_number_prefix ON accounts (account_phone_number text_pattern_ops);
+
+CREATE INDEX IF NOT EXISTS idx_accounts_name_prefix ON accounts (account_name text_pattern_ops);
+
+ALTER TABLE auth_events ADD COLUMN actor_account_id BIGINT;
+
+CREATE INDEX IF NOT EXISTS idx_auth_events_actor_account_id ON auth_events (actor_account_id, auth_event_id DESC) WHERE actor_account_id IS NOT NULL;
@@ -0,0 +1,19 @@
+ALTER TABLE accounts ADD COLUMN account_role VARCHAR NOT NULL DEFAULT 'user';
+
+UPDATE accounts a
+SET account_role = 'admin'
+WHERE EXISTS (
+    SELECT 1
+    FROM account_roles ar
+    JOIN roles r ON r.role_id = ar.role_id
+    WHERE ar.account_id = a.account_id
+        AND r.role_name = 'admin'
+);
+
+DROP TABLE IF EXISTS account_roles;
+
+DROP TABLE IF EXISTS role_permissions;
+
+DROP TABLE IF EXISTS permissions;
+
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,83 @@
+CREATE TABLE IF NOT EXISTS roles (
+    role_id BIGSERIAL PRIMARY KEY,
+    role_name VARCHAR NOT NULL,
+    description VARCHAR NOT NULL DEFAULT '',
+    is_builtin BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_roles_role_name ON roles (role_name);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    permission_id BIGSERIAL PRIMARY KEY,
+    permission_name VARCHAR NOT NULL,
+    description VARCHAR NOT NULL DEFAULT '',
+    is_builtin BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_permissions_permission_name ON permissions (permission_name);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id BIGINT NOT NULL,
+    permission_id BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+ALTER TABLE role_permissions
+    ADD CONSTRAINT fk_role_permissions_role_id
+    FOREIGN KEY (role_id) REFERENCES roles (role_id) ON DELETE CASCADE;
+
+ALTER TABLE role_permissions
+    ADD CONSTRAINT fk_role_permissions_permission_id
+    FOREIGN KEY (permission_id) REFERENCES permissions (permission_id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_role_permissions_permission_id ON role_permissions (permission_id);
+
+CREATE TABLE IF NOT EXISTS account_roles (
+    account_id BIGINT NOT NULL,
+    role_id BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY (account_id, role_id)
+);
+
+ALTER TABLE account_roles
+    ADD CONSTRAINT fk_account_roles_account_id
+    FOREIGN KEY (account_id) REFERENCES accounts (account_id) ON DELETE CASCADE;
+
+ALTER TABLE account_roles
+    ADD CONSTRAINT fk_account_roles_role_id
+    FOREIGN KEY (role_id) REFERENCES roles (role_id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_account_roles_role_id ON account_roles (role_id);
+
+-- The permissions the admin API checks, and the admin role holding them.
+INSERT INTO permissions (permission_name, description, is_builtin, created_at, updated_at)
+VALUES
+    ('accounts:read', 'Search and view accounts', TRUE, (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT, (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT),
+    ('accounts:write', 'Change account status, revoke sessions and force password resets', TRUE, (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT, (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT),
+    ('auth_events:read', 'Search the audit log', TRUE, (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT, (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT),
+    ('roles:read', 'View roles, permissions and account roles', TRUE, (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT, (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT),
+    ('roles:write', 'Manage roles and permissions and assign roles to accounts', TRUE, (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT, (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT);
+
+INSERT INTO roles (role_name, description, is_builtin, created_at, updated_at)
+VALUES ('admin', 'Full access to the admin API', TRUE, (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT, (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT);
+
+INSERT INTO role_permissions (role_id, permission_id, created_at)
+SELECT r.role_id, p.permission_id, (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.role_name = 'admin'
+    AND p.is_builtin;
+
+-- Admins move from the account_role column to the admin role.
+INSERT INTO account_roles (account_id, role_id, created_at)
+SELECT a.account_id, r.role_id, (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT
+FROM accounts a
+JOIN roles r ON r.role_name = 'admin'
+WHERE a.account_role = 'admin';
+
+ALTER TABLE accounts DROP COLUMN account_role;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_accounts_tenant_id_name;
+
+DROP INDEX IF EXISTS idx_accounts_tenant_id_phone_number;
+
+DROP INDEX IF EXISTS idx_accounts_tenant_id_email;
+
+ALTER TABLE accounts DROP COLUMN tenant_id;
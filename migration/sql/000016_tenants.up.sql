@@ -0,0 +1,7 @@
+ALTER TABLE accounts ADD COLUMN tenant_id VARCHAR NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS idx_accounts_tenant_id_email ON accounts (tenant_id, account_email) WHERE purged_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_accounts_tenant_id_phone_number ON accounts (tenant_id, account_phone_number) WHERE purged_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_accounts_tenant_id_name ON accounts (tenant_id, account_name) WHERE purged_at IS NULL;
@@ -0,0 +1,9 @@
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS fk_refresh_tokens_organization_id;
+
+ALTER TABLE refresh_tokens DROP COLUMN organization_id;
+
+DROP TABLE IF EXISTS organization_invitations;
+
+DROP TABLE IF EXISTS organization_members;
+
+DROP TABLE IF EXISTS organizations;
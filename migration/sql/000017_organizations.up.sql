@@ -0,0 +1,56 @@
+CREATE TABLE IF NOT EXISTS organizations (
+    organization_id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR NOT NULL,
+    organization_name VARCHAR NOT NULL,
+    created_by BIGINT NOT NULL,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+    organization_id BIGINT NOT NULL,
+    account_id BIGINT NOT NULL,
+    member_role VARCHAR NOT NULL,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL,
+    PRIMARY KEY (organization_id, account_id)
+);
+
+ALTER TABLE organization_members
+    ADD CONSTRAINT fk_organization_members_organization_id
+    FOREIGN KEY (organization_id) REFERENCES organizations (organization_id) ON DELETE CASCADE;
+
+ALTER TABLE organization_members
+    ADD CONSTRAINT fk_organization_members_account_id
+    FOREIGN KEY (account_id) REFERENCES accounts (account_id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_organization_members_account_id ON organization_members (account_id);
+
+CREATE TABLE IF NOT EXISTS organization_invitations (
+    invitation_id BIGSERIAL PRIMARY KEY,
+    organization_id BIGINT NOT NULL,
+    email VARCHAR NOT NULL,
+    member_role VARCHAR NOT NULL,
+    token_hash VARCHAR NOT NULL,
+    invited_by BIGINT NOT NULL,
+    expired_at BIGINT NOT NULL,
+    accepted_by BIGINT,
+    accepted_at BIGINT,
+    revoked_at BIGINT,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+ALTER TABLE organization_invitations
+    ADD CONSTRAINT fk_organization_invitations_organization_id
+    FOREIGN KEY (organization_id) REFERENCES organizations (organization_id) ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_organization_invitations_token_hash ON organization_invitations (token_hash);
+
+CREATE INDEX IF NOT EXISTS idx_organization_invitations_organization_id ON organization_invitations (organization_id);
+
+ALTER TABLE refresh_tokens ADD COLUMN organization_id BIGINT;
+
+ALTER TABLE refresh_tokens
+    ADD CONSTRAINT fk_refresh_tokens_organization_id
+    FOREIGN KEY (organization_id) REFERENCES organizations (organization_id) ON DELETE SET NULL;
@@ -0,0 +1,11 @@
+DELETE FROM permissions WHERE permission_name IN ('oauth_clients:read', 'oauth_clients:write');
+
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS fk_refresh_tokens_client_id;
+
+ALTER TABLE refresh_tokens DROP COLUMN scope;
+
+ALTER TABLE refresh_tokens DROP COLUMN client_id;
+
+DROP TABLE IF EXISTS oauth_authorization_codes;
+
+DROP TABLE IF EXISTS oauth_clients;
@@ -0,0 +1,59 @@
+-- Redirect URIs and scopes are space separated, as scopes are in OAuth.
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    client_id VARCHAR PRIMARY KEY,
+    tenant_id VARCHAR NOT NULL,
+    client_name VARCHAR NOT NULL,
+    client_secret_hash VARCHAR,
+    redirect_uris VARCHAR NOT NULL,
+    allowed_scopes VARCHAR NOT NULL,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+    authorization_code_id BIGSERIAL PRIMARY KEY,
+    code_hash VARCHAR NOT NULL,
+    client_id VARCHAR NOT NULL,
+    account_id BIGINT NOT NULL,
+    device_id BIGINT NOT NULL,
+    redirect_uri VARCHAR NOT NULL,
+    scope VARCHAR NOT NULL,
+    code_challenge VARCHAR NOT NULL,
+    expired_at BIGINT NOT NULL,
+    used_at BIGINT,
+    created_at BIGINT NOT NULL
+);
+
+ALTER TABLE oauth_authorization_codes
+    ADD CONSTRAINT fk_oauth_authorization_codes_client_id
+    FOREIGN KEY (client_id) REFERENCES oauth_clients (client_id) ON DELETE CASCADE;
+
+ALTER TABLE oauth_authorization_codes
+    ADD CONSTRAINT fk_oauth_authorization_codes_account_id
+    FOREIGN KEY (account_id) REFERENCES accounts (account_id) ON DELETE CASCADE;
+
+ALTER TABLE oauth_authorization_codes
+    ADD CONSTRAINT fk_oauth_authorization_codes_device_id
+    FOREIGN KEY (device_id) REFERENCES account_devices (device_id) ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_oauth_authorization_codes_code_hash ON oauth_authorization_codes (code_hash);
+
+ALTER TABLE refresh_tokens ADD COLUMN client_id VARCHAR;
+
+ALTER TABLE refresh_tokens ADD COLUMN scope VARCHAR;
+
+ALTER TABLE refresh_tokens
+    ADD CONSTRAINT fk_refresh_tokens_client_id
+    FOREIGN KEY (client_id) REFERENCES oauth_clients (client_id) ON DELETE CASCADE;
+
+INSERT INTO permissions (permission_name, description, is_builtin, created_at, updated_at)
+VALUES
+    ('oauth_clients:read', 'View OAuth clients', TRUE, (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT, (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT),
+    ('oauth_clients:write', 'Register, change and delete OAuth clients', TRUE, (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT, (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT);
+
+INSERT INTO role_permissions (role_id, permission_id, created_at)
+SELECT r.role_id, p.permission_id, (EXTRACT(EPOCH FROM now()) * 1000)::BIGINT
+FROM roles r
+CROSS JOIN permissions p
+WHERE r.role_name = 'admin'
+    AND p.permission_name IN ('oauth_clients:read', 'oauth_clients:write');
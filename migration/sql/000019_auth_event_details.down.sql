@@ -0,0 +1,11 @@
+ALTER TABLE auth_events ADD COLUMN ip_address VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE auth_events ADD COLUMN user_agent VARCHAR NOT NULL DEFAULT '';
+
+UPDATE auth_events e
+SET ip_address = d.ip_address,
+    user_agent = d.user_agent
+FROM auth_event_details d
+WHERE d.auth_event_id = e.auth_event_id;
+
+DROP TABLE IF EXISTS auth_event_details;
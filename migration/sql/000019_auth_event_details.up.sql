@@ -0,0 +1,22 @@
+-- The client IP and user agent of an event are kept out of the hash chain,
+-- so they can be erased along with the account without breaking it.
+CREATE TABLE IF NOT EXISTS auth_event_details (
+    auth_event_id BIGINT PRIMARY KEY,
+    ip_address VARCHAR NOT NULL DEFAULT '',
+    user_agent VARCHAR NOT NULL DEFAULT ''
+);
+
+ALTER TABLE auth_event_details
+    ADD CONSTRAINT fk_auth_event_details_auth_event_id
+    FOREIGN KEY (auth_event_id) REFERENCES auth_events (auth_event_id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_auth_event_details_ip_address ON auth_event_details (ip_address);
+
+INSERT INTO auth_event_details (auth_event_id, ip_address, user_agent)
+SELECT auth_event_id, ip_address, user_agent
+FROM auth_events
+WHERE ip_address <> '' OR user_agent <> '';
+
+ALTER TABLE auth_events DROP COLUMN ip_address;
+
+ALTER TABLE auth_events DROP COLUMN user_agent;
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS uq_permissions_tenant_id_permission_name;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_permissions_permission_name ON permissions (permission_name);
+
+ALTER TABLE permissions DROP COLUMN tenant_id;
+
+DROP INDEX IF EXISTS uq_roles_tenant_id_role_name;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_roles_role_name ON roles (role_name);
+
+ALTER TABLE roles DROP COLUMN tenant_id;
@@ -0,0 +1,17 @@
+-- Roles and permissions belong to a tenant. The builtin ones belong to none
+-- and are shared by every tenant.
+ALTER TABLE roles ADD COLUMN tenant_id VARCHAR NOT NULL DEFAULT 'default';
+
+UPDATE roles SET tenant_id = '' WHERE is_builtin;
+
+DROP INDEX IF EXISTS uq_roles_role_name;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_roles_tenant_id_role_name ON roles (tenant_id, role_name);
+
+ALTER TABLE permissions ADD COLUMN tenant_id VARCHAR NOT NULL DEFAULT 'default';
+
+UPDATE permissions SET tenant_id = '' WHERE is_builtin;
+
+DROP INDEX IF EXISTS uq_permissions_permission_name;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_permissions_tenant_id_permission_name ON permissions (tenant_id, permission_name);
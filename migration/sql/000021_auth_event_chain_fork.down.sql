@@ -0,0 +1 @@
+DROP INDEX IF EXISTS uq_auth_events_prev_hash;
@@ -0,0 +1,4 @@
+-- No two events of the chain follow the same one, so an append that hashed
+-- against a stale head fails instead of forking the chain. Events recorded
+-- before the chain have no hash and are left out.
+CREATE UNIQUE INDEX IF NOT EXISTS uq_auth_events_prev_hash ON auth_events (prev_hash) WHERE hash <> '';
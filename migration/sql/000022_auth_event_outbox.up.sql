@@ -0,0 +1,15 @@
+-- Events are written here in the transaction of the change they record, and
+-- moved into the hash chain of auth_events in the background.
+CREATE TABLE IF NOT EXISTS auth_event_outbox (
+    auth_event_outbox_id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR NOT NULL,
+    account_id BIGINT,
+    device_id BIGINT,
+    actor_account_id BIGINT,
+    ip_address VARCHAR NOT NULL DEFAULT '',
+    user_agent VARCHAR NOT NULL DEFAULT '',
+    request_id VARCHAR NOT NULL DEFAULT '',
+    outcome VARCHAR NOT NULL,
+    reason VARCHAR NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL
+);
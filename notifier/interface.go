@@ -0,0 +1,33 @@
+package notifier
+
+import (
+	"context"
+
+	"github.com/michaelyusak/go-auth/entity"
+)
+
+type NewDeviceNotifier interface {
+	NotifyNewDevice(ctx context.Context, event entity.NewDeviceEvent) error
+}
+
+// ContactChangeNotifier delivers the confirmation code of a contact change
+// to the new value and the cancel link to the old one.
+type ContactChangeNotifier interface {
+	NotifyContactChange(ctx context.Context, event entity.ContactChangeEvent) error
+}
+
+// PasswordResetNotifier delivers the reset link of a password reset forced
+// by an admin to the account's email.
+type PasswordResetNotifier interface {
+	NotifyPasswordReset(ctx context.Context, event entity.PasswordResetEvent) error
+}
+
+// InvitationNotifier delivers the accept link of an organization
+// invitation to the invited email.
+type InvitationNotifier interface {
+	NotifyInvitation(ctx context.Context, event entity.InvitationEvent) error
+}
+
+type Mailer interface {
+	SendMail(ctx context.Context, to, subject, body string) error
+}
@@ -0,0 +1,66 @@
+package notifier
+
+import (
+	"context"
+
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/sirupsen/logrus"
+)
+
+type logNotifier struct {
+	log *logrus.Logger
+}
+
+func NewLogNotifier(log *logrus.Logger) *logNotifier {
+	return &logNotifier{
+		log: log,
+	}
+}
+
+func (n *logNotifier) NotifyNewDevice(ctx context.Context, event entity.NewDeviceEvent) error {
+	n.log.WithFields(logrus.Fields{
+		"account_id":   event.AccountId,
+		"device_id":    event.DeviceId,
+		"browser":      event.Browser,
+		"os":           event.Os,
+		"device_info":  event.DeviceInfo,
+		"ip_address":   event.IpAddress,
+		"approval_url": event.ApprovalUrl,
+	}).Info("[notifier][NotifyNewDevice] new device login")
+
+	return nil
+}
+
+func (n *logNotifier) NotifyContactChange(ctx context.Context, event entity.ContactChangeEvent) error {
+	n.log.WithFields(logrus.Fields{
+		"account_id":   event.AccountId,
+		"contact_type": event.ContactType,
+		"old_value":    event.OldValue,
+		"new_value":    event.NewValue,
+		"code":         event.Code,
+		"cancel_url":   event.CancelUrl,
+	}).Info("[notifier][NotifyContactChange] contact change requested")
+
+	return nil
+}
+
+func (n *logNotifier) NotifyPasswordReset(ctx context.Context, event entity.PasswordResetEvent) error {
+	n.log.WithFields(logrus.Fields{
+		"account_id": event.AccountId,
+		"email":      event.Email,
+		"reset_url":  event.ResetUrl,
+	}).Info("[notifier][NotifyPasswordReset] password reset required")
+
+	return nil
+}
+
+func (n *logNotifier) NotifyInvitation(ctx context.Context, event entity.InvitationEvent) error {
+	n.log.WithFields(logrus.Fields{
+		"organization_id": event.OrganizationId,
+		"email":           event.Email,
+		"role":            event.Role,
+		"accept_url":      event.AcceptUrl,
+	}).Info("[notifier][NotifyInvitation] organization invitation sent")
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/entity"
+)
+
+const (
+	newDeviceMailSubject           = "New sign-in to your account"
+	contactChangeConfirmSubject    = "Confirm your new email address"
+	contactChangeCancelMailSubject = "Your email address is being changed"
+	passwordResetMailSubject       = "Reset your password"
+	invitationMailSubject          = "You're invited to join an organization"
+)
+
+type mailNotifier struct {
+	mailer Mailer
+}
+
+func NewMailNotifier(mailer Mailer) *mailNotifier {
+	return &mailNotifier{
+		mailer: mailer,
+	}
+}
+
+func (n *mailNotifier) NotifyNewDevice(ctx context.Context, event entity.NewDeviceEvent) error {
+	var body strings.Builder
+
+	fmt.Fprintf(&body, "Hi %s,\n\n", event.Name)
+	fmt.Fprintf(&body, "Your account was just signed in to from a new device.\n\n")
+	fmt.Fprintf(&body, "Browser: %s\n", event.Browser)
+	fmt.Fprintf(&body, "Operating system: %s\n", event.Os)
+	fmt.Fprintf(&body, "Device: %s\n", event.DeviceInfo)
+	fmt.Fprintf(&body, "IP address: %s\n", event.IpAddress)
+	fmt.Fprintf(&body, "Time: %s\n\n", time.UnixMilli(event.OccurredAt).UTC().Format(time.RFC1123))
+
+	if event.ApprovalUrl != "" {
+		fmt.Fprintf(&body, "If this was you, approve the device here:\n%s\n\n", event.ApprovalUrl)
+		fmt.Fprintf(&body, "If it was not, ignore this email and change your password.\n")
+	} else {
+		fmt.Fprintf(&body, "If this was not you, remove the device from your account and change your password.\n")
+	}
+
+	err := n.mailer.SendMail(ctx, event.Email, newDeviceMailSubject, body.String())
+	if err != nil {
+		return fmt.Errorf("[notifier][mailNotifier][NotifyNewDevice][mailer.SendMail] Error: %w", err)
+	}
+
+	return nil
+}
+
+// NotifyContactChange mails the code to the new address and the cancel link
+// to the old one. Only email changes can be delivered by mail.
+func (n *mailNotifier) NotifyContactChange(ctx context.Context, event entity.ContactChangeEvent) error {
+	if event.ContactType != constant.ContactTypeEmail {
+		return fmt.Errorf("[notifier][mailNotifier][NotifyContactChange] unsupported contact type: %s", event.ContactType)
+	}
+
+	expiredAt := time.UnixMilli(event.ExpiredAt).UTC().Format(time.RFC1123)
+
+	var confirmBody strings.Builder
+
+	fmt.Fprintf(&confirmBody, "Hi %s,\n\n", event.Name)
+	fmt.Fprintf(&confirmBody, "Use this code to confirm %s as your new email address:\n\n", event.NewValue)
+	fmt.Fprintf(&confirmBody, "%s\n\n", event.Code)
+	fmt.Fprintf(&confirmBody, "The code expires at %s.\n", expiredAt)
+
+	err := n.mailer.SendMail(ctx, event.NewValue, contactChangeConfirmSubject, confirmBody.String())
+	if err != nil {
+		return fmt.Errorf("[notifier][mailNotifier][NotifyContactChange][mailer.SendMail] Error: %w", err)
+	}
+
+	var cancelBody strings.Builder
+
+	fmt.Fprintf(&cancelBody, "Hi %s,\n\n", event.Name)
+	fmt.Fprintf(&cancelBody, "A change of your account's email address to %s was requested.\n\n", event.NewValue)
+	fmt.Fprintf(&cancelBody, "If this was not you, cancel it here before %s:\n%s\n\n", expiredAt, event.CancelUrl)
+	fmt.Fprintf(&cancelBody, "Then change your password.\n")
+
+	err = n.mailer.SendMail(ctx, event.OldValue, contactChangeCancelMailSubject, cancelBody.String())
+	if err != nil {
+		return fmt.Errorf("[notifier][mailNotifier][NotifyContactChange][mailer.SendMail] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (n *mailNotifier) NotifyPasswordReset(ctx context.Context, event entity.PasswordResetEvent) error {
+	var body strings.Builder
+
+	fmt.Fprintf(&body, "Hi %s,\n\n", event.Name)
+	fmt.Fprintf(&body, "You need to set a new password before you can sign in to your account again.\n\n")
+	fmt.Fprintf(&body, "Set it here before %s:\n%s\n", time.UnixMilli(event.ExpiredAt).UTC().Format(time.RFC1123), event.ResetUrl)
+
+	err := n.mailer.SendMail(ctx, event.Email, passwordResetMailSubject, body.String())
+	if err != nil {
+		return fmt.Errorf("[notifier][mailNotifier][NotifyPasswordReset][mailer.SendMail] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (n *mailNotifier) NotifyInvitation(ctx context.Context, event entity.InvitationEvent) error {
+	var body strings.Builder
+
+	fmt.Fprintf(&body, "Hi,\n\n")
+	fmt.Fprintf(&body, "%s invited you to join %s as %s.\n\n", event.InviterName, event.OrganizationName, event.Role)
+	fmt.Fprintf(&body, "Accept the invitation here before %s:\n%s\n", time.UnixMilli(event.ExpiredAt).UTC().Format(time.RFC1123), event.AcceptUrl)
+
+	err := n.mailer.SendMail(ctx, event.Email, invitationMailSubject, body.String())
+	if err != nil {
+		return fmt.Errorf("[notifier][mailNotifier][NotifyInvitation][mailer.SendMail] Error: %w", err)
+	}
+
+	return nil
+}
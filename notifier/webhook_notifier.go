@@ -0,0 +1,93 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/michaelyusak/go-auth/entity"
+)
+
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url string, timeout time.Duration) *webhookNotifier {
+	return &webhookNotifier{
+		url: url,
+		client: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+func (n *webhookNotifier) NotifyNewDevice(ctx context.Context, event entity.NewDeviceEvent) error {
+	err := n.post(ctx, event)
+	if err != nil {
+		return fmt.Errorf("[notifier][webhookNotifier][NotifyNewDevice][post] Error: %w", err)
+	}
+
+	return nil
+}
+
+// NotifyContactChange leaves delivery to the receiver, which is expected to
+// send the code to new_value and the cancel link to old_value, by email or
+// SMS depending on contact_type.
+func (n *webhookNotifier) NotifyContactChange(ctx context.Context, event entity.ContactChangeEvent) error {
+	err := n.post(ctx, event)
+	if err != nil {
+		return fmt.Errorf("[notifier][webhookNotifier][NotifyContactChange][post] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (n *webhookNotifier) NotifyPasswordReset(ctx context.Context, event entity.PasswordResetEvent) error {
+	err := n.post(ctx, event)
+	if err != nil {
+		return fmt.Errorf("[notifier][webhookNotifier][NotifyPasswordReset][post] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (n *webhookNotifier) NotifyInvitation(ctx context.Context, event entity.InvitationEvent) error {
+	err := n.post(ctx, event)
+	if err != nil {
+		return fmt.Errorf("[notifier][webhookNotifier][NotifyInvitation][post] Error: %w", err)
+	}
+
+	return nil
+}
+
+// post sends the event as JSON to the webhook URL. Any non 2xx response is
+// treated as a failure.
+func (n *webhookNotifier) post(ctx context.Context, event any) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("[notifier][webhookNotifier][post][json.Marshal] Error: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("[notifier][webhookNotifier][post][http.NewRequestWithContext] Error: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("[notifier][webhookNotifier][post][client.Do] Error: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("[notifier][webhookNotifier][post] unexpected status: %d", res.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package purger
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/michaelyusak/go-auth/audit"
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// accountPurger erases the personal data of accounts whose deletion grace
+// period has ended. The account row is anonymized rather than deleted, so
+// the audit log's hash chain and its references to the account stay valid;
+// only the client IPs and user agents of its events, which the chain does
+// not cover, are erased.
+type accountPurger struct {
+	accountRepo   repository.AccountRepository
+	transaction   repository.Transaction
+	gracePeriod   time.Duration
+	interval      time.Duration
+	batchSize     int
+	log           *logrus.Logger
+	auditRecorder audit.Recorder
+}
+
+type AccountPurgerOpt struct {
+	AccountRepo   repository.AccountRepository
+	Transaction   repository.Transaction
+	GracePeriod   time.Duration
+	Interval      time.Duration
+	BatchSize     int
+	Log           *logrus.Logger
+	AuditRecorder audit.Recorder
+}
+
+func NewAccountPurger(opt AccountPurgerOpt) *accountPurger {
+	return &accountPurger{
+		accountRepo:   opt.AccountRepo,
+		transaction:   opt.Transaction,
+		gracePeriod:   opt.GracePeriod,
+		interval:      opt.Interval,
+		batchSize:     opt.BatchSize,
+		log:           opt.Log,
+		auditRecorder: opt.AuditRecorder,
+	}
+}
+
+// Run purges one batch of accounts every interval until ctx ends.
+func (p *accountPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := p.purgeBatch(ctx)
+		if err != nil {
+			p.log.WithFields(logrus.Fields{
+				"error": err.Error(),
+			}).Error("[purger][accountPurger][Run][purgeBatch]")
+		}
+	}
+}
+
+// purgeBatch purges each account in its own transaction, so one failure
+// does not hold back the rest of the batch.
+func (p *accountPurger) purgeBatch(ctx context.Context) error {
+	deletedBefore := time.Now().Add(-p.gracePeriod).UnixMilli()
+
+	accountIds, err := p.accountRepo.GetAccountIdsToPurge(ctx, deletedBefore, p.batchSize)
+	if err != nil {
+		return fmt.Errorf("[purger][accountPurger][purgeBatch][accountRepo.GetAccountIdsToPurge] Error: %w", err)
+	}
+
+	for _, accountId := range accountIds {
+		err := p.purge(ctx, accountId, deletedBefore)
+		if err != nil {
+			p.log.WithFields(logrus.Fields{
+				"error":      err.Error(),
+				"account_id": accountId,
+			}).Error("[purger][accountPurger][purgeBatch][purge]")
+		}
+	}
+
+	return nil
+}
+
+// purge anonymizes the account first; if it was restored in the meantime
+// nothing is purged.
+func (p *accountPurger) purge(ctx context.Context, accountId, deletedBefore int64) error {
+	return p.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		purged, err := repos.Account.PurgeAccount(ctx, accountId, deletedBefore)
+		if err != nil {
+			return fmt.Errorf("[purger][accountPurger][purge][accountRepo.PurgeAccount] Error: %w", err)
+		}
+
+		if !purged {
+			return nil
+		}
+
+		err = repos.RefreshToken.DeleteTokenByAccountId(ctx, accountId)
+		if err != nil {
+			return fmt.Errorf("[purger][accountPurger][purge][refreshTokenRepo.DeleteTokenByAccountId] Error: %w", err)
+		}
+
+		err = repos.AccountDevice.DeleteDevicesByAccountId(ctx, accountId)
+		if err != nil {
+			return fmt.Errorf("[purger][accountPurger][purge][accountDeviceRepo.DeleteDevicesByAccountId] Error: %w", err)
+		}
+
+		err = repos.ContactChange.DeleteChangesByAccountId(ctx, accountId)
+		if err != nil {
+			return fmt.Errorf("[purger][accountPurger][purge][contactChangeRepo.DeleteChangesByAccountId] Error: %w", err)
+		}
+
+		err = repos.Organization.DeleteMembershipsByAccountId(ctx, accountId)
+		if err != nil {
+			return fmt.Errorf("[purger][accountPurger][purge][organizationRepo.DeleteMembershipsByAccountId] Error: %w", err)
+		}
+
+		err = repos.AuthEvent.DeleteEventDetailsByAccountId(ctx, accountId)
+		if err != nil {
+			return fmt.Errorf("[purger][accountPurger][purge][authEventRepo.DeleteEventDetailsByAccountId] Error: %w", err)
+		}
+
+		err = p.auditRecorder.RecordTx(ctx, repos, entity.AuthEvent{
+			EventType: constant.AuthEventAccountPurged,
+			AccountId: &accountId,
+			Outcome:   constant.AuthEventOutcomeSuccess,
+			Reason:    constant.AuthEventReasonGracePeriodEnded,
+		})
+		if err != nil {
+			return fmt.Errorf("[purger][accountPurger][purge][auditRecorder.RecordTx] Error: %w", err)
+		}
+
+		return nil
+	})
+}
@@ -4,4 +4,8 @@ import "time"
 
 func nowUnixMilli() int64 {
 	return time.Now().UnixMilli()
-}
\ No newline at end of file
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+type sqlDB struct {
+	db *sql.DB
+}
+
+// NewDB lets repositories run their statements on the database outside of
+// a transaction.
+func NewDB(db *sql.DB) DBTX {
+	return &sqlDB{
+		db: db,
+	}
+}
+
+func (d *sqlDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return d.db.ExecContext(ctx, query, args...)
+}
+
+func (d *sqlDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return d.db.PrepareContext(ctx, query)
+}
+
+func (d *sqlDB) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+func (d *sqlDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) Row {
+	return d.db.QueryRowContext(ctx, query, args...)
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	pgForeignKeyViolation = "23503"
+	pgUniqueViolation     = "23505"
+)
+
+var (
+	ErrAccountReferenceNotFound       = errors.New("referenced account does not exist")
+	ErrDeviceReferenceNotFound        = errors.New("referenced device does not exist")
+	ErrDeviceHashAlreadyExists        = errors.New("device hash already exists")
+	ErrRefreshTokenAlreadyExists      = errors.New("refresh token already exists")
+	ErrRoleReferenceNotFound          = errors.New("referenced role does not exist")
+	ErrPermissionReferenceNotFound    = errors.New("referenced permission does not exist")
+	ErrRoleNameAlreadyExists          = errors.New("role name already exists")
+	ErrPermissionNameAlreadyExists    = errors.New("permission name already exists")
+	ErrOrganizationReferenceNotFound  = errors.New("referenced organization does not exist")
+	ErrMemberAlreadyExists            = errors.New("organization member already exists")
+	ErrInvitationTokenAlreadyExists   = errors.New("invitation token already exists")
+	ErrOAuthClientReferenceNotFound   = errors.New("referenced oauth client does not exist")
+	ErrAuthorizationCodeAlreadyExists = errors.New("authorization code already exists")
+	ErrAuthEventChainForked           = errors.New("auth event chain head already has a successor")
+)
+
+// constraintErrors maps constraint names from the migrations to the typed
+// errors the repositories return for them.
+var constraintErrors = map[string]error{
+	"fk_account_devices_account_id":               ErrAccountReferenceNotFound,
+	"fk_refresh_tokens_account_id":                ErrAccountReferenceNotFound,
+	"fk_refresh_tokens_device_id":                 ErrDeviceReferenceNotFound,
+	"fk_account_contact_changes_account_id":       ErrAccountReferenceNotFound,
+	"fk_account_roles_account_id":                 ErrAccountReferenceNotFound,
+	"fk_account_roles_role_id":                    ErrRoleReferenceNotFound,
+	"fk_role_permissions_role_id":                 ErrRoleReferenceNotFound,
+	"fk_role_permissions_permission_id":           ErrPermissionReferenceNotFound,
+	"uq_account_devices_device_hash":              ErrDeviceHashAlreadyExists,
+	"uq_refresh_tokens_token_hash":                ErrRefreshTokenAlreadyExists,
+	"uq_roles_tenant_id_role_name":                ErrRoleNameAlreadyExists,
+	"uq_permissions_tenant_id_permission_name":    ErrPermissionNameAlreadyExists,
+	"fk_organization_members_organization_id":     ErrOrganizationReferenceNotFound,
+	"fk_organization_members_account_id":          ErrAccountReferenceNotFound,
+	"fk_organization_invitations_organization_id": ErrOrganizationReferenceNotFound,
+	"fk_refresh_tokens_organization_id":           ErrOrganizationReferenceNotFound,
+	"organization_members_pkey":                   ErrMemberAlreadyExists,
+	"uq_organization_invitations_token_hash":      ErrInvitationTokenAlreadyExists,
+	"fk_oauth_authorization_codes_client_id":      ErrOAuthClientReferenceNotFound,
+	"fk_oauth_authorization_codes_account_id":     ErrAccountReferenceNotFound,
+	"fk_oauth_authorization_codes_device_id":      ErrDeviceReferenceNotFound,
+	"fk_refresh_tokens_client_id":                 ErrOAuthClientReferenceNotFound,
+	"uq_oauth_authorization_codes_code_hash":      ErrAuthorizationCodeAlreadyExists,
+	"uq_auth_events_prev_hash":                    ErrAuthEventChainForked,
+}
+
+// ConstraintError wraps a Postgres constraint violation. It matches the
+// typed error of its constraint with errors.Is and still unwraps to the
+// original *pgconn.PgError.
+type ConstraintError struct {
+	Constraint string
+	Kind       error
+	Err        error
+}
+
+func (e *ConstraintError) Error() string {
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+func (e *ConstraintError) Is(target error) bool {
+	return e.Kind == target
+}
+
+func (e *ConstraintError) Unwrap() error {
+	return e.Err
+}
+
+func translatePgError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	if pgErr.Code != pgForeignKeyViolation && pgErr.Code != pgUniqueViolation {
+		return err
+	}
+
+	kind, ok := constraintErrors[pgErr.ConstraintName]
+	if !ok {
+		return err
+	}
+
+	return &ConstraintError{
+		Constraint: pgErr.ConstraintName,
+		Kind:       kind,
+		Err:        err,
+	}
+}
@@ -7,27 +7,149 @@ import (
 	"github.com/michaelyusak/go-auth/entity"
 )
 
+// DBTX is what repositories run their statements on: the database, see
+// NewDB, or a transaction. Rows and Row are interfaces so a transaction can
+// see the errors that only surface while reading results.
 type DBTX interface {
 	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
 	PrepareContext(context.Context, string) (*sql.Stmt, error)
-	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
-	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+	QueryContext(context.Context, string, ...interface{}) (Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) Row
+}
+
+// Rows is the subset of *sql.Rows repositories use.
+type Rows interface {
+	Next() bool
+	Scan(dest ...any) error
+	Err() error
+	Close() error
+}
+
+// Row is the subset of *sql.Row repositories use.
+type Row interface {
+	Scan(dest ...any) error
+	Err() error
 }
 
 type AccountRepository interface {
-	GetAccountByEmail(ctx context.Context, email string) (*entity.Account, error)
-	GetAccountByPhoneNumber(ctx context.Context, phoneNumber string) (*entity.Account, error)
+	GetAccountByEmail(ctx context.Context, tenantId, email string) (*entity.Account, error)
+	GetAccountByPhoneNumber(ctx context.Context, tenantId, phoneNumber string) (*entity.Account, error)
 	Lock(ctx context.Context) error
 	Register(ctx context.Context, newAccount entity.Account) (int64, error)
-	GetAccountByName(ctx context.Context, name string) (*entity.Account, error)
+	GetAccountByName(ctx context.Context, tenantId, name string) (*entity.Account, error)
+	GetAccountById(ctx context.Context, accountId int64) (*entity.Account, error)
+	UpdateAccount(ctx context.Context, account entity.Account) error
+	SoftDeleteAccount(ctx context.Context, accountId int64) error
+	RestoreAccount(ctx context.Context, accountId int64) error
+	GetAccountIdsToPurge(ctx context.Context, deletedBefore int64, limit int) ([]int64, error)
+	PurgeAccount(ctx context.Context, accountId, deletedBefore int64) (bool, error)
+	UpdateStatus(ctx context.Context, accountId int64, status, reason string, until *int64) error
+	SearchAccounts(ctx context.Context, filter entity.AccountFilter) ([]entity.Account, error)
+	RequirePasswordReset(ctx context.Context, accountId int64, tokenHash string, expiredAt int64) error
+	GetAccountByPasswordResetTokenHash(ctx context.Context, tokenHash string) (*entity.Account, error)
+	ResetPassword(ctx context.Context, accountId int64, password string) error
 }
 
 type RefreshTokenRepository interface {
-	InsertToken(ctx context.Context, token string, accountId, deviceId, expiredAt int64) error
+	InsertToken(ctx context.Context, newToken entity.RefreshToken) error
+	GetTokenByHash(ctx context.Context, tokenHash string) (*entity.RefreshToken, error)
+	GetTokensByAccountId(ctx context.Context, accountId int64) ([]entity.RefreshToken, error)
+	RevokeToken(ctx context.Context, refreshTokenId int64) (bool, error)
+	DeleteTokenByFamilyId(ctx context.Context, familyId string) error
 	DeleteTokenByAccountId(ctx context.Context, accountId int64) error
+	DeleteTokenByDeviceId(ctx context.Context, deviceId int64) error
+	DeleteDeviceSession(ctx context.Context, deviceId int64) error
+	DeleteOtherSessions(ctx context.Context, accountId, keepDeviceId int64) (int64, error)
+	DeleteLeastRecentlyUsedSessions(ctx context.Context, accountId int64, keep int) (int64, error)
 }
 
 type AccountDeviceRepository interface {
 	InsertDevice(ctx context.Context, newDevice entity.AccountDevice) (int64, error)
 	GetDeviceByHash(ctx context.Context, hash string) (*entity.AccountDevice, error)
+	GetDeviceById(ctx context.Context, accountId, deviceId int64) (*entity.AccountDevice, error)
+	GetDevicesByAccountId(ctx context.Context, accountId int64) ([]entity.AccountDevice, error)
+	UpdateDeviceName(ctx context.Context, deviceId int64, name string) error
+	UpdateLastSeen(ctx context.Context, deviceId int64, ipAddress string) error
+	UpdateDeviceDetails(ctx context.Context, accountDevice entity.AccountDevice) error
+	SoftDeleteDevice(ctx context.Context, deviceId int64) error
+	SoftDeleteDevicesByAccountId(ctx context.Context, accountId int64) error
+	DeleteDevicesByAccountId(ctx context.Context, accountId int64) error
+	GetDeviceByApprovalTokenHash(ctx context.Context, tokenHash string) (*entity.AccountDevice, error)
+	UpdateApprovalToken(ctx context.Context, deviceId int64, tokenHash string, expiredAt int64) error
+	ApproveDevice(ctx context.Context, deviceId int64) error
+}
+
+type AuthEventRepository interface {
+	InsertEvent(ctx context.Context, event entity.AuthEvent) error
+	QueueEvent(ctx context.Context, event entity.AuthEvent) error
+	AppendQueuedEvents(ctx context.Context, limit int) (int, error)
+	DeleteEventDetailsByAccountId(ctx context.Context, accountId int64) error
+	GetEvents(ctx context.Context, filter entity.AuthEventFilter) ([]entity.AuthEvent, error)
+	GetChainHead(ctx context.Context) (*entity.AuthEvent, error)
+	GetEventsAfter(ctx context.Context, afterId int64, limit int) ([]entity.AuthEvent, error)
+	InsertCheckpoint(ctx context.Context, checkpoint entity.AuthEventCheckpoint) error
+	GetLatestCheckpoint(ctx context.Context) (*entity.AuthEventCheckpoint, error)
+	GetCheckpoints(ctx context.Context) ([]entity.AuthEventCheckpoint, error)
+}
+
+type AccountContactChangeRepository interface {
+	InsertChange(ctx context.Context, contactChange entity.AccountContactChange) (int64, error)
+	GetPendingChangeById(ctx context.Context, accountId, contactChangeId int64) (*entity.AccountContactChange, error)
+	GetPendingChangeByCancelTokenHash(ctx context.Context, tokenHash string) (*entity.AccountContactChange, error)
+	CancelPendingChanges(ctx context.Context, accountId int64, contactType string) error
+	IncrementAttempts(ctx context.Context, contactChangeId int64) error
+	ConfirmChange(ctx context.Context, contactChangeId int64) error
+	CancelChange(ctx context.Context, contactChangeId int64) error
+	DeleteChangesByAccountId(ctx context.Context, accountId int64) error
+}
+
+type RoleRepository interface {
+	GetRoles(ctx context.Context, tenantId string) ([]entity.Role, error)
+	GetRoleById(ctx context.Context, roleId int64) (*entity.Role, error)
+	GetRoleByName(ctx context.Context, tenantId, name string) (*entity.Role, error)
+	InsertRole(ctx context.Context, role entity.Role) (int64, error)
+	UpdateRole(ctx context.Context, role entity.Role) error
+	DeleteRole(ctx context.Context, roleId int64) error
+	GetRolePermissionNames(ctx context.Context, tenantId string) (map[int64][]string, error)
+	SetRolePermissions(ctx context.Context, roleId int64, permissionIds []int64) error
+	GetPermissions(ctx context.Context, tenantId string) ([]entity.Permission, error)
+	GetPermissionById(ctx context.Context, permissionId int64) (*entity.Permission, error)
+	InsertPermission(ctx context.Context, permission entity.Permission) (int64, error)
+	DeletePermission(ctx context.Context, permissionId int64) error
+	GetAccountRoles(ctx context.Context, accountId int64) ([]entity.Role, error)
+	GetAccountGrants(ctx context.Context, accountId int64) (*entity.AccountGrants, error)
+	AssignRole(ctx context.Context, accountId, roleId int64) error
+	UnassignRole(ctx context.Context, accountId, roleId int64) (bool, error)
+}
+
+type OrganizationRepository interface {
+	InsertOrganization(ctx context.Context, organization entity.Organization) (int64, error)
+	GetOrganizationById(ctx context.Context, organizationId int64) (*entity.Organization, error)
+	GetOrganizationForUpdate(ctx context.Context, organizationId int64) (*entity.Organization, error)
+	GetMembershipsByAccountId(ctx context.Context, accountId int64) ([]entity.OrganizationMembership, error)
+	GetMember(ctx context.Context, organizationId, accountId int64) (*entity.OrganizationMember, error)
+	GetMembers(ctx context.Context, organizationId int64) ([]entity.OrganizationMember, error)
+	CountOwners(ctx context.Context, organizationId int64) (int, error)
+	InsertMember(ctx context.Context, member entity.OrganizationMember) error
+	UpdateMemberRole(ctx context.Context, organizationId, accountId int64, role string) error
+	DeleteMember(ctx context.Context, organizationId, accountId int64) error
+	DeleteMembershipsByAccountId(ctx context.Context, accountId int64) error
+	InsertInvitation(ctx context.Context, invitation entity.OrganizationInvitation) (int64, error)
+	GetInvitationById(ctx context.Context, invitationId int64) (*entity.OrganizationInvitation, error)
+	GetInvitationByTokenHash(ctx context.Context, tokenHash string) (*entity.OrganizationInvitation, error)
+	GetPendingInvitations(ctx context.Context, organizationId int64) ([]entity.OrganizationInvitation, error)
+	RevokeInvitation(ctx context.Context, invitationId int64) (bool, error)
+	RevokeInvitationsByEmail(ctx context.Context, organizationId int64, email string) error
+	AcceptInvitation(ctx context.Context, invitationId, accountId int64) (bool, error)
+}
+
+type OAuthRepository interface {
+	GetClients(ctx context.Context, tenantId string) ([]entity.OAuthClient, error)
+	GetClientById(ctx context.Context, clientId string) (*entity.OAuthClient, error)
+	InsertClient(ctx context.Context, client entity.OAuthClient) error
+	UpdateClient(ctx context.Context, client entity.OAuthClient) error
+	DeleteClient(ctx context.Context, clientId string) (bool, error)
+	InsertAuthorizationCode(ctx context.Context, code entity.OAuthAuthorizationCode) error
+	GetAuthorizationCodeByHash(ctx context.Context, codeHash string) (*entity.OAuthAuthorizationCode, error)
+	UseAuthorizationCode(ctx context.Context, authorizationCodeId int64) (bool, error)
 }
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/michaelyusak/go-auth/entity"
+)
+
+const accountContactChangeColumns = `contact_change_id, account_id, contact_type, old_value, new_value, code_hash, cancel_token_hash,
+	attempts, expired_at, confirmed_at, cancelled_at, created_at, updated_at`
+
+func scanAccountContactChange(row rowScanner, contactChange *entity.AccountContactChange) error {
+	return row.Scan(
+		&contactChange.ContactChangeId,
+		&contactChange.AccountId,
+		&contactChange.ContactType,
+		&contactChange.OldValue,
+		&contactChange.NewValue,
+		&contactChange.CodeHash,
+		&contactChange.CancelTokenHash,
+		&contactChange.Attempts,
+		&contactChange.ExpiredAt,
+		&contactChange.ConfirmedAt,
+		&contactChange.CancelledAt,
+		&contactChange.CreatedAt,
+		&contactChange.UpdatedAt,
+	)
+}
+
+type accountContactChangeRepositoryPostgres struct {
+	dbtx DBTX
+}
+
+func NewAccountContactChangeRepositoryPostgres(dbtx DBTX) *accountContactChangeRepositoryPostgres {
+	return &accountContactChangeRepositoryPostgres{
+		dbtx: dbtx,
+	}
+}
+
+func (r *accountContactChangeRepositoryPostgres) InsertChange(ctx context.Context, contactChange entity.AccountContactChange) (int64, error) {
+	q := `
+		INSERT INTO account_contact_changes (account_id, contact_type, old_value, new_value, code_hash, cancel_token_hash, expired_at,
+			created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		RETURNING contact_change_id
+	`
+
+	var contactChangeId int64
+
+	err := r.dbtx.QueryRowContext(ctx, q,
+		contactChange.AccountId,
+		contactChange.ContactType,
+		contactChange.OldValue,
+		contactChange.NewValue,
+		contactChange.CodeHash,
+		contactChange.CancelTokenHash,
+		contactChange.ExpiredAt,
+		nowUnixMilli()).Scan(&contactChangeId)
+	if err != nil {
+		return contactChangeId, fmt.Errorf("[postgres][account_contact_change_repository][InsertChange][QueryRowContext] Error: %w", translatePgError(err))
+	}
+
+	return contactChangeId, nil
+}
+
+// GetPendingChangeById returns the account's change if it is neither
+// confirmed nor cancelled. Expiry is left to the caller.
+func (r *accountContactChangeRepositoryPostgres) GetPendingChangeById(ctx context.Context, accountId, contactChangeId int64) (*entity.AccountContactChange, error) {
+	q := `
+		SELECT ` + accountContactChangeColumns + `
+		FROM account_contact_changes
+		WHERE contact_change_id = $1
+			AND account_id = $2
+			AND confirmed_at IS NULL
+			AND cancelled_at IS NULL
+	`
+
+	var contactChange entity.AccountContactChange
+
+	err := scanAccountContactChange(r.dbtx.QueryRowContext(ctx, q, contactChangeId, accountId), &contactChange)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("[postgres][account_contact_change_repository][GetPendingChangeById][QueryRowContext] Error: %w", err)
+	}
+
+	return &contactChange, nil
+}
+
+func (r *accountContactChangeRepositoryPostgres) GetPendingChangeByCancelTokenHash(ctx context.Context, tokenHash string) (*entity.AccountContactChange, error) {
+	q := `
+		SELECT ` + accountContactChangeColumns + `
+		FROM account_contact_changes
+		WHERE cancel_token_hash = $1
+			AND confirmed_at IS NULL
+			AND cancelled_at IS NULL
+	`
+
+	var contactChange entity.AccountContactChange
+
+	err := scanAccountContactChange(r.dbtx.QueryRowContext(ctx, q, tokenHash), &contactChange)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("[postgres][account_contact_change_repository][GetPendingChangeByCancelTokenHash][QueryRowContext] Error: %w", err)
+	}
+
+	return &contactChange, nil
+}
+
+func (r *accountContactChangeRepositoryPostgres) CancelPendingChanges(ctx context.Context, accountId int64, contactType string) error {
+	q := `
+		UPDATE account_contact_changes
+		SET cancelled_at = $3,
+			updated_at = $3
+		WHERE account_id = $1
+			AND contact_type = $2
+			AND confirmed_at IS NULL
+			AND cancelled_at IS NULL
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, accountId, contactType, nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][account_contact_change_repository][CancelPendingChanges][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *accountContactChangeRepositoryPostgres) IncrementAttempts(ctx context.Context, contactChangeId int64) error {
+	q := `
+		UPDATE account_contact_changes
+		SET attempts = attempts + 1,
+			updated_at = $2
+		WHERE contact_change_id = $1
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, contactChangeId, nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][account_contact_change_repository][IncrementAttempts][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *accountContactChangeRepositoryPostgres) ConfirmChange(ctx context.Context, contactChangeId int64) error {
+	q := `
+		UPDATE account_contact_changes
+		SET confirmed_at = $2,
+			updated_at = $2
+		WHERE contact_change_id = $1
+			AND confirmed_at IS NULL
+			AND cancelled_at IS NULL
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, contactChangeId, nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][account_contact_change_repository][ConfirmChange][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *accountContactChangeRepositoryPostgres) CancelChange(ctx context.Context, contactChangeId int64) error {
+	q := `
+		UPDATE account_contact_changes
+		SET cancelled_at = $2,
+			updated_at = $2
+		WHERE contact_change_id = $1
+			AND confirmed_at IS NULL
+			AND cancelled_at IS NULL
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, contactChangeId, nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][account_contact_change_repository][CancelChange][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *accountContactChangeRepositoryPostgres) DeleteChangesByAccountId(ctx context.Context, accountId int64) error {
+	q := `
+		DELETE FROM account_contact_changes
+		WHERE account_id = $1
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, accountId)
+	if err != nil {
+		return fmt.Errorf("[postgres][account_contact_change_repository][DeleteChangesByAccountId][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
@@ -4,10 +4,42 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 
 	"github.com/michaelyusak/go-auth/entity"
 )
 
+const accountDeviceColumns = `device_id, account_id, device_hash, device_name, user_agent, device_info, browser, os, device_type,
+	platform, os_version, app_version, device_model, push_token, created_ip, last_ip, last_seen_at, approved_at, approval_expired_at,
+	created_at, updated_at, deleted_at`
+
+func scanAccountDevice(row rowScanner, accountDevice *entity.AccountDevice) error {
+	return row.Scan(
+		&accountDevice.DeviceId,
+		&accountDevice.AccountId,
+		&accountDevice.DeviceHash,
+		&accountDevice.DeviceName,
+		&accountDevice.UserAgent,
+		&accountDevice.DeviceInfo,
+		&accountDevice.Browser,
+		&accountDevice.Os,
+		&accountDevice.DeviceType,
+		&accountDevice.Platform,
+		&accountDevice.OsVersion,
+		&accountDevice.AppVersion,
+		&accountDevice.DeviceModel,
+		&accountDevice.PushToken,
+		&accountDevice.CreatedIp,
+		&accountDevice.LastIp,
+		&accountDevice.LastSeenAt,
+		&accountDevice.ApprovedAt,
+		&accountDevice.ApprovalExpiredAt,
+		&accountDevice.CreatedAt,
+		&accountDevice.UpdatedAt,
+		&accountDevice.DeletedAt,
+	)
+}
+
 type accountDeviceRepositoryPostgres struct {
 	dbtx DBTX
 }
@@ -20,8 +52,10 @@ func NewAccountDeviceRepositoryPostgres(dbtx DBTX) *accountDeviceRepositoryPostg
 
 func (r *accountDeviceRepositoryPostgres) InsertDevice(ctx context.Context, newDevice entity.AccountDevice) (int64, error) {
 	q := `
-		INSERT INTO account_devices (account_id, device_hash, user_agent, device_info, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $5)
+		INSERT INTO account_devices (account_id, device_hash, user_agent, device_info, browser, os, device_type, platform, os_version,
+			app_version, device_model, push_token, created_ip, last_ip, approved_at, approval_token_hash, approval_expired_at, last_seen_at,
+			created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $13, $14, $15, $16, $17, $17, $17)
 		RETURNING device_id
 	`
 
@@ -32,9 +66,21 @@ func (r *accountDeviceRepositoryPostgres) InsertDevice(ctx context.Context, newD
 		newDevice.DeviceHash,
 		newDevice.UserAgent,
 		newDevice.DeviceInfo,
+		newDevice.Browser,
+		newDevice.Os,
+		newDevice.DeviceType,
+		newDevice.Platform,
+		newDevice.OsVersion,
+		newDevice.AppVersion,
+		newDevice.DeviceModel,
+		newDevice.PushToken,
+		newDevice.CreatedIp,
+		newDevice.ApprovedAt,
+		newDevice.ApprovalTokenHash,
+		newDevice.ApprovalExpiredAt,
 		nowUnixMilli()).Scan(&deviceId)
 	if err != nil {
-		return deviceId, err
+		return deviceId, fmt.Errorf("[postgres][account_device_repository][InsertDevice][QueryRowContext] Error: %w", translatePgError(err))
 	}
 
 	return deviceId, nil
@@ -42,7 +88,7 @@ func (r *accountDeviceRepositoryPostgres) InsertDevice(ctx context.Context, newD
 
 func (r *accountDeviceRepositoryPostgres) GetDeviceByHash(ctx context.Context, hash string) (*entity.AccountDevice, error) {
 	q := `
-		SELECT device_id, account_id, device_hash, user_agent, device_info, created_at, updated_at, deleted_at
+		SELECT ` + accountDeviceColumns + `
 		FROM account_devices
 		WHERE device_hash = $1
 			AND deleted_at IS NULL
@@ -50,23 +96,255 @@ func (r *accountDeviceRepositoryPostgres) GetDeviceByHash(ctx context.Context, h
 
 	var accountDevice entity.AccountDevice
 
-	err := r.dbtx.QueryRowContext(ctx, q, hash).Scan(
-		&accountDevice.DeviceId,
-		&accountDevice.AccountId,
-		&accountDevice.DeviceHash,
-		&accountDevice.UserAgent,
-		&accountDevice.DeviceInfo,
-		&accountDevice.CreatedAt,
-		&accountDevice.UpdatedAt,
-		&accountDevice.DeletedAt,
-	)
+	err := scanAccountDevice(r.dbtx.QueryRowContext(ctx, q, hash), &accountDevice)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("[postgres][account_device_repository][GetDeviceByHash][QueryRowContext] Error: %w", err)
+	}
+
+	return &accountDevice, nil
+}
+
+func (r *accountDeviceRepositoryPostgres) GetDeviceById(ctx context.Context, accountId, deviceId int64) (*entity.AccountDevice, error) {
+	q := `
+		SELECT ` + accountDeviceColumns + `
+		FROM account_devices
+		WHERE device_id = $1
+			AND account_id = $2
+			AND deleted_at IS NULL
+	`
+
+	var accountDevice entity.AccountDevice
+
+	err := scanAccountDevice(r.dbtx.QueryRowContext(ctx, q, deviceId, accountId), &accountDevice)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("[postgres][account_device_repository][GetDeviceById][QueryRowContext] Error: %w", err)
+	}
+
+	return &accountDevice, nil
+}
+
+func (r *accountDeviceRepositoryPostgres) GetDevicesByAccountId(ctx context.Context, accountId int64) ([]entity.AccountDevice, error) {
+	q := `
+		SELECT ` + accountDeviceColumns + `
+		FROM account_devices
+		WHERE account_id = $1
+			AND deleted_at IS NULL
+		ORDER BY last_seen_at DESC
+	`
+
+	rows, err := r.dbtx.QueryContext(ctx, q, accountId)
+	if err != nil {
+		return nil, fmt.Errorf("[postgres][account_device_repository][GetDevicesByAccountId][QueryContext] Error: %w", err)
+	}
+	defer rows.Close()
+
+	accountDevices := []entity.AccountDevice{}
+
+	for rows.Next() {
+		var accountDevice entity.AccountDevice
+
+		err = scanAccountDevice(rows, &accountDevice)
+		if err != nil {
+			return nil, fmt.Errorf("[postgres][account_device_repository][GetDevicesByAccountId][rows.Scan] Error: %w", err)
+		}
+
+		accountDevices = append(accountDevices, accountDevice)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("[postgres][account_device_repository][GetDevicesByAccountId][rows.Err] Error: %w", err)
+	}
+
+	return accountDevices, nil
+}
+
+func (r *accountDeviceRepositoryPostgres) UpdateDeviceName(ctx context.Context, deviceId int64, name string) error {
+	q := `
+		UPDATE account_devices
+		SET device_name = $2,
+			updated_at = $3
+		WHERE device_id = $1
+			AND deleted_at IS NULL
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, deviceId, name, nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][account_device_repository][UpdateDeviceName][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *accountDeviceRepositoryPostgres) UpdateLastSeen(ctx context.Context, deviceId int64, ipAddress string) error {
+	q := `
+		UPDATE account_devices
+		SET last_seen_at = $2,
+			last_ip = $3
+		WHERE device_id = $1
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, deviceId, nowUnixMilli(), ipAddress)
+	if err != nil {
+		return fmt.Errorf("[postgres][account_device_repository][UpdateLastSeen][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateDeviceDetails refreshes what the device reported at login,
+// including its hash.
+func (r *accountDeviceRepositoryPostgres) UpdateDeviceDetails(ctx context.Context, accountDevice entity.AccountDevice) error {
+	q := `
+		UPDATE account_devices
+		SET user_agent = $2,
+			device_info = $3,
+			browser = $4,
+			os = $5,
+			device_type = $6,
+			platform = $7,
+			os_version = $8,
+			app_version = $9,
+			device_model = $10,
+			push_token = $11,
+			device_hash = $12,
+			updated_at = $13
+		WHERE device_id = $1
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q,
+		accountDevice.DeviceId,
+		accountDevice.UserAgent,
+		accountDevice.DeviceInfo,
+		accountDevice.Browser,
+		accountDevice.Os,
+		accountDevice.DeviceType,
+		accountDevice.Platform,
+		accountDevice.OsVersion,
+		accountDevice.AppVersion,
+		accountDevice.DeviceModel,
+		accountDevice.PushToken,
+		accountDevice.DeviceHash,
+		nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][account_device_repository][UpdateDeviceDetails][ExecContext] Error: %w", translatePgError(err))
+	}
+
+	return nil
+}
+
+func (r *accountDeviceRepositoryPostgres) SoftDeleteDevice(ctx context.Context, deviceId int64) error {
+	q := `
+		UPDATE account_devices
+		SET deleted_at = $2,
+			updated_at = $2
+		WHERE device_id = $1
+			AND deleted_at IS NULL
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, deviceId, nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][account_device_repository][SoftDeleteDevice][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *accountDeviceRepositoryPostgres) SoftDeleteDevicesByAccountId(ctx context.Context, accountId int64) error {
+	q := `
+		UPDATE account_devices
+		SET deleted_at = $2,
+			updated_at = $2
+		WHERE account_id = $1
+			AND deleted_at IS NULL
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, accountId, nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][account_device_repository][SoftDeleteDevicesByAccountId][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteDevicesByAccountId removes the account's devices for good, along
+// with the user agents, IPs and push tokens recorded on them.
+func (r *accountDeviceRepositoryPostgres) DeleteDevicesByAccountId(ctx context.Context, accountId int64) error {
+	q := `
+		DELETE FROM account_devices
+		WHERE account_id = $1
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, accountId)
+	if err != nil {
+		return fmt.Errorf("[postgres][account_device_repository][DeleteDevicesByAccountId][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *accountDeviceRepositoryPostgres) GetDeviceByApprovalTokenHash(ctx context.Context, tokenHash string) (*entity.AccountDevice, error) {
+	q := `
+		SELECT ` + accountDeviceColumns + `
+		FROM account_devices
+		WHERE approval_token_hash = $1
+			AND deleted_at IS NULL
+	`
+
+	var accountDevice entity.AccountDevice
+
+	err := scanAccountDevice(r.dbtx.QueryRowContext(ctx, q, tokenHash), &accountDevice)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
 
-		return nil, err
+		return nil, fmt.Errorf("[postgres][account_device_repository][GetDeviceByApprovalTokenHash][QueryRowContext] Error: %w", err)
 	}
 
 	return &accountDevice, nil
 }
+
+func (r *accountDeviceRepositoryPostgres) UpdateApprovalToken(ctx context.Context, deviceId int64, tokenHash string, expiredAt int64) error {
+	q := `
+		UPDATE account_devices
+		SET approval_token_hash = $2,
+			approval_expired_at = $3,
+			updated_at = $4
+		WHERE device_id = $1
+			AND approved_at IS NULL
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, deviceId, tokenHash, expiredAt, nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][account_device_repository][UpdateApprovalToken][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *accountDeviceRepositoryPostgres) ApproveDevice(ctx context.Context, deviceId int64) error {
+	q := `
+		UPDATE account_devices
+		SET approved_at = $2,
+			approval_token_hash = NULL,
+			approval_expired_at = NULL,
+			updated_at = $2
+		WHERE device_id = $1
+			AND approved_at IS NULL
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, deviceId, nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][account_device_repository][ApproveDevice][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
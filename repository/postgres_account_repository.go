@@ -5,10 +5,33 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/michaelyusak/go-auth/entity"
 )
 
+const accountColumns = `account_id, tenant_id, account_name, account_email, account_phone_number, account_password, account_status, status_reason,
+	status_until, password_reset_required, password_reset_expired_at, created_at, updated_at, deleted_at`
+
+func scanAccount(row rowScanner, account *entity.Account) error {
+	return row.Scan(
+		&account.Id,
+		&account.TenantId,
+		&account.Name,
+		&account.Email,
+		&account.PhoneNumber,
+		&account.Password,
+		&account.Status,
+		&account.StatusReason,
+		&account.StatusUntil,
+		&account.PasswordResetRequired,
+		&account.PasswordResetExpiredAt,
+		&account.CreatedAt,
+		&account.UpdatedAt,
+		&account.DeletedAt,
+	)
+}
+
 type accountRepositoryPostgres struct {
 	dbtx DBTX
 }
@@ -19,26 +42,23 @@ func NewAccountRepositoryPostgres(dbtx DBTX) *accountRepositoryPostgres {
 	}
 }
 
-func (r *accountRepositoryPostgres) GetAccountByEmail(ctx context.Context, email string) (*entity.Account, error) {
+// GetAccountByEmail, GetAccountByPhoneNumber and GetAccountByName look the
+// account up within a tenant, as the same email, phone number or name may be
+// used in other tenants. They also return deleted accounts that are not
+// purged yet, so their email, phone number and name stay reserved through
+// the grace period and logging in can restore them. Callers check DeletedAt.
+func (r *accountRepositoryPostgres) GetAccountByEmail(ctx context.Context, tenantId, email string) (*entity.Account, error) {
 	q := `
-		SELECT account_id, account_name, account_email, account_phone_number, account_password, created_at, updated_at, deleted_at
+		SELECT ` + accountColumns + `
 		FROM accounts
-		WHERE account_email = $1
-			AND deleted_at IS NULL
+		WHERE tenant_id = $1
+			AND account_email = $2
+			AND purged_at IS NULL
 	`
 
 	var account entity.Account
 
-	err := r.dbtx.QueryRowContext(ctx, q, email).Scan(
-		&account.Id,
-		&account.Name,
-		&account.Email,
-		&account.PhoneNumber,
-		&account.Password,
-		&account.CreatedAt,
-		&account.UpdatedAt,
-		&account.DeletedAt,
-	)
+	err := scanAccount(r.dbtx.QueryRowContext(ctx, q, tenantId, email), &account)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -50,26 +70,18 @@ func (r *accountRepositoryPostgres) GetAccountByEmail(ctx context.Context, email
 	return &account, nil
 }
 
-func (r *accountRepositoryPostgres) GetAccountByPhoneNumber(ctx context.Context, phoneNumber string) (*entity.Account, error) {
+func (r *accountRepositoryPostgres) GetAccountByPhoneNumber(ctx context.Context, tenantId, phoneNumber string) (*entity.Account, error) {
 	q := `
-	SELECT account_id, account_name, account_email, account_phone_number, account_password, created_at, updated_at, deleted_at
+	SELECT ` + accountColumns + `
 	FROM accounts
-	WHERE account_phone_number = $1
-		AND deleted_at IS NULL
+	WHERE tenant_id = $1
+		AND account_phone_number = $2
+		AND purged_at IS NULL
 	`
 
 	var account entity.Account
 
-	err := r.dbtx.QueryRowContext(ctx, q, phoneNumber).Scan(
-		&account.Id,
-		&account.Name,
-		&account.Email,
-		&account.PhoneNumber,
-		&account.Password,
-		&account.CreatedAt,
-		&account.UpdatedAt,
-		&account.DeletedAt,
-	)
+	err := scanAccount(r.dbtx.QueryRowContext(ctx, q, tenantId, phoneNumber), &account)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -96,14 +108,15 @@ func (r *accountRepositoryPostgres) Lock(ctx context.Context) error {
 
 func (r *accountRepositoryPostgres) Register(ctx context.Context, newAccount entity.Account) (int64, error) {
 	q := `
-		INSERT INTO accounts (account_name, account_email, account_phone_number, account_password, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $5)
+		INSERT INTO accounts (tenant_id, account_name, account_email, account_phone_number, account_password, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
 		RETURNING account_id
 	`
 
 	var accountId int64
 
 	err := r.dbtx.QueryRowContext(ctx, q,
+		newAccount.TenantId,
 		newAccount.Name,
 		newAccount.Email,
 		newAccount.PhoneNumber,
@@ -116,26 +129,18 @@ func (r *accountRepositoryPostgres) Register(ctx context.Context, newAccount ent
 	return accountId, nil
 }
 
-func (r *accountRepositoryPostgres) GetAccountByName(ctx context.Context, name string) (*entity.Account, error) {
+func (r *accountRepositoryPostgres) GetAccountByName(ctx context.Context, tenantId, name string) (*entity.Account, error) {
 	q := `
-	SELECT account_id, account_name, account_email, account_phone_number, account_password, created_at, updated_at, deleted_at
+	SELECT ` + accountColumns + `
 	FROM accounts
-	WHERE account_name = $1
-		AND deleted_at IS NULL
+	WHERE tenant_id = $1
+		AND account_name = $2
+		AND purged_at IS NULL
 	`
 
 	var account entity.Account
 
-	err := r.dbtx.QueryRowContext(ctx, q, name).Scan(
-		&account.Id,
-		&account.Name,
-		&account.Email,
-		&account.PhoneNumber,
-		&account.Password,
-		&account.CreatedAt,
-		&account.UpdatedAt,
-		&account.DeletedAt,
-	)
+	err := scanAccount(r.dbtx.QueryRowContext(ctx, q, tenantId, name), &account)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -146,3 +151,305 @@ func (r *accountRepositoryPostgres) GetAccountByName(ctx context.Context, name s
 
 	return &account, nil
 }
+
+func (r *accountRepositoryPostgres) GetAccountById(ctx context.Context, accountId int64) (*entity.Account, error) {
+	q := `
+		SELECT ` + accountColumns + `
+		FROM accounts
+		WHERE account_id = $1
+			AND deleted_at IS NULL
+	`
+
+	var account entity.Account
+
+	err := scanAccount(r.dbtx.QueryRowContext(ctx, q, accountId), &account)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("[postgres][account_repository][GetAccountById][QueryRowContext] Error: %w", err)
+	}
+
+	return &account, nil
+}
+
+func (r *accountRepositoryPostgres) UpdateAccount(ctx context.Context, account entity.Account) error {
+	q := `
+		UPDATE accounts
+		SET account_name = $2,
+			account_email = $3,
+			account_phone_number = $4,
+			updated_at = $5
+		WHERE account_id = $1
+			AND deleted_at IS NULL
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q,
+		account.Id,
+		account.Name,
+		account.Email,
+		account.PhoneNumber,
+		nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][account_repository][UpdateAccount][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *accountRepositoryPostgres) SoftDeleteAccount(ctx context.Context, accountId int64) error {
+	q := `
+		UPDATE accounts
+		SET deleted_at = $2,
+			updated_at = $2
+		WHERE account_id = $1
+			AND deleted_at IS NULL
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, accountId, nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][account_repository][SoftDeleteAccount][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *accountRepositoryPostgres) RestoreAccount(ctx context.Context, accountId int64) error {
+	q := `
+		UPDATE accounts
+		SET deleted_at = NULL,
+			updated_at = $2
+		WHERE account_id = $1
+			AND purged_at IS NULL
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, accountId, nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][account_repository][RestoreAccount][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+// GetAccountIdsToPurge returns up to limit accounts deleted before
+// deletedBefore that still hold personal data, oldest first.
+func (r *accountRepositoryPostgres) GetAccountIdsToPurge(ctx context.Context, deletedBefore int64, limit int) ([]int64, error) {
+	q := `
+		SELECT account_id
+		FROM accounts
+		WHERE deleted_at IS NOT NULL
+			AND deleted_at <= $1
+			AND purged_at IS NULL
+		ORDER BY deleted_at
+		LIMIT $2
+	`
+
+	rows, err := r.dbtx.QueryContext(ctx, q, deletedBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("[postgres][account_repository][GetAccountIdsToPurge][QueryContext] Error: %w", err)
+	}
+	defer rows.Close()
+
+	accountIds := []int64{}
+
+	for rows.Next() {
+		var accountId int64
+
+		err = rows.Scan(&accountId)
+		if err != nil {
+			return nil, fmt.Errorf("[postgres][account_repository][GetAccountIdsToPurge][rows.Scan] Error: %w", err)
+		}
+
+		accountIds = append(accountIds, accountId)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("[postgres][account_repository][GetAccountIdsToPurge][rows.Err] Error: %w", err)
+	}
+
+	return accountIds, nil
+}
+
+// PurgeAccount erases the account's personal data if it was deleted before
+// deletedBefore, and reports whether it did. The row itself is kept, so the
+// audit log's references to the account id stay valid.
+func (r *accountRepositoryPostgres) PurgeAccount(ctx context.Context, accountId, deletedBefore int64) (bool, error) {
+	q := `
+		UPDATE accounts
+		SET account_name = '',
+			account_email = '',
+			account_phone_number = '',
+			account_password = '',
+			purged_at = $3,
+			updated_at = $3
+		WHERE account_id = $1
+			AND deleted_at <= $2
+			AND purged_at IS NULL
+	`
+
+	res, err := r.dbtx.ExecContext(ctx, q, accountId, deletedBefore, nowUnixMilli())
+	if err != nil {
+		return false, fmt.Errorf("[postgres][account_repository][PurgeAccount][ExecContext] Error: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("[postgres][account_repository][PurgeAccount][RowsAffected] Error: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *accountRepositoryPostgres) UpdateStatus(ctx context.Context, accountId int64, status, reason string, until *int64) error {
+	q := `
+		UPDATE accounts
+		SET account_status = $2,
+			status_reason = $3,
+			status_until = $4,
+			updated_at = $5
+		WHERE account_id = $1
+			AND deleted_at IS NULL
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, accountId, status, reason, until, nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][account_repository][UpdateStatus][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+// SearchAccounts returns up to filter.Limit accounts that are not deleted,
+// newest first, whose email, phone number and name start with the given
+// prefixes.
+func (r *accountRepositoryPostgres) SearchAccounts(ctx context.Context, filter entity.AccountFilter) ([]entity.Account, error) {
+	conditions := []string{"deleted_at IS NULL"}
+	args := []any{}
+
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.TenantId != "" {
+		where("tenant_id = $%d", filter.TenantId)
+	}
+	if filter.EmailPrefix != "" {
+		where("account_email LIKE $%d", likePrefix(filter.EmailPrefix))
+	}
+	if filter.PhoneNumberPrefix != "" {
+		where("account_phone_number LIKE $%d", likePrefix(filter.PhoneNumberPrefix))
+	}
+	if filter.NamePrefix != "" {
+		where("account_name LIKE $%d", likePrefix(filter.NamePrefix))
+	}
+	if filter.Before > 0 {
+		where("account_id < $%d", filter.Before)
+	}
+
+	args = append(args, filter.Limit)
+
+	q := `
+		SELECT ` + accountColumns + `
+		FROM accounts
+		WHERE ` + strings.Join(conditions, " AND ") + fmt.Sprintf(`
+		ORDER BY account_id DESC
+		LIMIT $%d
+	`, len(args))
+
+	rows, err := r.dbtx.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("[postgres][account_repository][SearchAccounts][QueryContext] Error: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []entity.Account{}
+
+	for rows.Next() {
+		var account entity.Account
+
+		err = scanAccount(rows, &account)
+		if err != nil {
+			return nil, fmt.Errorf("[postgres][account_repository][SearchAccounts][rows.Scan] Error: %w", err)
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("[postgres][account_repository][SearchAccounts][rows.Err] Error: %w", err)
+	}
+
+	return accounts, nil
+}
+
+// likePrefix is a LIKE pattern matching values that start with prefix, with
+// the wildcards in prefix itself escaped.
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+}
+
+// RequirePasswordReset blocks logging in to the account until its password
+// is reset with the token, replacing any earlier token.
+func (r *accountRepositoryPostgres) RequirePasswordReset(ctx context.Context, accountId int64, tokenHash string, expiredAt int64) error {
+	q := `
+		UPDATE accounts
+		SET password_reset_required = TRUE,
+			password_reset_token_hash = $2,
+			password_reset_expired_at = $3,
+			updated_at = $4
+		WHERE account_id = $1
+			AND deleted_at IS NULL
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, accountId, tokenHash, expiredAt, nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][account_repository][RequirePasswordReset][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *accountRepositoryPostgres) GetAccountByPasswordResetTokenHash(ctx context.Context, tokenHash string) (*entity.Account, error) {
+	q := `
+		SELECT ` + accountColumns + `
+		FROM accounts
+		WHERE password_reset_token_hash = $1
+			AND deleted_at IS NULL
+	`
+
+	var account entity.Account
+
+	err := scanAccount(r.dbtx.QueryRowContext(ctx, q, tokenHash), &account)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("[postgres][account_repository][GetAccountByPasswordResetTokenHash][QueryRowContext] Error: %w", err)
+	}
+
+	return &account, nil
+}
+
+// ResetPassword sets the account's password and clears a pending reset.
+func (r *accountRepositoryPostgres) ResetPassword(ctx context.Context, accountId int64, password string) error {
+	q := `
+		UPDATE accounts
+		SET account_password = $2,
+			password_reset_required = FALSE,
+			password_reset_token_hash = NULL,
+			password_reset_expired_at = NULL,
+			updated_at = $3
+		WHERE account_id = $1
+			AND deleted_at IS NULL
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, accountId, password, nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][account_repository][ResetPassword][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
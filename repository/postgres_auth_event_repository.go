@@ -0,0 +1,463 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/michaelyusak/go-auth/entity"
+)
+
+// authEventChainLockKey serializes appends to the auth event chain, so each
+// record is hashed against the one committed right before it.
+const authEventChainLockKey = 7310598233412
+
+// The client IP and user agent live in auth_event_details, outside of the
+// hash chain, so they can be erased without breaking it.
+const authEventColumns = `e.auth_event_id, e.event_type, e.account_id, e.device_id, e.actor_account_id, COALESCE(d.ip_address, ''),
+	COALESCE(d.user_agent, ''), e.request_id, e.outcome, e.reason, e.prev_hash, e.hash, e.created_at`
+
+const authEventTables = `auth_events e
+	LEFT JOIN auth_event_details d ON d.auth_event_id = e.auth_event_id`
+
+func scanAuthEvent(row rowScanner, event *entity.AuthEvent) error {
+	return row.Scan(
+		&event.AuthEventId,
+		&event.EventType,
+		&event.AccountId,
+		&event.DeviceId,
+		&event.ActorAccountId,
+		&event.IpAddress,
+		&event.UserAgent,
+		&event.RequestId,
+		&event.Outcome,
+		&event.Reason,
+		&event.PrevHash,
+		&event.Hash,
+		&event.CreatedAt,
+	)
+}
+
+// authEventHashVersion leads the hashed fields, so the field list can
+// change later without old and new hashes being mistaken for one another.
+const authEventHashVersion = 1
+
+// AuthEventHash is the chain hash of an event: SHA-256 over the previous
+// record's hash followed by the event's fields. Every field is always
+// hashed, unset ones as null, except the client IP and user agent, which
+// are personal data erased when the account is purged.
+func AuthEventHash(prevHash string, event entity.AuthEvent) string {
+	fields, _ := json.Marshal([]any{
+		authEventHashVersion,
+		event.AuthEventId,
+		event.EventType,
+		event.AccountId,
+		event.DeviceId,
+		event.ActorAccountId,
+		event.RequestId,
+		event.Outcome,
+		event.Reason,
+		event.CreatedAt,
+	})
+
+	sum := sha256.Sum256(append([]byte(prevHash), fields...))
+
+	return hex.EncodeToString(sum[:])
+}
+
+type authEventRepositoryPostgres struct {
+	dbtx DBTX
+}
+
+func NewAuthEventRepositoryPostgres(dbtx DBTX) *authEventRepositoryPostgres {
+	return &authEventRepositoryPostgres{
+		dbtx: dbtx,
+	}
+}
+
+// InsertEvent appends the event to the chain. It must run in a READ
+// COMMITTED transaction: the chain lock is held until the transaction ends,
+// and only a statement snapshot taken after the lock sees the head the
+// previous holder committed. Under a stale head the insert fails with
+// ErrAuthEventChainForked rather than forking the chain.
+func (r *authEventRepositoryPostgres) InsertEvent(ctx context.Context, event entity.AuthEvent) error {
+	_, err := r.dbtx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, authEventChainLockKey)
+	if err != nil {
+		return fmt.Errorf("[postgres][auth_event_repository][InsertEvent][ExecContext] lock | Error: %w", err)
+	}
+
+	head, err := r.GetChainHead(ctx)
+	if err != nil {
+		return fmt.Errorf("[postgres][auth_event_repository][InsertEvent][GetChainHead] Error: %w", err)
+	}
+
+	if head != nil {
+		event.PrevHash = head.Hash
+	}
+
+	err = r.dbtx.QueryRowContext(ctx, `SELECT nextval(pg_get_serial_sequence('auth_events', 'auth_event_id'))`).Scan(&event.AuthEventId)
+	if err != nil {
+		return fmt.Errorf("[postgres][auth_event_repository][InsertEvent][QueryRowContext] nextval | Error: %w", err)
+	}
+
+	event.Hash = AuthEventHash(event.PrevHash, event)
+
+	q := `
+		INSERT INTO auth_events (auth_event_id, event_type, account_id, device_id, actor_account_id, request_id,
+			outcome, reason, prev_hash, hash, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	_, err = r.dbtx.ExecContext(ctx, q,
+		event.AuthEventId,
+		event.EventType,
+		event.AccountId,
+		event.DeviceId,
+		event.ActorAccountId,
+		event.RequestId,
+		event.Outcome,
+		event.Reason,
+		event.PrevHash,
+		event.Hash,
+		event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("[postgres][auth_event_repository][InsertEvent][ExecContext] Error: %w", translatePgError(err))
+	}
+
+	if event.IpAddress == "" && event.UserAgent == "" {
+		return nil
+	}
+
+	q = `
+		INSERT INTO auth_event_details (auth_event_id, ip_address, user_agent)
+		VALUES ($1, $2, $3)
+	`
+
+	_, err = r.dbtx.ExecContext(ctx, q, event.AuthEventId, event.IpAddress, event.UserAgent)
+	if err != nil {
+		return fmt.Errorf("[postgres][auth_event_repository][InsertEvent][ExecContext] details | Error: %w", err)
+	}
+
+	return nil
+}
+
+// QueueEvent writes the event to the outbox, to be appended to the chain by
+// AppendQueuedEvents. Run in the transaction of the change the event
+// records, it is committed with the change or not at all.
+func (r *authEventRepositoryPostgres) QueueEvent(ctx context.Context, event entity.AuthEvent) error {
+	q := `
+		INSERT INTO auth_event_outbox (event_type, account_id, device_id, actor_account_id, ip_address, user_agent,
+			request_id, outcome, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q,
+		event.EventType,
+		event.AccountId,
+		event.DeviceId,
+		event.ActorAccountId,
+		event.IpAddress,
+		event.UserAgent,
+		event.RequestId,
+		event.Outcome,
+		event.Reason,
+		event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("[postgres][auth_event_repository][QueueEvent][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+// AppendQueuedEvents moves up to limit of the oldest queued events into the
+// chain, returning how many were moved. Like InsertEvent it must run in a
+// READ COMMITTED transaction; the chain lock is taken before reading the
+// outbox, so concurrent callers do not append the same event twice.
+func (r *authEventRepositoryPostgres) AppendQueuedEvents(ctx context.Context, limit int) (int, error) {
+	_, err := r.dbtx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, authEventChainLockKey)
+	if err != nil {
+		return 0, fmt.Errorf("[postgres][auth_event_repository][AppendQueuedEvents][ExecContext] lock | Error: %w", err)
+	}
+
+	q := `
+		SELECT auth_event_outbox_id, event_type, account_id, device_id, actor_account_id, ip_address, user_agent,
+			request_id, outcome, reason, created_at
+		FROM auth_event_outbox
+		ORDER BY auth_event_outbox_id
+		LIMIT $1
+	`
+
+	rows, err := r.dbtx.QueryContext(ctx, q, limit)
+	if err != nil {
+		return 0, fmt.Errorf("[postgres][auth_event_repository][AppendQueuedEvents][QueryContext] Error: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		outboxIds []int64
+		events    []entity.AuthEvent
+	)
+
+	for rows.Next() {
+		var (
+			outboxId int64
+			event    entity.AuthEvent
+		)
+
+		err = rows.Scan(
+			&outboxId,
+			&event.EventType,
+			&event.AccountId,
+			&event.DeviceId,
+			&event.ActorAccountId,
+			&event.IpAddress,
+			&event.UserAgent,
+			&event.RequestId,
+			&event.Outcome,
+			&event.Reason,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("[postgres][auth_event_repository][AppendQueuedEvents][rows.Scan] Error: %w", err)
+		}
+
+		outboxIds = append(outboxIds, outboxId)
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("[postgres][auth_event_repository][AppendQueuedEvents][rows.Err] Error: %w", err)
+	}
+
+	rows.Close()
+
+	q = `
+		DELETE FROM auth_event_outbox
+		WHERE auth_event_outbox_id = $1
+	`
+
+	for i, event := range events {
+		err = r.InsertEvent(ctx, event)
+		if err != nil {
+			return 0, fmt.Errorf("[postgres][auth_event_repository][AppendQueuedEvents][InsertEvent] Error: %w", err)
+		}
+
+		_, err = r.dbtx.ExecContext(ctx, q, outboxIds[i])
+		if err != nil {
+			return 0, fmt.Errorf("[postgres][auth_event_repository][AppendQueuedEvents][ExecContext] delete | Error: %w", err)
+		}
+	}
+
+	return len(events), nil
+}
+
+// DeleteEventDetailsByAccountId erases the client IP and user agent of the
+// events the account acted in: its own, and the ones where it acted on
+// another account as an admin. Events an admin recorded on the account keep
+// the admin's.
+func (r *authEventRepositoryPostgres) DeleteEventDetailsByAccountId(ctx context.Context, accountId int64) error {
+	q := `
+		DELETE FROM auth_event_details d
+		USING auth_events e
+		WHERE d.auth_event_id = e.auth_event_id
+			AND ((e.account_id = $1 AND e.actor_account_id IS NULL) OR e.actor_account_id = $1)
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, accountId)
+	if err != nil {
+		return fmt.Errorf("[postgres][auth_event_repository][DeleteEventDetailsByAccountId][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *authEventRepositoryPostgres) GetEvents(ctx context.Context, filter entity.AuthEventFilter) ([]entity.AuthEvent, error) {
+	conditions := []string{}
+	args := []any{}
+
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.TenantId != "" {
+		where(`EXISTS (
+			SELECT 1
+			FROM accounts a
+			WHERE a.account_id = COALESCE(e.account_id, e.actor_account_id)
+				AND a.tenant_id = $%d
+		)`, filter.TenantId)
+	}
+	if filter.AccountId != nil {
+		where("e.account_id = $%d", *filter.AccountId)
+	}
+	if filter.ActorAccountId != nil {
+		where("e.actor_account_id = $%d", *filter.ActorAccountId)
+	}
+	if filter.EventType != "" {
+		where("e.event_type = $%d", filter.EventType)
+	}
+	if filter.Outcome != "" {
+		where("e.outcome = $%d", filter.Outcome)
+	}
+	if filter.IpAddress != "" {
+		where("d.ip_address = $%d", filter.IpAddress)
+	}
+	if filter.From > 0 {
+		where("e.created_at >= $%d", filter.From)
+	}
+	if filter.To > 0 {
+		where("e.created_at < $%d", filter.To)
+	}
+	if filter.Before > 0 {
+		where("e.auth_event_id < $%d", filter.Before)
+	}
+
+	q := `
+		SELECT ` + authEventColumns + `
+		FROM ` + authEventTables + `
+	`
+
+	if len(conditions) > 0 {
+		q += "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, filter.Limit)
+	q += fmt.Sprintf(" ORDER BY e.auth_event_id DESC LIMIT $%d", len(args))
+
+	return r.queryEvents(ctx, "GetEvents", q, args...)
+}
+
+// GetChainHead returns the most recent event, or nil when there is none.
+func (r *authEventRepositoryPostgres) GetChainHead(ctx context.Context) (*entity.AuthEvent, error) {
+	q := `
+		SELECT ` + authEventColumns + `
+		FROM ` + authEventTables + `
+		ORDER BY e.auth_event_id DESC
+		LIMIT 1
+	`
+
+	var event entity.AuthEvent
+
+	err := scanAuthEvent(r.dbtx.QueryRowContext(ctx, q), &event)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("[postgres][auth_event_repository][GetChainHead][QueryRowContext] Error: %w", err)
+	}
+
+	return &event, nil
+}
+
+// GetEventsAfter returns up to limit events following afterId in chain
+// order.
+func (r *authEventRepositoryPostgres) GetEventsAfter(ctx context.Context, afterId int64, limit int) ([]entity.AuthEvent, error) {
+	q := `
+		SELECT ` + authEventColumns + `
+		FROM ` + authEventTables + `
+		WHERE e.auth_event_id > $1
+		ORDER BY e.auth_event_id ASC
+		LIMIT $2
+	`
+
+	return r.queryEvents(ctx, "GetEventsAfter", q, afterId, limit)
+}
+
+func (r *authEventRepositoryPostgres) queryEvents(ctx context.Context, caller, q string, args ...any) ([]entity.AuthEvent, error) {
+	rows, err := r.dbtx.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("[postgres][auth_event_repository][%s][QueryContext] Error: %w", caller, err)
+	}
+	defer rows.Close()
+
+	events := []entity.AuthEvent{}
+
+	for rows.Next() {
+		var event entity.AuthEvent
+
+		err = scanAuthEvent(rows, &event)
+		if err != nil {
+			return nil, fmt.Errorf("[postgres][auth_event_repository][%s][rows.Scan] Error: %w", caller, err)
+		}
+
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("[postgres][auth_event_repository][%s][rows.Err] Error: %w", caller, err)
+	}
+
+	return events, nil
+}
+
+func (r *authEventRepositoryPostgres) InsertCheckpoint(ctx context.Context, checkpoint entity.AuthEventCheckpoint) error {
+	q := `
+		INSERT INTO auth_event_checkpoints (auth_event_id, hash, signature, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, checkpoint.AuthEventId, checkpoint.Hash, checkpoint.Signature, nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][auth_event_repository][InsertCheckpoint][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *authEventRepositoryPostgres) GetLatestCheckpoint(ctx context.Context) (*entity.AuthEventCheckpoint, error) {
+	checkpoints, err := r.getCheckpoints(ctx, "GetLatestCheckpoint", "ORDER BY auth_event_id DESC LIMIT 1")
+	if err != nil || len(checkpoints) == 0 {
+		return nil, err
+	}
+
+	return &checkpoints[0], nil
+}
+
+func (r *authEventRepositoryPostgres) GetCheckpoints(ctx context.Context) ([]entity.AuthEventCheckpoint, error) {
+	return r.getCheckpoints(ctx, "GetCheckpoints", "ORDER BY auth_event_id ASC")
+}
+
+func (r *authEventRepositoryPostgres) getCheckpoints(ctx context.Context, caller, orderBy string) ([]entity.AuthEventCheckpoint, error) {
+	q := `
+		SELECT checkpoint_id, auth_event_id, hash, signature, created_at
+		FROM auth_event_checkpoints
+		` + orderBy
+
+	rows, err := r.dbtx.QueryContext(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("[postgres][auth_event_repository][%s][QueryContext] Error: %w", caller, err)
+	}
+	defer rows.Close()
+
+	checkpoints := []entity.AuthEventCheckpoint{}
+
+	for rows.Next() {
+		var checkpoint entity.AuthEventCheckpoint
+
+		err = rows.Scan(
+			&checkpoint.CheckpointId,
+			&checkpoint.AuthEventId,
+			&checkpoint.Hash,
+			&checkpoint.Signature,
+			&checkpoint.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("[postgres][auth_event_repository][%s][rows.Scan] Error: %w", caller, err)
+		}
+
+		checkpoints = append(checkpoints, checkpoint)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("[postgres][auth_event_repository][%s][rows.Err] Error: %w", caller, err)
+	}
+
+	return checkpoints, nil
+}
@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/michaelyusak/go-auth/entity"
+)
+
+const oauthClientColumns = `client_id, tenant_id, client_name, client_secret_hash, redirect_uris, allowed_scopes, created_at, updated_at`
+
+const authorizationCodeColumns = `authorization_code_id, code_hash, client_id, account_id, device_id, redirect_uri, scope, code_challenge, expired_at,
+	used_at, created_at`
+
+// scanOAuthClient splits the space separated redirect URIs and scopes.
+func scanOAuthClient(row rowScanner, client *entity.OAuthClient) error {
+	var redirectUris, scopes string
+
+	err := row.Scan(
+		&client.ClientId,
+		&client.TenantId,
+		&client.Name,
+		&client.SecretHash,
+		&redirectUris,
+		&scopes,
+		&client.CreatedAt,
+		&client.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	client.RedirectUris = strings.Fields(redirectUris)
+	client.Scopes = strings.Fields(scopes)
+
+	return nil
+}
+
+func scanAuthorizationCode(row rowScanner, code *entity.OAuthAuthorizationCode) error {
+	return row.Scan(
+		&code.AuthorizationCodeId,
+		&code.CodeHash,
+		&code.ClientId,
+		&code.AccountId,
+		&code.DeviceId,
+		&code.RedirectUri,
+		&code.Scope,
+		&code.CodeChallenge,
+		&code.ExpiredAt,
+		&code.UsedAt,
+		&code.CreatedAt,
+	)
+}
+
+// oauthRepositoryPostgres covers OAuth clients and the authorization codes
+// issued to them.
+type oauthRepositoryPostgres struct {
+	dbtx DBTX
+}
+
+func NewOAuthRepositoryPostgres(dbtx DBTX) *oauthRepositoryPostgres {
+	return &oauthRepositoryPostgres{
+		dbtx: dbtx,
+	}
+}
+
+func (r *oauthRepositoryPostgres) GetClients(ctx context.Context, tenantId string) ([]entity.OAuthClient, error) {
+	q := `
+		SELECT ` + oauthClientColumns + `
+		FROM oauth_clients
+		WHERE tenant_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.dbtx.QueryContext(ctx, q, tenantId)
+	if err != nil {
+		return nil, fmt.Errorf("[postgres][oauth_repository][GetClients][QueryContext] Error: %w", err)
+	}
+	defer rows.Close()
+
+	clients := []entity.OAuthClient{}
+
+	for rows.Next() {
+		var client entity.OAuthClient
+
+		err = scanOAuthClient(rows, &client)
+		if err != nil {
+			return nil, fmt.Errorf("[postgres][oauth_repository][GetClients][rows.Scan] Error: %w", err)
+		}
+
+		clients = append(clients, client)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("[postgres][oauth_repository][GetClients][rows.Err] Error: %w", err)
+	}
+
+	return clients, nil
+}
+
+func (r *oauthRepositoryPostgres) GetClientById(ctx context.Context, clientId string) (*entity.OAuthClient, error) {
+	q := `
+		SELECT ` + oauthClientColumns + `
+		FROM oauth_clients
+		WHERE client_id = $1
+	`
+
+	var client entity.OAuthClient
+
+	err := scanOAuthClient(r.dbtx.QueryRowContext(ctx, q, clientId), &client)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("[postgres][oauth_repository][GetClientById][QueryRowContext] Error: %w", err)
+	}
+
+	return &client, nil
+}
+
+func (r *oauthRepositoryPostgres) InsertClient(ctx context.Context, client entity.OAuthClient) error {
+	q := `
+		INSERT INTO oauth_clients (client_id, tenant_id, client_name, client_secret_hash, redirect_uris, allowed_scopes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q,
+		client.ClientId,
+		client.TenantId,
+		client.Name,
+		client.SecretHash,
+		strings.Join(client.RedirectUris, " "),
+		strings.Join(client.Scopes, " "),
+		nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][oauth_repository][InsertClient][ExecContext] Error: %w", translatePgError(err))
+	}
+
+	return nil
+}
+
+// UpdateClient replaces the name, redirect URIs and scopes of the client.
+func (r *oauthRepositoryPostgres) UpdateClient(ctx context.Context, client entity.OAuthClient) error {
+	q := `
+		UPDATE oauth_clients
+		SET client_name = $2,
+			redirect_uris = $3,
+			allowed_scopes = $4,
+			updated_at = $5
+		WHERE client_id = $1
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q,
+		client.ClientId,
+		client.Name,
+		strings.Join(client.RedirectUris, " "),
+		strings.Join(client.Scopes, " "),
+		nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][oauth_repository][UpdateClient][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteClient deletes the client along with its authorization codes and
+// sessions, reporting whether it existed.
+func (r *oauthRepositoryPostgres) DeleteClient(ctx context.Context, clientId string) (bool, error) {
+	q := `
+		DELETE FROM oauth_clients
+		WHERE client_id = $1
+	`
+
+	res, err := r.dbtx.ExecContext(ctx, q, clientId)
+	if err != nil {
+		return false, fmt.Errorf("[postgres][oauth_repository][DeleteClient][ExecContext] Error: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("[postgres][oauth_repository][DeleteClient][RowsAffected] Error: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *oauthRepositoryPostgres) InsertAuthorizationCode(ctx context.Context, code entity.OAuthAuthorizationCode) error {
+	q := `
+		INSERT INTO oauth_authorization_codes (code_hash, client_id, account_id, device_id, redirect_uri, scope, code_challenge, expired_at,
+			created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q,
+		code.CodeHash,
+		code.ClientId,
+		code.AccountId,
+		code.DeviceId,
+		code.RedirectUri,
+		code.Scope,
+		code.CodeChallenge,
+		code.ExpiredAt,
+		nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][oauth_repository][InsertAuthorizationCode][ExecContext] Error: %w", translatePgError(err))
+	}
+
+	return nil
+}
+
+func (r *oauthRepositoryPostgres) GetAuthorizationCodeByHash(ctx context.Context, codeHash string) (*entity.OAuthAuthorizationCode, error) {
+	q := `
+		SELECT ` + authorizationCodeColumns + `
+		FROM oauth_authorization_codes
+		WHERE code_hash = $1
+	`
+
+	var code entity.OAuthAuthorizationCode
+
+	err := scanAuthorizationCode(r.dbtx.QueryRowContext(ctx, q, codeHash), &code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("[postgres][oauth_repository][GetAuthorizationCodeByHash][QueryRowContext] Error: %w", err)
+	}
+
+	return &code, nil
+}
+
+// UseAuthorizationCode marks an unused code as used. It reports false when
+// the code had already been used, e.g. by a concurrent token request.
+func (r *oauthRepositoryPostgres) UseAuthorizationCode(ctx context.Context, authorizationCodeId int64) (bool, error) {
+	q := `
+		UPDATE oauth_authorization_codes
+		SET used_at = $2
+		WHERE authorization_code_id = $1
+			AND used_at IS NULL
+	`
+
+	res, err := r.dbtx.ExecContext(ctx, q, authorizationCodeId, nowUnixMilli())
+	if err != nil {
+		return false, fmt.Errorf("[postgres][oauth_repository][UseAuthorizationCode][ExecContext] Error: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("[postgres][oauth_repository][UseAuthorizationCode][RowsAffected] Error: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,464 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/michaelyusak/go-auth/entity"
+)
+
+const organizationColumns = `organization_id, tenant_id, organization_name, created_by, created_at, updated_at`
+
+// organizationMemberColumns are read from organization_members m joined
+// with accounts a.
+const organizationMemberColumns = `m.organization_id, m.account_id, m.member_role, a.account_name, a.account_email, m.created_at, m.updated_at`
+
+const invitationColumns = `invitation_id, organization_id, email, member_role, token_hash, invited_by, expired_at, accepted_by, accepted_at,
+	revoked_at, created_at, updated_at`
+
+func scanOrganization(row rowScanner, organization *entity.Organization) error {
+	return row.Scan(
+		&organization.OrganizationId,
+		&organization.TenantId,
+		&organization.Name,
+		&organization.CreatedBy,
+		&organization.CreatedAt,
+		&organization.UpdatedAt,
+	)
+}
+
+func scanOrganizationMember(row rowScanner, member *entity.OrganizationMember) error {
+	return row.Scan(
+		&member.OrganizationId,
+		&member.AccountId,
+		&member.Role,
+		&member.Name,
+		&member.Email,
+		&member.CreatedAt,
+		&member.UpdatedAt,
+	)
+}
+
+func scanInvitation(row rowScanner, invitation *entity.OrganizationInvitation) error {
+	return row.Scan(
+		&invitation.InvitationId,
+		&invitation.OrganizationId,
+		&invitation.Email,
+		&invitation.Role,
+		&invitation.TokenHash,
+		&invitation.InvitedBy,
+		&invitation.ExpiredAt,
+		&invitation.AcceptedBy,
+		&invitation.AcceptedAt,
+		&invitation.RevokedAt,
+		&invitation.CreatedAt,
+		&invitation.UpdatedAt,
+	)
+}
+
+// organizationRepositoryPostgres covers organizations, their members and
+// the invitations to join them.
+type organizationRepositoryPostgres struct {
+	dbtx DBTX
+}
+
+func NewOrganizationRepositoryPostgres(dbtx DBTX) *organizationRepositoryPostgres {
+	return &organizationRepositoryPostgres{
+		dbtx: dbtx,
+	}
+}
+
+func (r *organizationRepositoryPostgres) InsertOrganization(ctx context.Context, organization entity.Organization) (int64, error) {
+	q := `
+		INSERT INTO organizations (tenant_id, organization_name, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+		RETURNING organization_id
+	`
+
+	var organizationId int64
+
+	err := r.dbtx.QueryRowContext(ctx, q, organization.TenantId, organization.Name, organization.CreatedBy, nowUnixMilli()).Scan(&organizationId)
+	if err != nil {
+		return organizationId, fmt.Errorf("[postgres][organization_repository][InsertOrganization][QueryRowContext] Error: %w", err)
+	}
+
+	return organizationId, nil
+}
+
+func (r *organizationRepositoryPostgres) GetOrganizationById(ctx context.Context, organizationId int64) (*entity.Organization, error) {
+	return r.getOrganization(ctx, "GetOrganizationById", "", organizationId)
+}
+
+// GetOrganizationForUpdate locks the organization's row until the
+// transaction ends, so changes to its members are made one at a time.
+func (r *organizationRepositoryPostgres) GetOrganizationForUpdate(ctx context.Context, organizationId int64) (*entity.Organization, error) {
+	return r.getOrganization(ctx, "GetOrganizationForUpdate", "FOR UPDATE", organizationId)
+}
+
+func (r *organizationRepositoryPostgres) getOrganization(ctx context.Context, caller, lock string, organizationId int64) (*entity.Organization, error) {
+	q := `
+		SELECT ` + organizationColumns + `
+		FROM organizations
+		WHERE organization_id = $1
+		` + lock
+
+	var organization entity.Organization
+
+	err := scanOrganization(r.dbtx.QueryRowContext(ctx, q, organizationId), &organization)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("[postgres][organization_repository][%s][QueryRowContext] Error: %w", caller, err)
+	}
+
+	return &organization, nil
+}
+
+// GetMembershipsByAccountId returns the organizations the account belongs
+// to, oldest membership first.
+func (r *organizationRepositoryPostgres) GetMembershipsByAccountId(ctx context.Context, accountId int64) ([]entity.OrganizationMembership, error) {
+	q := `
+		SELECT o.organization_id, o.tenant_id, o.organization_name, o.created_by, o.created_at, o.updated_at, m.member_role
+		FROM organization_members m
+		JOIN organizations o ON o.organization_id = m.organization_id
+		WHERE m.account_id = $1
+		ORDER BY m.created_at
+	`
+
+	rows, err := r.dbtx.QueryContext(ctx, q, accountId)
+	if err != nil {
+		return nil, fmt.Errorf("[postgres][organization_repository][GetMembershipsByAccountId][QueryContext] Error: %w", err)
+	}
+	defer rows.Close()
+
+	memberships := []entity.OrganizationMembership{}
+
+	for rows.Next() {
+		var membership entity.OrganizationMembership
+
+		err = rows.Scan(
+			&membership.Organization.OrganizationId,
+			&membership.Organization.TenantId,
+			&membership.Organization.Name,
+			&membership.Organization.CreatedBy,
+			&membership.Organization.CreatedAt,
+			&membership.Organization.UpdatedAt,
+			&membership.Role,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("[postgres][organization_repository][GetMembershipsByAccountId][rows.Scan] Error: %w", err)
+		}
+
+		memberships = append(memberships, membership)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("[postgres][organization_repository][GetMembershipsByAccountId][rows.Err] Error: %w", err)
+	}
+
+	return memberships, nil
+}
+
+func (r *organizationRepositoryPostgres) GetMember(ctx context.Context, organizationId, accountId int64) (*entity.OrganizationMember, error) {
+	q := `
+		SELECT ` + organizationMemberColumns + `
+		FROM organization_members m
+		JOIN accounts a ON a.account_id = m.account_id
+		WHERE m.organization_id = $1
+			AND m.account_id = $2
+	`
+
+	var member entity.OrganizationMember
+
+	err := scanOrganizationMember(r.dbtx.QueryRowContext(ctx, q, organizationId, accountId), &member)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("[postgres][organization_repository][GetMember][QueryRowContext] Error: %w", err)
+	}
+
+	return &member, nil
+}
+
+// GetMembers returns the organization's members, oldest first.
+func (r *organizationRepositoryPostgres) GetMembers(ctx context.Context, organizationId int64) ([]entity.OrganizationMember, error) {
+	q := `
+		SELECT ` + organizationMemberColumns + `
+		FROM organization_members m
+		JOIN accounts a ON a.account_id = m.account_id
+		WHERE m.organization_id = $1
+		ORDER BY m.created_at
+	`
+
+	rows, err := r.dbtx.QueryContext(ctx, q, organizationId)
+	if err != nil {
+		return nil, fmt.Errorf("[postgres][organization_repository][GetMembers][QueryContext] Error: %w", err)
+	}
+	defer rows.Close()
+
+	members := []entity.OrganizationMember{}
+
+	for rows.Next() {
+		var member entity.OrganizationMember
+
+		err = scanOrganizationMember(rows, &member)
+		if err != nil {
+			return nil, fmt.Errorf("[postgres][organization_repository][GetMembers][rows.Scan] Error: %w", err)
+		}
+
+		members = append(members, member)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("[postgres][organization_repository][GetMembers][rows.Err] Error: %w", err)
+	}
+
+	return members, nil
+}
+
+func (r *organizationRepositoryPostgres) CountOwners(ctx context.Context, organizationId int64) (int, error) {
+	q := `
+		SELECT COUNT(*)
+		FROM organization_members
+		WHERE organization_id = $1
+			AND member_role = 'owner'
+	`
+
+	var count int
+
+	err := r.dbtx.QueryRowContext(ctx, q, organizationId).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("[postgres][organization_repository][CountOwners][QueryRowContext] Error: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *organizationRepositoryPostgres) InsertMember(ctx context.Context, member entity.OrganizationMember) error {
+	q := `
+		INSERT INTO organization_members (organization_id, account_id, member_role, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, member.OrganizationId, member.AccountId, member.Role, nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][organization_repository][InsertMember][ExecContext] Error: %w", translatePgError(err))
+	}
+
+	return nil
+}
+
+func (r *organizationRepositoryPostgres) UpdateMemberRole(ctx context.Context, organizationId, accountId int64, role string) error {
+	q := `
+		UPDATE organization_members
+		SET member_role = $3,
+			updated_at = $4
+		WHERE organization_id = $1
+			AND account_id = $2
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, organizationId, accountId, role, nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][organization_repository][UpdateMemberRole][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *organizationRepositoryPostgres) DeleteMember(ctx context.Context, organizationId, accountId int64) error {
+	q := `
+		DELETE FROM organization_members
+		WHERE organization_id = $1
+			AND account_id = $2
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, organizationId, accountId)
+	if err != nil {
+		return fmt.Errorf("[postgres][organization_repository][DeleteMember][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *organizationRepositoryPostgres) DeleteMembershipsByAccountId(ctx context.Context, accountId int64) error {
+	q := `
+		DELETE FROM organization_members
+		WHERE account_id = $1
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, accountId)
+	if err != nil {
+		return fmt.Errorf("[postgres][organization_repository][DeleteMembershipsByAccountId][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *organizationRepositoryPostgres) InsertInvitation(ctx context.Context, invitation entity.OrganizationInvitation) (int64, error) {
+	q := `
+		INSERT INTO organization_invitations (organization_id, email, member_role, token_hash, invited_by, expired_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		RETURNING invitation_id
+	`
+
+	var invitationId int64
+
+	err := r.dbtx.QueryRowContext(ctx, q,
+		invitation.OrganizationId,
+		invitation.Email,
+		invitation.Role,
+		invitation.TokenHash,
+		invitation.InvitedBy,
+		invitation.ExpiredAt,
+		nowUnixMilli()).Scan(&invitationId)
+	if err != nil {
+		return invitationId, fmt.Errorf("[postgres][organization_repository][InsertInvitation][QueryRowContext] Error: %w", translatePgError(err))
+	}
+
+	return invitationId, nil
+}
+
+func (r *organizationRepositoryPostgres) GetInvitationById(ctx context.Context, invitationId int64) (*entity.OrganizationInvitation, error) {
+	return r.getInvitation(ctx, "GetInvitationById", "invitation_id = $1", invitationId)
+}
+
+func (r *organizationRepositoryPostgres) GetInvitationByTokenHash(ctx context.Context, tokenHash string) (*entity.OrganizationInvitation, error) {
+	return r.getInvitation(ctx, "GetInvitationByTokenHash", "token_hash = $1", tokenHash)
+}
+
+func (r *organizationRepositoryPostgres) getInvitation(ctx context.Context, caller, condition string, arg any) (*entity.OrganizationInvitation, error) {
+	q := `
+		SELECT ` + invitationColumns + `
+		FROM organization_invitations
+		WHERE ` + condition
+
+	var invitation entity.OrganizationInvitation
+
+	err := scanInvitation(r.dbtx.QueryRowContext(ctx, q, arg), &invitation)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("[postgres][organization_repository][%s][QueryRowContext] Error: %w", caller, err)
+	}
+
+	return &invitation, nil
+}
+
+// GetPendingInvitations returns the organization's invitations that are
+// neither accepted, revoked nor expired, newest first.
+func (r *organizationRepositoryPostgres) GetPendingInvitations(ctx context.Context, organizationId int64) ([]entity.OrganizationInvitation, error) {
+	q := `
+		SELECT ` + invitationColumns + `
+		FROM organization_invitations
+		WHERE organization_id = $1
+			AND accepted_at IS NULL
+			AND revoked_at IS NULL
+			AND expired_at > $2
+		ORDER BY invitation_id DESC
+	`
+
+	rows, err := r.dbtx.QueryContext(ctx, q, organizationId, nowUnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("[postgres][organization_repository][GetPendingInvitations][QueryContext] Error: %w", err)
+	}
+	defer rows.Close()
+
+	invitations := []entity.OrganizationInvitation{}
+
+	for rows.Next() {
+		var invitation entity.OrganizationInvitation
+
+		err = scanInvitation(rows, &invitation)
+		if err != nil {
+			return nil, fmt.Errorf("[postgres][organization_repository][GetPendingInvitations][rows.Scan] Error: %w", err)
+		}
+
+		invitations = append(invitations, invitation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("[postgres][organization_repository][GetPendingInvitations][rows.Err] Error: %w", err)
+	}
+
+	return invitations, nil
+}
+
+// RevokeInvitation revokes an invitation that is neither accepted nor
+// revoked yet, reporting whether it was.
+func (r *organizationRepositoryPostgres) RevokeInvitation(ctx context.Context, invitationId int64) (bool, error) {
+	q := `
+		UPDATE organization_invitations
+		SET revoked_at = $2,
+			updated_at = $2
+		WHERE invitation_id = $1
+			AND accepted_at IS NULL
+			AND revoked_at IS NULL
+	`
+
+	res, err := r.dbtx.ExecContext(ctx, q, invitationId, nowUnixMilli())
+	if err != nil {
+		return false, fmt.Errorf("[postgres][organization_repository][RevokeInvitation][ExecContext] Error: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("[postgres][organization_repository][RevokeInvitation][RowsAffected] Error: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// RevokeInvitationsByEmail revokes every open invitation of the email to
+// the organization.
+func (r *organizationRepositoryPostgres) RevokeInvitationsByEmail(ctx context.Context, organizationId int64, email string) error {
+	q := `
+		UPDATE organization_invitations
+		SET revoked_at = $3,
+			updated_at = $3
+		WHERE organization_id = $1
+			AND email = $2
+			AND accepted_at IS NULL
+			AND revoked_at IS NULL
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, organizationId, email, nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][organization_repository][RevokeInvitationsByEmail][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+// AcceptInvitation marks an invitation that is neither accepted nor revoked
+// as accepted by the account, reporting whether it was.
+func (r *organizationRepositoryPostgres) AcceptInvitation(ctx context.Context, invitationId, accountId int64) (bool, error) {
+	q := `
+		UPDATE organization_invitations
+		SET accepted_by = $2,
+			accepted_at = $3,
+			updated_at = $3
+		WHERE invitation_id = $1
+			AND accepted_at IS NULL
+			AND revoked_at IS NULL
+	`
+
+	res, err := r.dbtx.ExecContext(ctx, q, invitationId, accountId, nowUnixMilli())
+	if err != nil {
+		return false, fmt.Errorf("[postgres][organization_repository][AcceptInvitation][ExecContext] Error: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("[postgres][organization_repository][AcceptInvitation][RowsAffected] Error: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
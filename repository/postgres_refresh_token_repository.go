@@ -2,6 +2,11 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/michaelyusak/go-auth/entity"
 )
 
 type refreshTokenRepositoryPostgres struct {
@@ -14,20 +19,154 @@ func NewRefreshTokenRepositoryPostgres(dbtx DBTX) *refreshTokenRepositoryPostgre
 	}
 }
 
-func (r *refreshTokenRepositoryPostgres) InsertToken(ctx context.Context, token string, accountId, deviceId, expiredAt int64) error {
+func (r *refreshTokenRepositoryPostgres) InsertToken(ctx context.Context, newToken entity.RefreshToken) error {
 	q := `
-		INSERT INTO refresh_tokens (refresh_token, account_id, device_id, expired_at, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $5)
+		INSERT INTO refresh_tokens (token_hash, account_id, device_id, family_id, organization_id, client_id, scope, ip_address, expired_at,
+			last_used_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10, $10)
 	`
 
 	_, err := r.dbtx.ExecContext(ctx, q,
-		token,
-		accountId,
-		deviceId,
-		expiredAt,
+		newToken.TokenHash,
+		newToken.AccountId,
+		newToken.DeviceId,
+		newToken.FamilyId,
+		newToken.OrganizationId,
+		newToken.ClientId,
+		newToken.Scope,
+		newToken.IpAddress,
+		newToken.ExpiredAt,
 		nowUnixMilli())
 	if err != nil {
-		return err
+		return fmt.Errorf("[postgres][refresh_token_repository][InsertToken][ExecContext] Error: %w", translatePgError(err))
+	}
+
+	return nil
+}
+
+func (r *refreshTokenRepositoryPostgres) GetTokenByHash(ctx context.Context, tokenHash string) (*entity.RefreshToken, error) {
+	q := `
+		SELECT refresh_token_id, token_hash, account_id, device_id, family_id, organization_id, client_id, scope, ip_address, expired_at,
+			last_used_at, revoked_at, created_at, updated_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+	`
+
+	var refreshToken entity.RefreshToken
+
+	err := r.dbtx.QueryRowContext(ctx, q, tokenHash).Scan(
+		&refreshToken.RefreshTokenId,
+		&refreshToken.TokenHash,
+		&refreshToken.AccountId,
+		&refreshToken.DeviceId,
+		&refreshToken.FamilyId,
+		&refreshToken.OrganizationId,
+		&refreshToken.ClientId,
+		&refreshToken.Scope,
+		&refreshToken.IpAddress,
+		&refreshToken.ExpiredAt,
+		&refreshToken.LastUsedAt,
+		&refreshToken.RevokedAt,
+		&refreshToken.CreatedAt,
+		&refreshToken.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("[postgres][refresh_token_repository][GetTokenByHash][QueryRowContext] Error: %w", err)
+	}
+
+	return &refreshToken, nil
+}
+
+// GetTokensByAccountId returns every token of the account, revoked ones
+// included, newest first.
+func (r *refreshTokenRepositoryPostgres) GetTokensByAccountId(ctx context.Context, accountId int64) ([]entity.RefreshToken, error) {
+	q := `
+		SELECT refresh_token_id, token_hash, account_id, device_id, family_id, organization_id, client_id, scope, ip_address, expired_at,
+			last_used_at, revoked_at, created_at, updated_at
+		FROM refresh_tokens
+		WHERE account_id = $1
+		ORDER BY refresh_token_id DESC
+	`
+
+	rows, err := r.dbtx.QueryContext(ctx, q, accountId)
+	if err != nil {
+		return nil, fmt.Errorf("[postgres][refresh_token_repository][GetTokensByAccountId][QueryContext] Error: %w", err)
+	}
+	defer rows.Close()
+
+	refreshTokens := []entity.RefreshToken{}
+
+	for rows.Next() {
+		var refreshToken entity.RefreshToken
+
+		err = rows.Scan(
+			&refreshToken.RefreshTokenId,
+			&refreshToken.TokenHash,
+			&refreshToken.AccountId,
+			&refreshToken.DeviceId,
+			&refreshToken.FamilyId,
+			&refreshToken.OrganizationId,
+			&refreshToken.ClientId,
+			&refreshToken.Scope,
+			&refreshToken.IpAddress,
+			&refreshToken.ExpiredAt,
+			&refreshToken.LastUsedAt,
+			&refreshToken.RevokedAt,
+			&refreshToken.CreatedAt,
+			&refreshToken.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("[postgres][refresh_token_repository][GetTokensByAccountId][rows.Scan] Error: %w", err)
+		}
+
+		refreshTokens = append(refreshTokens, refreshToken)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("[postgres][refresh_token_repository][GetTokensByAccountId][rows.Err] Error: %w", err)
+	}
+
+	return refreshTokens, nil
+}
+
+// RevokeToken marks an active token as used and revoked. It reports false
+// when the token had already been revoked, e.g. by a concurrent refresh.
+func (r *refreshTokenRepositoryPostgres) RevokeToken(ctx context.Context, refreshTokenId int64) (bool, error) {
+	q := `
+		UPDATE refresh_tokens
+		SET revoked_at = $2,
+			last_used_at = $2,
+			updated_at = $2
+		WHERE refresh_token_id = $1
+			AND revoked_at IS NULL
+	`
+
+	res, err := r.dbtx.ExecContext(ctx, q, refreshTokenId, nowUnixMilli())
+	if err != nil {
+		return false, fmt.Errorf("[postgres][refresh_token_repository][RevokeToken][ExecContext] Error: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("[postgres][refresh_token_repository][RevokeToken][RowsAffected] Error: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *refreshTokenRepositoryPostgres) DeleteTokenByFamilyId(ctx context.Context, familyId string) error {
+	q := `
+		DELETE FROM refresh_tokens
+		WHERE family_id = $1
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, familyId)
+	if err != nil {
+		return fmt.Errorf("[postgres][refresh_token_repository][DeleteTokenByFamilyId][ExecContext] Error: %w", err)
 	}
 
 	return nil
@@ -41,8 +180,95 @@ func (r *refreshTokenRepositoryPostgres) DeleteTokenByAccountId(ctx context.Cont
 
 	_, err := r.dbtx.ExecContext(ctx, q, accountId)
 	if err != nil {
-		return err
+		return fmt.Errorf("[postgres][refresh_token_repository][DeleteTokenByAccountId][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *refreshTokenRepositoryPostgres) DeleteTokenByDeviceId(ctx context.Context, deviceId int64) error {
+	q := `
+		DELETE FROM refresh_tokens
+		WHERE device_id = $1
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, deviceId)
+	if err != nil {
+		return fmt.Errorf("[postgres][refresh_token_repository][DeleteTokenByDeviceId][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteDeviceSession deletes the session the device logged in with,
+// leaving the sessions of the OAuth clients authorized from it.
+func (r *refreshTokenRepositoryPostgres) DeleteDeviceSession(ctx context.Context, deviceId int64) error {
+	q := `
+		DELETE FROM refresh_tokens
+		WHERE device_id = $1
+			AND client_id IS NULL
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, deviceId)
+	if err != nil {
+		return fmt.Errorf("[postgres][refresh_token_repository][DeleteDeviceSession][ExecContext] Error: %w", err)
 	}
 
 	return nil
 }
+
+// DeleteOtherSessions deletes every token of the account except the ones
+// issued to keepDeviceId, returning how many tokens were deleted.
+func (r *refreshTokenRepositoryPostgres) DeleteOtherSessions(ctx context.Context, accountId, keepDeviceId int64) (int64, error) {
+	q := `
+		DELETE FROM refresh_tokens
+		WHERE account_id = $1
+			AND device_id <> $2
+	`
+
+	res, err := r.dbtx.ExecContext(ctx, q, accountId, keepDeviceId)
+	if err != nil {
+		return 0, fmt.Errorf("[postgres][refresh_token_repository][DeleteOtherSessions][ExecContext] Error: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("[postgres][refresh_token_repository][DeleteOtherSessions][RowsAffected] Error: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+// DeleteLeastRecentlyUsedSessions keeps the keep most recently used active
+// sessions of an account and deletes every other session family, returning
+// how many tokens were deleted. Sessions of OAuth clients are neither
+// counted nor deleted.
+func (r *refreshTokenRepositoryPostgres) DeleteLeastRecentlyUsedSessions(ctx context.Context, accountId int64, keep int) (int64, error) {
+	q := `
+		DELETE FROM refresh_tokens
+		WHERE account_id = $1
+			AND client_id IS NULL
+			AND family_id IN (
+				SELECT family_id
+				FROM refresh_tokens
+				WHERE account_id = $1
+					AND client_id IS NULL
+					AND revoked_at IS NULL
+					AND expired_at > $3
+				ORDER BY last_used_at DESC
+				OFFSET $2
+			)
+	`
+
+	res, err := r.dbtx.ExecContext(ctx, q, accountId, keep, nowUnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("[postgres][refresh_token_repository][DeleteLeastRecentlyUsedSessions][ExecContext] Error: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("[postgres][refresh_token_repository][DeleteLeastRecentlyUsedSessions][RowsAffected] Error: %w", err)
+	}
+
+	return rowsAffected, nil
+}
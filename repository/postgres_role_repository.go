@@ -0,0 +1,432 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/michaelyusak/go-auth/entity"
+)
+
+const roleColumns = `role_id, tenant_id, role_name, description, is_builtin, created_at, updated_at`
+
+const permissionColumns = `permission_id, tenant_id, permission_name, description, is_builtin, created_at, updated_at`
+
+func scanRole(row rowScanner, role *entity.Role) error {
+	return row.Scan(
+		&role.RoleId,
+		&role.TenantId,
+		&role.Name,
+		&role.Description,
+		&role.IsBuiltin,
+		&role.CreatedAt,
+		&role.UpdatedAt,
+	)
+}
+
+func scanPermission(row rowScanner, permission *entity.Permission) error {
+	return row.Scan(
+		&permission.PermissionId,
+		&permission.TenantId,
+		&permission.Name,
+		&permission.Description,
+		&permission.IsBuiltin,
+		&permission.CreatedAt,
+		&permission.UpdatedAt,
+	)
+}
+
+// roleRepositoryPostgres covers roles, permissions and how they are granted:
+// the role_permissions and account_roles tables.
+type roleRepositoryPostgres struct {
+	dbtx DBTX
+}
+
+func NewRoleRepositoryPostgres(dbtx DBTX) *roleRepositoryPostgres {
+	return &roleRepositoryPostgres{
+		dbtx: dbtx,
+	}
+}
+
+// GetRoles returns the roles of the tenant and the builtin ones.
+func (r *roleRepositoryPostgres) GetRoles(ctx context.Context, tenantId string) ([]entity.Role, error) {
+	q := `
+		SELECT ` + roleColumns + `
+		FROM roles
+		WHERE tenant_id = $1
+			OR is_builtin
+		ORDER BY role_name
+	`
+
+	return r.queryRoles(ctx, "GetRoles", q, tenantId)
+}
+
+func (r *roleRepositoryPostgres) GetRoleById(ctx context.Context, roleId int64) (*entity.Role, error) {
+	return r.getRole(ctx, "GetRoleById", "role_id = $1", roleId)
+}
+
+// GetRoleByName returns the role of the tenant, or the builtin role, with
+// the name.
+func (r *roleRepositoryPostgres) GetRoleByName(ctx context.Context, tenantId, name string) (*entity.Role, error) {
+	return r.getRole(ctx, "GetRoleByName", "(tenant_id = $1 OR is_builtin) AND role_name = $2", tenantId, name)
+}
+
+func (r *roleRepositoryPostgres) getRole(ctx context.Context, caller, condition string, args ...any) (*entity.Role, error) {
+	q := `
+		SELECT ` + roleColumns + `
+		FROM roles
+		WHERE ` + condition
+
+	var role entity.Role
+
+	err := scanRole(r.dbtx.QueryRowContext(ctx, q, args...), &role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("[postgres][role_repository][%s][QueryRowContext] Error: %w", caller, err)
+	}
+
+	return &role, nil
+}
+
+func (r *roleRepositoryPostgres) InsertRole(ctx context.Context, role entity.Role) (int64, error) {
+	q := `
+		INSERT INTO roles (tenant_id, role_name, description, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+		RETURNING role_id
+	`
+
+	var roleId int64
+
+	err := r.dbtx.QueryRowContext(ctx, q, role.TenantId, role.Name, role.Description, nowUnixMilli()).Scan(&roleId)
+	if err != nil {
+		return roleId, fmt.Errorf("[postgres][role_repository][InsertRole][QueryRowContext] Error: %w", translatePgError(err))
+	}
+
+	return roleId, nil
+}
+
+func (r *roleRepositoryPostgres) UpdateRole(ctx context.Context, role entity.Role) error {
+	q := `
+		UPDATE roles
+		SET description = $2,
+			updated_at = $3
+		WHERE role_id = $1
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, role.RoleId, role.Description, nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][role_repository][UpdateRole][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteRole deletes the role; its permissions and account assignments go
+// with it.
+func (r *roleRepositoryPostgres) DeleteRole(ctx context.Context, roleId int64) error {
+	q := `
+		DELETE FROM roles
+		WHERE role_id = $1
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, roleId)
+	if err != nil {
+		return fmt.Errorf("[postgres][role_repository][DeleteRole][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+// GetRolePermissionNames returns the names of the permissions of each role
+// of the tenant and of the builtin ones, keyed by role id.
+func (r *roleRepositoryPostgres) GetRolePermissionNames(ctx context.Context, tenantId string) (map[int64][]string, error) {
+	q := `
+		SELECT rp.role_id, p.permission_name
+		FROM role_permissions rp
+		JOIN roles r ON r.role_id = rp.role_id
+		JOIN permissions p ON p.permission_id = rp.permission_id
+		WHERE r.tenant_id = $1
+			OR r.is_builtin
+		ORDER BY p.permission_name
+	`
+
+	rows, err := r.dbtx.QueryContext(ctx, q, tenantId)
+	if err != nil {
+		return nil, fmt.Errorf("[postgres][role_repository][GetRolePermissionNames][QueryContext] Error: %w", err)
+	}
+	defer rows.Close()
+
+	permissionNames := map[int64][]string{}
+
+	for rows.Next() {
+		var (
+			roleId         int64
+			permissionName string
+		)
+
+		err = rows.Scan(&roleId, &permissionName)
+		if err != nil {
+			return nil, fmt.Errorf("[postgres][role_repository][GetRolePermissionNames][rows.Scan] Error: %w", err)
+		}
+
+		permissionNames[roleId] = append(permissionNames[roleId], permissionName)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("[postgres][role_repository][GetRolePermissionNames][rows.Err] Error: %w", err)
+	}
+
+	return permissionNames, nil
+}
+
+// SetRolePermissions replaces the role's permissions.
+func (r *roleRepositoryPostgres) SetRolePermissions(ctx context.Context, roleId int64, permissionIds []int64) error {
+	q := `
+		DELETE FROM role_permissions
+		WHERE role_id = $1
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, roleId)
+	if err != nil {
+		return fmt.Errorf("[postgres][role_repository][SetRolePermissions][ExecContext] delete | Error: %w", err)
+	}
+
+	q = `
+		INSERT INTO role_permissions (role_id, permission_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`
+
+	now := nowUnixMilli()
+
+	for _, permissionId := range permissionIds {
+		_, err = r.dbtx.ExecContext(ctx, q, roleId, permissionId, now)
+		if err != nil {
+			return fmt.Errorf("[postgres][role_repository][SetRolePermissions][ExecContext] insert | Error: %w", translatePgError(err))
+		}
+	}
+
+	return nil
+}
+
+// GetPermissions returns the permissions of the tenant and the builtin ones.
+func (r *roleRepositoryPostgres) GetPermissions(ctx context.Context, tenantId string) ([]entity.Permission, error) {
+	q := `
+		SELECT ` + permissionColumns + `
+		FROM permissions
+		WHERE tenant_id = $1
+			OR is_builtin
+		ORDER BY permission_name
+	`
+
+	rows, err := r.dbtx.QueryContext(ctx, q, tenantId)
+	if err != nil {
+		return nil, fmt.Errorf("[postgres][role_repository][GetPermissions][QueryContext] Error: %w", err)
+	}
+	defer rows.Close()
+
+	permissions := []entity.Permission{}
+
+	for rows.Next() {
+		var permission entity.Permission
+
+		err = scanPermission(rows, &permission)
+		if err != nil {
+			return nil, fmt.Errorf("[postgres][role_repository][GetPermissions][rows.Scan] Error: %w", err)
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("[postgres][role_repository][GetPermissions][rows.Err] Error: %w", err)
+	}
+
+	return permissions, nil
+}
+
+func (r *roleRepositoryPostgres) GetPermissionById(ctx context.Context, permissionId int64) (*entity.Permission, error) {
+	q := `
+		SELECT ` + permissionColumns + `
+		FROM permissions
+		WHERE permission_id = $1
+	`
+
+	var permission entity.Permission
+
+	err := scanPermission(r.dbtx.QueryRowContext(ctx, q, permissionId), &permission)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("[postgres][role_repository][GetPermissionById][QueryRowContext] Error: %w", err)
+	}
+
+	return &permission, nil
+}
+
+func (r *roleRepositoryPostgres) InsertPermission(ctx context.Context, permission entity.Permission) (int64, error) {
+	q := `
+		INSERT INTO permissions (tenant_id, permission_name, description, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+		RETURNING permission_id
+	`
+
+	var permissionId int64
+
+	err := r.dbtx.QueryRowContext(ctx, q, permission.TenantId, permission.Name, permission.Description, nowUnixMilli()).Scan(&permissionId)
+	if err != nil {
+		return permissionId, fmt.Errorf("[postgres][role_repository][InsertPermission][QueryRowContext] Error: %w", translatePgError(err))
+	}
+
+	return permissionId, nil
+}
+
+// DeletePermission deletes the permission and takes it away from every
+// role holding it.
+func (r *roleRepositoryPostgres) DeletePermission(ctx context.Context, permissionId int64) error {
+	q := `
+		DELETE FROM permissions
+		WHERE permission_id = $1
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, permissionId)
+	if err != nil {
+		return fmt.Errorf("[postgres][role_repository][DeletePermission][ExecContext] Error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *roleRepositoryPostgres) GetAccountRoles(ctx context.Context, accountId int64) ([]entity.Role, error) {
+	q := `
+		SELECT r.role_id, r.tenant_id, r.role_name, r.description, r.is_builtin, r.created_at, r.updated_at
+		FROM account_roles ar
+		JOIN roles r ON r.role_id = ar.role_id
+		WHERE ar.account_id = $1
+		ORDER BY r.role_name
+	`
+
+	return r.queryRoles(ctx, "GetAccountRoles", q, accountId)
+}
+
+// GetAccountGrants returns the names of the account's roles and of every
+// permission they grant.
+func (r *roleRepositoryPostgres) GetAccountGrants(ctx context.Context, accountId int64) (*entity.AccountGrants, error) {
+	roles, err := r.GetAccountRoles(ctx, accountId)
+	if err != nil {
+		return nil, fmt.Errorf("[postgres][role_repository][GetAccountGrants][GetAccountRoles] Error: %w", err)
+	}
+
+	q := `
+		SELECT DISTINCT p.permission_name
+		FROM account_roles ar
+		JOIN role_permissions rp ON rp.role_id = ar.role_id
+		JOIN permissions p ON p.permission_id = rp.permission_id
+		WHERE ar.account_id = $1
+		ORDER BY p.permission_name
+	`
+
+	rows, err := r.dbtx.QueryContext(ctx, q, accountId)
+	if err != nil {
+		return nil, fmt.Errorf("[postgres][role_repository][GetAccountGrants][QueryContext] Error: %w", err)
+	}
+	defer rows.Close()
+
+	grants := &entity.AccountGrants{
+		Roles:       make([]string, 0, len(roles)),
+		Permissions: []string{},
+	}
+
+	for _, role := range roles {
+		grants.Roles = append(grants.Roles, role.Name)
+	}
+
+	for rows.Next() {
+		var permissionName string
+
+		err = rows.Scan(&permissionName)
+		if err != nil {
+			return nil, fmt.Errorf("[postgres][role_repository][GetAccountGrants][rows.Scan] Error: %w", err)
+		}
+
+		grants.Permissions = append(grants.Permissions, permissionName)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("[postgres][role_repository][GetAccountGrants][rows.Err] Error: %w", err)
+	}
+
+	return grants, nil
+}
+
+// AssignRole gives the account the role; assigning a role it already has
+// does nothing.
+func (r *roleRepositoryPostgres) AssignRole(ctx context.Context, accountId, roleId int64) error {
+	q := `
+		INSERT INTO account_roles (account_id, role_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`
+
+	_, err := r.dbtx.ExecContext(ctx, q, accountId, roleId, nowUnixMilli())
+	if err != nil {
+		return fmt.Errorf("[postgres][role_repository][AssignRole][ExecContext] Error: %w", translatePgError(err))
+	}
+
+	return nil
+}
+
+// UnassignRole takes the role away from the account, reporting whether the
+// account had it.
+func (r *roleRepositoryPostgres) UnassignRole(ctx context.Context, accountId, roleId int64) (bool, error) {
+	q := `
+		DELETE FROM account_roles
+		WHERE account_id = $1
+			AND role_id = $2
+	`
+
+	res, err := r.dbtx.ExecContext(ctx, q, accountId, roleId)
+	if err != nil {
+		return false, fmt.Errorf("[postgres][role_repository][UnassignRole][ExecContext] Error: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("[postgres][role_repository][UnassignRole][RowsAffected] Error: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *roleRepositoryPostgres) queryRoles(ctx context.Context, caller, q string, args ...any) ([]entity.Role, error) {
+	rows, err := r.dbtx.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("[postgres][role_repository][%s][QueryContext] Error: %w", caller, err)
+	}
+	defer rows.Close()
+
+	roles := []entity.Role{}
+
+	for rows.Next() {
+		var role entity.Role
+
+		err = scanRole(rows, &role)
+		if err != nil {
+			return nil, fmt.Errorf("[postgres][role_repository][%s][rows.Scan] Error: %w", caller, err)
+		}
+
+		roles = append(roles, role)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("[postgres][role_repository][%s][rows.Err] Error: %w", caller, err)
+	}
+
+	return roles, nil
+}
@@ -1,63 +1,301 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
 )
 
+// ErrTransaction marks failures to begin or commit a transaction, as opposed
+// to errors returned by the function run inside it.
+var ErrTransaction = errors.New("transaction error")
+
+// TxRepositories are repositories bound to a single transaction. They must
+// not be used after the WithinTx callback that received them returns.
+type TxRepositories struct {
+	Account       AccountRepository
+	RefreshToken  RefreshTokenRepository
+	AccountDevice AccountDeviceRepository
+	AuthEvent     AuthEventRepository
+	ContactChange AccountContactChangeRepository
+	Role          RoleRepository
+	Organization  OrganizationRepository
+	OAuth         OAuthRepository
+}
+
 type Transaction interface {
-	Begin() error
-	Rollback() error
-	Commit() error
-	AccounPostgrestTx() *accountRepositoryPostgres
-	RefreshTokenPostgresTx() *refreshTokenRepositoryPostgres
-	AccountDevicePostgresTx() *accountDeviceRepositoryPostgres
+	WithinTx(ctx context.Context, fn func(repos TxRepositories) error) error
 }
 
 type sqlTransaction struct {
-	db *sql.DB
-	tx *sql.Tx
+	db              *sql.DB
+	isolationLevel  sql.IsolationLevel
+	maxRetries      int
+	retryBackoff    time.Duration
+	maxRetryBackoff time.Duration
+	log             *logrus.Logger
+}
+
+type SqlTransactionOpt struct {
+	IsolationLevel  sql.IsolationLevel
+	MaxRetries      int
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	Log             *logrus.Logger
 }
 
-func NewSqlTransaction(db *sql.DB) *sqlTransaction {
+func NewSqlTransaction(db *sql.DB, opt SqlTransactionOpt) *sqlTransaction {
 	return &sqlTransaction{
-		db: db,
+		db:              db,
+		isolationLevel:  opt.IsolationLevel,
+		maxRetries:      opt.MaxRetries,
+		retryBackoff:    opt.RetryBackoff,
+		maxRetryBackoff: opt.MaxRetryBackoff,
+		log:             opt.Log,
+	}
+}
+
+// ParseIsolationLevel maps a config value such as "read_committed" or
+// "serializable" to its sql.IsolationLevel. An empty value keeps the
+// database default.
+func ParseIsolationLevel(level string) (sql.IsolationLevel, error) {
+	switch strings.ToLower(strings.ReplaceAll(level, "_", " ")) {
+	case "", "default":
+		return sql.LevelDefault, nil
+	case "read committed":
+		return sql.LevelReadCommitted, nil
+	case "repeatable read":
+		return sql.LevelRepeatableRead, nil
+	case "serializable":
+		return sql.LevelSerializable, nil
+	default:
+		return sql.LevelDefault, fmt.Errorf("unsupported isolation level: %s", level)
+	}
+}
+
+// WithinTx runs fn in its own transaction. The transaction is committed when
+// fn returns nil and rolled back when fn returns an error or panics; the
+// error from fn is returned unchanged. When the transaction fails with a
+// serialization failure or deadlock, fn is run again in a new transaction,
+// so it must not leave side effects outside the repositories it receives.
+func (s *sqlTransaction) WithinTx(ctx context.Context, fn func(repos TxRepositories) error) error {
+	backoff := s.retryBackoff
+
+	for attempt := 1; ; attempt++ {
+		retryable, err := s.run(ctx, fn)
+		if retryable == nil || attempt > s.maxRetries {
+			return err
+		}
+
+		s.log.WithFields(logrus.Fields{
+			"request_id": ctx.Value(constant.RequestIdCtxKey),
+			"attempt":    attempt,
+			"backoff":    backoff.String(),
+			"error":      retryable.Error(),
+		}).Warn("[transaction][WithinTx] retrying transaction")
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(jitter(backoff)):
+		}
+
+		backoff = min(backoff*2, s.maxRetryBackoff)
 	}
 }
 
-func (s *sqlTransaction) Begin() error {
-	tx, err := s.db.Begin()
+// run executes a single attempt. retryable is the serialization failure or
+// deadlock that made the attempt fail, if any.
+func (s *sqlTransaction) run(ctx context.Context, fn func(repos TxRepositories) error) (retryable error, err error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: s.isolationLevel,
+	})
 	if err != nil {
-		return fmt.Errorf("[transaction][Begin][db.Begin] Error: %w", err)
+		return nil, fmt.Errorf("[transaction][WithinTx][db.BeginTx] %w: %w", ErrTransaction, err)
+	}
+
+	dbtx := &observedTx{
+		tx: tx,
 	}
 
-	s.tx = tx
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+
+		if err != nil {
+			tx.Rollback()
+
+			retryable = dbtx.retryable
+			if retryable == nil && isRetryable(err) {
+				retryable = err
+			}
 
-	return nil
+			return
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			if isRetryable(err) {
+				retryable = err
+			}
+
+			err = fmt.Errorf("[transaction][WithinTx][tx.Commit] %w: %w", ErrTransaction, err)
+		}
+	}()
+
+	return nil, fn(newTxRepositories(dbtx))
 }
 
-func (s *sqlTransaction) Rollback() error {
-	return s.tx.Rollback()
+func newTxRepositories(dbtx DBTX) TxRepositories {
+	return TxRepositories{
+		Account:       NewAccountRepositoryPostgres(dbtx),
+		RefreshToken:  NewRefreshTokenRepositoryPostgres(dbtx),
+		AccountDevice: NewAccountDeviceRepositoryPostgres(dbtx),
+		AuthEvent:     NewAuthEventRepositoryPostgres(dbtx),
+		ContactChange: NewAccountContactChangeRepositoryPostgres(dbtx),
+		Role:          NewRoleRepositoryPostgres(dbtx),
+		Organization:  NewOrganizationRepositoryPostgres(dbtx),
+		OAuth:         NewOAuthRepositoryPostgres(dbtx),
+	}
 }
 
-func (s *sqlTransaction) Commit() error {
-	return s.tx.Commit()
+// observedTx remembers the first retryable error returned by a statement,
+// including the ones that only surface while reading its results. Callers
+// usually flatten repository errors into application errors, so the
+// Postgres error code would otherwise be lost by the time fn returns.
+type observedTx struct {
+	tx        *sql.Tx
+	retryable error
 }
 
-func (s *sqlTransaction) AccounPostgrestTx() *accountRepositoryPostgres {
-	return &accountRepositoryPostgres{
-		dbtx: s.tx,
+func (o *observedTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	res, err := o.tx.ExecContext(ctx, query, args...)
+	o.observe(err)
+
+	return res, err
+}
+
+func (o *observedTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	stmt, err := o.tx.PrepareContext(ctx, query)
+	o.observe(err)
+
+	return stmt, err
+}
+
+func (o *observedTx) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	rows, err := o.tx.QueryContext(ctx, query, args...)
+	o.observe(err)
+	if err != nil {
+		return nil, err
 	}
+
+	return &observedRows{
+		rows: rows,
+		tx:   o,
+	}, nil
 }
 
-func (s *sqlTransaction) RefreshTokenPostgresTx() *refreshTokenRepositoryPostgres {
-	return &refreshTokenRepositoryPostgres{
-		dbtx: s.tx,
+func (o *observedTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) Row {
+	row := o.tx.QueryRowContext(ctx, query, args...)
+	o.observe(row.Err())
+
+	return &observedRow{
+		row: row,
+		tx:  o,
 	}
 }
 
-func (s *sqlTransaction) AccountDevicePostgresTx() *accountDeviceRepositoryPostgres {
-	return &accountDeviceRepositoryPostgres{
-		dbtx: s.tx,
+func (o *observedTx) observe(err error) {
+	if o.retryable == nil && isRetryable(err) {
+		o.retryable = err
 	}
 }
+
+type observedRows struct {
+	rows *sql.Rows
+	tx   *observedTx
+}
+
+// Next ending early means the read failed or the rows ran out; Err tells
+// which, so it is observed here for callers that do not check it.
+func (r *observedRows) Next() bool {
+	if r.rows.Next() {
+		return true
+	}
+
+	r.tx.observe(r.rows.Err())
+
+	return false
+}
+
+func (r *observedRows) Scan(dest ...any) error {
+	err := r.rows.Scan(dest...)
+	r.tx.observe(err)
+
+	return err
+}
+
+func (r *observedRows) Err() error {
+	err := r.rows.Err()
+	r.tx.observe(err)
+
+	return err
+}
+
+func (r *observedRows) Close() error {
+	err := r.rows.Close()
+	r.tx.observe(err)
+
+	return err
+}
+
+type observedRow struct {
+	row *sql.Row
+	tx  *observedTx
+}
+
+func (r *observedRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	r.tx.observe(err)
+
+	return err
+}
+
+func (r *observedRow) Err() error {
+	return r.row.Err()
+}
+
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	return pgErr.Code == pgSerializationFailure || pgErr.Code == pgDeadlockDetected
+}
+
+// jitter spreads retries of concurrent transactions over [d/2, d].
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+
+	half := d / 2
+
+	return half + rand.N(d-half+1)
+}
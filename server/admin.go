@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// grantAdmins gives the accounts listed in admin_account_ids the builtin
+// admin role on startup, so a new deployment has admins to manage the rest.
+// Removing an id from the list does not take the role away.
+func grantAdmins(roleRepo repository.RoleRepository, accountIds []int64, log *logrus.Logger) {
+	if len(accountIds) == 0 {
+		return
+	}
+
+	// Builtin roles belong to no tenant, so no tenant id finds only them.
+	role, err := roleRepo.GetRoleByName(context.Background(), "", constant.RoleAdmin)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error": fmt.Sprintf("[server][grantAdmins][roleRepo.GetRoleByName] error: %s", err.Error()),
+		}).Error("error granting admin role")
+		return
+	}
+
+	if role == nil {
+		log.WithFields(logrus.Fields{
+			"error": "[server][grantAdmins] admin role not found",
+		}).Error("error granting admin role")
+		return
+	}
+
+	for _, accountId := range accountIds {
+		err = roleRepo.AssignRole(context.Background(), accountId, role.RoleId)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"error":      fmt.Sprintf("[server][grantAdmins][roleRepo.AssignRole] error: %s", err.Error()),
+				"account_id": accountId,
+			}).Error("error granting admin role")
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/michaelyusak/go-auth/adaptor"
+	"github.com/michaelyusak/go-auth/config"
+	"github.com/michaelyusak/go-auth/migration"
+	hHelper "github.com/michaelyusak/go-helper/helper"
+	"github.com/sirupsen/logrus"
+)
+
+const migrateUsage = "usage: migrate up | migrate down N | migrate status"
+
+// Migrate runs the migrate subcommand: "up", "down N" or "status".
+func Migrate(args []string) {
+	log := hHelper.NewLogrus()
+
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	config := config.Init(log)
+
+	db := adaptor.ConnectPostgres(config.Postgres, log)
+	defer db.Close()
+
+	migrator, err := migration.NewMigrator(db, log)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Fatal("error loading migrations")
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		count, err := migrator.Up(ctx)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"error": err.Error(),
+			}).Fatal("error applying migrations")
+		}
+
+		log.Infof("%d migration(s) applied", count)
+	case "down":
+		if len(args) < 2 {
+			log.Fatal(migrateUsage)
+		}
+
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			log.Fatalf("invalid migration count: %s", args[1])
+		}
+
+		count, err := migrator.Down(ctx, n)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"error": err.Error(),
+			}).Fatal("error rolling back migrations")
+		}
+
+		log.Infof("%d migration(s) rolled back", count)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"error": err.Error(),
+			}).Fatal("error reading migration status")
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = time.UnixMilli(*status.AppliedAt).UTC().Format(time.RFC3339)
+			}
+
+			fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+
+		w.Flush()
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
+
+func autoMigrate(db *sql.DB, log *logrus.Logger) {
+	migrator, err := migration.NewMigrator(db, log)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Fatal("error loading migrations")
+	}
+
+	count, err := migrator.Up(context.Background())
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Fatal("error applying migrations")
+	}
+
+	log.Infof("%d migration(s) applied on startup", count)
+}
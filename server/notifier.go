@@ -0,0 +1,66 @@
+package server
+
+import (
+	"time"
+
+	"github.com/michaelyusak/go-auth/adaptor"
+	"github.com/michaelyusak/go-auth/config"
+	"github.com/michaelyusak/go-auth/notifier"
+	"github.com/sirupsen/logrus"
+)
+
+func newDeviceNotifier(config *config.ServiceConfig, log *logrus.Logger) notifier.NewDeviceNotifier {
+	switch config.NewDevice.Notifier {
+	case "email":
+		return notifier.NewMailNotifier(adaptor.NewSmtpMailer(config.Smtp))
+	case "webhook":
+		return notifier.NewWebhookNotifier(config.NewDevice.WebhookUrl, time.Duration(config.NewDevice.WebhookTimeout))
+	case "log", "":
+		return notifier.NewLogNotifier(log)
+	default:
+		log.Fatalf("unknown new device notifier: %s", config.NewDevice.Notifier)
+		return nil
+	}
+}
+
+func contactChangeNotifier(config *config.ServiceConfig, log *logrus.Logger) notifier.ContactChangeNotifier {
+	switch config.ContactChange.Notifier {
+	case "email":
+		return notifier.NewMailNotifier(adaptor.NewSmtpMailer(config.Smtp))
+	case "webhook":
+		return notifier.NewWebhookNotifier(config.ContactChange.WebhookUrl, time.Duration(config.ContactChange.WebhookTimeout))
+	case "log", "":
+		return notifier.NewLogNotifier(log)
+	default:
+		log.Fatalf("unknown contact change notifier: %s", config.ContactChange.Notifier)
+		return nil
+	}
+}
+
+func passwordResetNotifier(config *config.ServiceConfig, log *logrus.Logger) notifier.PasswordResetNotifier {
+	switch config.PasswordReset.Notifier {
+	case "email":
+		return notifier.NewMailNotifier(adaptor.NewSmtpMailer(config.Smtp))
+	case "webhook":
+		return notifier.NewWebhookNotifier(config.PasswordReset.WebhookUrl, time.Duration(config.PasswordReset.WebhookTimeout))
+	case "log", "":
+		return notifier.NewLogNotifier(log)
+	default:
+		log.Fatalf("unknown password reset notifier: %s", config.PasswordReset.Notifier)
+		return nil
+	}
+}
+
+func invitationNotifier(config *config.ServiceConfig, log *logrus.Logger) notifier.InvitationNotifier {
+	switch config.Invitation.Notifier {
+	case "email":
+		return notifier.NewMailNotifier(adaptor.NewSmtpMailer(config.Smtp))
+	case "webhook":
+		return notifier.NewWebhookNotifier(config.Invitation.WebhookUrl, time.Duration(config.Invitation.WebhookTimeout))
+	case "log", "":
+		return notifier.NewLogNotifier(log)
+	default:
+		log.Fatalf("unknown invitation notifier: %s", config.Invitation.Notifier)
+		return nil
+	}
+}
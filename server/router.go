@@ -1,15 +1,25 @@
 package server
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/michaelyusak/go-auth/adaptor"
+	"github.com/michaelyusak/go-auth/audit"
 	"github.com/michaelyusak/go-auth/config"
+	"github.com/michaelyusak/go-auth/constant"
 	"github.com/michaelyusak/go-auth/handler"
+	"github.com/michaelyusak/go-auth/helper"
+	"github.com/michaelyusak/go-auth/middleware"
+	"github.com/michaelyusak/go-auth/purger"
 	"github.com/michaelyusak/go-auth/repository"
 	"github.com/michaelyusak/go-auth/service"
+	"github.com/michaelyusak/go-auth/tenant"
 	helperHandler "github.com/michaelyusak/go-helper/handler"
 	hHelper "github.com/michaelyusak/go-helper/helper"
 	helperMiddleware "github.com/michaelyusak/go-helper/middleware"
@@ -17,73 +27,325 @@ import (
 )
 
 type routerOpts struct {
-	common  *helperHandler.CommonHandler
-	account *handler.AccountHandler
+	common        *helperHandler.CommonHandler
+	account       *handler.AccountHandler
+	accountDevice *handler.AccountDeviceHandler
+	authEvent     *handler.AuthEventHandler
+	contactChange *handler.ContactChangeHandler
+	accountExport *handler.AccountExportHandler
+	accountStatus *handler.AccountStatusHandler
+	adminAccount  *handler.AdminAccountHandler
+	role          *handler.RoleHandler
+	organization  *handler.OrganizationHandler
+	oauth         *handler.OAuthHandler
+	jwt           hHelper.JWTHelper
+	tenants       tenant.Registry
+	statusChecker middleware.AccountStatusChecker
 }
 
-func createRouter(log *logrus.Logger, config *config.ServiceConfig) *gin.Engine {
+// createRouter wires the service together. The returned drain function
+// flushes background work and must be called on shutdown.
+func createRouter(log *logrus.Logger, config *config.ServiceConfig) (*gin.Engine, func(ctx context.Context)) {
 	db := adaptor.ConnectPostgres(config.Postgres, log)
 
-	transaction := repository.NewSqlTransaction(db)
-	accountRepo := repository.NewAccountRepositoryPostgres(db)
-	refreshTokenRepo := repository.NewRefreshTokenRepositoryPostgres(db)
-	accountDeviceRepo := repository.NewAccountDeviceRepositoryPostgres(db)
+	if config.AutoMigrate {
+		autoMigrate(db, log)
+	}
+
+	isolationLevel, err := repository.ParseIsolationLevel(config.Transaction.IsolationLevel)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error": fmt.Sprintf("[server][createRouter][repository.ParseIsolationLevel] error: %s", err.Error()),
+		}).Fatal("error initiating transaction")
+	}
+
+	transaction := repository.NewSqlTransaction(db, repository.SqlTransactionOpt{
+		IsolationLevel:  isolationLevel,
+		MaxRetries:      config.Transaction.MaxRetries,
+		RetryBackoff:    time.Duration(config.Transaction.RetryBackoff),
+		MaxRetryBackoff: time.Duration(config.Transaction.MaxRetryBackoff),
+		Log:             log,
+	})
+	// Audit chain appends read the head after taking the chain lock, which
+	// only sees the previous append under READ COMMITTED, so they do not
+	// follow the configured isolation level.
+	auditTransaction := repository.NewSqlTransaction(db, repository.SqlTransactionOpt{
+		IsolationLevel:  sql.LevelReadCommitted,
+		MaxRetries:      config.Transaction.MaxRetries,
+		RetryBackoff:    time.Duration(config.Transaction.RetryBackoff),
+		MaxRetryBackoff: time.Duration(config.Transaction.MaxRetryBackoff),
+		Log:             log,
+	})
+	dbtx := repository.NewDB(db)
+	accountRepo := repository.NewAccountRepositoryPostgres(dbtx)
+	refreshTokenRepo := repository.NewRefreshTokenRepositoryPostgres(dbtx)
+	accountDeviceRepo := repository.NewAccountDeviceRepositoryPostgres(dbtx)
+	authEventRepo := repository.NewAuthEventRepositoryPostgres(dbtx)
+	contactChangeRepo := repository.NewAccountContactChangeRepositoryPostgres(dbtx)
+	roleRepo := repository.NewRoleRepositoryPostgres(dbtx)
+	organizationRepo := repository.NewOrganizationRepositoryPostgres(dbtx)
+	oauthRepo := repository.NewOAuthRepositoryPostgres(dbtx)
+
+	grantAdmins(roleRepo, config.AdminAccountIds, log)
+
+	auditRecorder := audit.NewOutboxRecorder(audit.OutboxRecorderOpt{
+		AuthEventRepo: authEventRepo,
+		Log:           log,
+	})
+
+	auditRelay := audit.NewRelay(audit.RelayOpt{
+		Transaction: auditTransaction,
+		Interval:    time.Duration(config.Audit.RelayInterval),
+		BatchSize:   config.Audit.RelayBatchSize,
+		Log:         log,
+	})
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+
+	go auditRelay.Run(backgroundCtx)
+
+	go audit.NewCheckpointer(audit.CheckpointerOpt{
+		Transaction: transaction,
+		Key:         config.Audit.CheckpointKey,
+		Interval:    time.Duration(config.Audit.CheckpointInterval),
+		Log:         log,
+	}).Run(backgroundCtx)
+
+	go purger.NewAccountPurger(purger.AccountPurgerOpt{
+		AccountRepo:   accountRepo,
+		Transaction:   transaction,
+		GracePeriod:   time.Duration(config.AccountDeletion.GracePeriod),
+		Interval:      time.Duration(config.AccountDeletion.PurgeInterval),
+		BatchSize:     config.AccountDeletion.PurgeBatchSize,
+		Log:           log,
+		AuditRecorder: auditRecorder,
+	}).Run(backgroundCtx)
+
+	tenants := tenantRegistry(config, log)
 
 	hashHelper := hHelper.NewHashHelper(config.Hash)
 	jwtHelper := hHelper.NewJWTHelper(config.Jwt.Secret)
+	tokenHasher := helper.NewTokenHasher(config.Jwt.RefreshTokenPepper)
 
 	accountService := service.NewAccountService(service.AccountServiceOpt{
+		AccountRepo:           accountRepo,
+		RefreshTokenRepo:      refreshTokenRepo,
+		AccountDeviceRepo:     accountDeviceRepo,
+		Transaction:           transaction,
+		Hash:                  hashHelper,
+		Jwt:                   jwtHelper,
+		TokenHasher:           tokenHasher,
+		Log:                   log,
+		SubRoutineTimeout:     time.Duration(config.SubRoutineContextTimeout),
+		Tenants:               tenants,
+		MaxSessionsPerAccount: config.Session.MaxPerAccount,
+		SingleSession:         config.Session.SingleSession,
+		NewDeviceNotifier:     newDeviceNotifier(config, log),
+		RequireDeviceApproval: config.NewDevice.RequireApproval,
+		DeviceApprovalTtl:     time.Duration(config.NewDevice.ApprovalTtl),
+		DeviceApprovalUrl:     config.NewDevice.ApprovalUrl,
+		LegacyDeviceHash:      config.Device.LegacyHash,
+		DeletionGracePeriod:   time.Duration(config.AccountDeletion.GracePeriod),
+		AuditRecorder:         auditRecorder,
+	})
+
+	accountDeviceService := service.NewAccountDeviceService(service.AccountDeviceServiceOpt{
+		AccountDeviceRepo: accountDeviceRepo,
+		Transaction:       transaction,
+		TokenHasher:       tokenHasher,
+		AuditRecorder:     auditRecorder,
+	})
+
+	authEventService := service.NewAuthEventService(service.AuthEventServiceOpt{
+		AuthEventRepo: authEventRepo,
+	})
+
+	contactChangeService := service.NewContactChangeService(service.ContactChangeServiceOpt{
+		ContactChangeRepo: contactChangeRepo,
+		Transaction:       transaction,
+		TokenHasher:       tokenHasher,
+		Notifier:          contactChangeNotifier(config, log),
+		Log:               log,
+		SubRoutineTimeout: time.Duration(config.SubRoutineContextTimeout),
+		CodeTtl:           time.Duration(config.ContactChange.CodeTtl),
+		MaxAttempts:       config.ContactChange.MaxAttempts,
+		CancelUrl:         config.ContactChange.CancelUrl,
+		AuditRecorder:     auditRecorder,
+	})
+
+	accountExportService := service.NewAccountExportService(service.AccountExportServiceOpt{
 		AccountRepo:       accountRepo,
-		RefreshTokenRepo:  refreshTokenRepo,
 		AccountDeviceRepo: accountDeviceRepo,
+		RefreshTokenRepo:  refreshTokenRepo,
+		AuthEventRepo:     authEventRepo,
+		Log:               log,
+	})
+
+	accountStatusService := service.NewAccountStatusService(service.AccountStatusServiceOpt{
+		AccountRepo:   accountRepo,
+		Transaction:   transaction,
+		AuditRecorder: auditRecorder,
+	})
+
+	adminAccountService := service.NewAdminAccountService(service.AdminAccountServiceOpt{
+		AccountRepo:          accountRepo,
+		AccountDeviceRepo:    accountDeviceRepo,
+		RoleRepo:             roleRepo,
+		Transaction:          transaction,
+		TokenHasher:          tokenHasher,
+		AccountStatusService: accountStatusService,
+		Notifier:             passwordResetNotifier(config, log),
+		Log:                  log,
+		SubRoutineTimeout:    time.Duration(config.SubRoutineContextTimeout),
+		ResetTokenTtl:        time.Duration(config.PasswordReset.TokenTtl),
+		ResetUrl:             config.PasswordReset.ResetUrl,
+		AuditRecorder:        auditRecorder,
+	})
+
+	roleService := service.NewRoleService(service.RoleServiceOpt{
+		RoleRepo:      roleRepo,
+		AccountRepo:   accountRepo,
+		Transaction:   transaction,
+		AuditRecorder: auditRecorder,
+	})
+
+	organizationService := service.NewOrganizationService(service.OrganizationServiceOpt{
+		OrganizationRepo:  organizationRepo,
 		Transaction:       transaction,
-		Hash:              hashHelper,
-		Jwt:               jwtHelper,
+		TokenHasher:       tokenHasher,
+		Notifier:          invitationNotifier(config, log),
 		Log:               log,
 		SubRoutineTimeout: time.Duration(config.SubRoutineContextTimeout),
+		InvitationTtl:     time.Duration(config.Invitation.TokenTtl),
+		AcceptUrl:         config.Invitation.AcceptUrl,
+		AuditRecorder:     auditRecorder,
+	})
+
+	oauthService := service.NewOAuthService(service.OAuthServiceOpt{
+		OAuthRepo:        oauthRepo,
+		RefreshTokenRepo: refreshTokenRepo,
+		RoleRepo:         roleRepo,
+		AccountService:   accountService,
+		Transaction:      transaction,
+		TokenHasher:      tokenHasher,
+		LoginUrl:         config.OAuth.LoginUrl,
+		CodeTtl:          time.Duration(config.OAuth.CodeTtl),
+		AuditRecorder:    auditRecorder,
 	})
 
 	commonHandler := &helperHandler.CommonHandler{}
-	accountHandler := handler.NewAccountHandler(time.Duration(config.ContextTimeout), accountService)
+	accountHandler := handler.NewAccountHandler(time.Duration(config.ContextTimeout), accountService, handler.DeviceTokenOpt{
+		Signer:       helper.NewDeviceTokenSigner(config.Device.TokenSecret),
+		MaxAge:       time.Duration(config.Device.TokenMaxAge),
+		SecureCookie: config.Device.SecureCookie,
+	})
+	accountDeviceHandler := handler.NewAccountDeviceHandler(time.Duration(config.ContextTimeout), accountDeviceService)
+	authEventHandler := handler.NewAuthEventHandler(time.Duration(config.ContextTimeout), authEventService)
+	contactChangeHandler := handler.NewContactChangeHandler(time.Duration(config.ContextTimeout), contactChangeService)
+	accountExportHandler := handler.NewAccountExportHandler(time.Duration(config.ContextTimeout), accountExportService)
+	accountStatusHandler := handler.NewAccountStatusHandler(time.Duration(config.ContextTimeout), accountStatusService)
+	adminAccountHandler := handler.NewAdminAccountHandler(time.Duration(config.ContextTimeout), adminAccountService)
+	roleHandler := handler.NewRoleHandler(time.Duration(config.ContextTimeout), roleService)
+	organizationHandler := handler.NewOrganizationHandler(time.Duration(config.ContextTimeout), organizationService)
+	oauthHandler := handler.NewOAuthHandler(time.Duration(config.ContextTimeout), oauthService)
 
-	return newRouter(
+	router := newRouter(
 		routerOpts{
-			common:  commonHandler,
-			account: accountHandler,
+			common:        commonHandler,
+			account:       accountHandler,
+			accountDevice: accountDeviceHandler,
+			authEvent:     authEventHandler,
+			contactChange: contactChangeHandler,
+			accountExport: accountExportHandler,
+			accountStatus: accountStatusHandler,
+			adminAccount:  adminAccountHandler,
+			role:          roleHandler,
+			organization:  organizationHandler,
+			oauth:         oauthHandler,
+			jwt:           jwtHelper,
+			tenants:       tenants,
+			statusChecker: accountStatusService,
 		},
 		log,
-		config.AllowedOrigins,
+		config.TrustedProxies,
 	)
+
+	drain := func(ctx context.Context) {
+		stopBackground()
+
+		err := auditRelay.Flush(ctx)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"error": fmt.Sprintf("[server][createRouter][auditRelay.Flush] error: %s", err.Error()),
+			}).Error("error draining audit events")
+		}
+	}
+
+	return router, drain
 }
 
-func newRouter(r routerOpts, log *logrus.Logger, allowedOrigins []string) *gin.Engine {
+func newRouter(r routerOpts, log *logrus.Logger, trustedProxies []string) *gin.Engine {
 	router := gin.New()
 
 	corsConfig := cors.DefaultConfig()
 
 	router.ContextWithFallback = true
 
+	// gin's own ClientIP, used by the request logger, follows the same list.
+	err := router.SetTrustedProxies(trustedProxies)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error": fmt.Sprintf("[server][newRouter][router.SetTrustedProxies] error: %s", err.Error()),
+		}).Fatal("error initiating router")
+	}
+
+	clientIpMiddleware, err := middleware.ClientIpMiddleware(trustedProxies)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error": fmt.Sprintf("[server][newRouter][middleware.ClientIpMiddleware] error: %s", err.Error()),
+		}).Fatal("error initiating router")
+	}
+
 	router.Use(
 		helperMiddleware.Logger(log),
 		helperMiddleware.RequestIdHandlerMiddleware,
+		middleware.RequestIdContextMiddleware,
+		clientIpMiddleware,
 		helperMiddleware.ErrorHandlerMiddleware,
 		gin.Recovery(),
+		middleware.TenantMiddleware(r.tenants),
 	)
 
-	// authMiddleware := middleware.AuthMiddleware(h.jwtHelper)
+	authMiddleware := middleware.AuthMiddleware(r.jwt, r.statusChecker)
 
-	corsRouting(router, corsConfig, allowedOrigins)
+	corsRouting(router, corsConfig, r.tenants)
 	commonRouting(router, r.common)
-	accountRouting(router, r.account)
+	accountRouting(router, r.account, authMiddleware)
+	accountDeviceRouting(router, r.accountDevice, authMiddleware)
+	authEventRouting(router, r.authEvent, authMiddleware)
+	contactChangeRouting(router, r.contactChange, authMiddleware)
+	accountExportRouting(router, r.accountExport, authMiddleware)
+	accountStatusRouting(router, r.accountStatus, authMiddleware)
+	adminAccountRouting(router, r.adminAccount, authMiddleware)
+	roleRouting(router, r.role, authMiddleware)
+	organizationRouting(router, r.organization, authMiddleware)
+	oauthRouting(router, r.oauth, authMiddleware)
 
 	return router
 }
 
-func corsRouting(router *gin.Engine, configCors cors.Config, allowedOrigins []string) {
-	configCors.AllowOrigins = allowedOrigins
+// corsRouting allows the origins of the tenant the request is made for. It
+// must be registered after TenantMiddleware.
+func corsRouting(router *gin.Engine, configCors cors.Config, tenants tenant.Registry) {
+	configCors.AllowOriginWithContextFunc = func(ctx *gin.Context, origin string) bool {
+		tenantId, _ := ctx.Request.Context().Value(constant.TenantCtxKey).(string)
+		allowedOrigins := tenants.Settings(tenantId).AllowedOrigins
+
+		return slices.Contains(allowedOrigins, "*") || slices.Contains(allowedOrigins, origin)
+	}
 	configCors.AllowMethods = []string{"POST", "GET", "PUT", "PATCH", "DELETE"}
-	configCors.AllowHeaders = []string{"Origin", "Authorization", "Content-Type", "Accept", "User-Agent", "Cache-Control"}
-	configCors.ExposeHeaders = []string{"Content-Length"}
+	configCors.AllowHeaders = []string{"Origin", "Authorization", "Content-Type", "Accept", "User-Agent", "Cache-Control", "Device-Info", "X-Device-Token", "X-Tenant-Id", "X-Client-Id"}
+	configCors.ExposeHeaders = []string{"Content-Length", "X-Device-Token"}
 	configCors.AllowCredentials = true
 	router.Use(cors.New(configCors))
 }
@@ -93,9 +355,130 @@ func commonRouting(router *gin.Engine, handler *helperHandler.CommonHandler) {
 	router.NoRoute(handler.NoRoute)
 }
 
-func accountRouting(router *gin.Engine, handler *handler.AccountHandler) {
+func accountRouting(router *gin.Engine, handler *handler.AccountHandler, authMiddleware gin.HandlerFunc) {
 	api := router.Group("v1/account")
 
 	api.POST("/register", handler.Register)
 	api.POST("/login", handler.Login)
+	api.POST("/token/refresh", handler.RefreshToken)
+	api.POST("/token/switch-organization", handler.SwitchOrganization)
+	api.POST("/password/reset", handler.ResetPassword)
+
+	authApi := api.Group("", authMiddleware)
+
+	authApi.GET("/me", handler.GetProfile)
+	authApi.PATCH("/me", handler.UpdateProfile)
+	authApi.DELETE("/me", handler.DeleteAccount)
+}
+
+func accountDeviceRouting(router *gin.Engine, handler *handler.AccountDeviceHandler, authMiddleware gin.HandlerFunc) {
+	api := router.Group("v1/account/devices")
+
+	api.POST("/approve", handler.ApproveDeviceByToken)
+
+	authApi := api.Group("", authMiddleware)
+
+	authApi.GET("", handler.GetDevices)
+	authApi.PATCH("/:id", handler.RenameDevice)
+	authApi.DELETE("/:id", handler.DeleteDevice)
+	authApi.POST("/:id/approve", handler.ApproveDevice)
+}
+
+func authEventRouting(router *gin.Engine, handler *handler.AuthEventHandler, authMiddleware gin.HandlerFunc) {
+	router.GET("v1/account/activity", authMiddleware, handler.GetActivity)
+
+	adminApi := router.Group("v1/admin", authMiddleware)
+
+	adminApi.GET("/auth-events", middleware.RequirePermission(constant.PermissionAuthEventsRead), handler.SearchEvents)
+}
+
+func contactChangeRouting(router *gin.Engine, handler *handler.ContactChangeHandler, authMiddleware gin.HandlerFunc) {
+	api := router.Group("v1/account")
+
+	api.POST("/contact-changes/cancel", handler.CancelContactChange)
+
+	authApi := api.Group("/me", authMiddleware)
+
+	authApi.POST("/email", handler.RequestEmailChange)
+	authApi.POST("/phone-number", handler.RequestPhoneNumberChange)
+	authApi.POST("/contact-changes/confirm", handler.ConfirmContactChange)
+}
+
+func accountExportRouting(router *gin.Engine, handler *handler.AccountExportHandler, authMiddleware gin.HandlerFunc) {
+	router.GET("v1/account/me/export", authMiddleware, handler.ExportAccount)
+}
+
+func accountStatusRouting(router *gin.Engine, handler *handler.AccountStatusHandler, authMiddleware gin.HandlerFunc) {
+	adminApi := router.Group("v1/admin/accounts", authMiddleware)
+
+	adminApi.PUT("/:id/status", middleware.RequirePermission(constant.PermissionAccountsWrite), handler.UpdateAccountStatus)
+}
+
+func adminAccountRouting(router *gin.Engine, handler *handler.AdminAccountHandler, authMiddleware gin.HandlerFunc) {
+	adminApi := router.Group("v1/admin/accounts", authMiddleware)
+
+	readApi := adminApi.Group("", middleware.RequirePermission(constant.PermissionAccountsRead))
+
+	readApi.GET("", handler.SearchAccounts)
+	readApi.GET("/:id", handler.GetAccount)
+
+	writeApi := adminApi.Group("", middleware.RequirePermission(constant.PermissionAccountsWrite))
+
+	writeApi.POST("/:id/suspend", handler.SuspendAccount)
+	writeApi.POST("/:id/unsuspend", handler.UnsuspendAccount)
+	writeApi.POST("/:id/unlock", handler.UnlockAccount)
+	writeApi.POST("/:id/sessions/revoke", handler.RevokeSessions)
+	writeApi.POST("/:id/password-reset", handler.ForcePasswordReset)
+}
+
+func roleRouting(router *gin.Engine, handler *handler.RoleHandler, authMiddleware gin.HandlerFunc) {
+	adminApi := router.Group("v1/admin", authMiddleware)
+
+	readApi := adminApi.Group("", middleware.RequirePermission(constant.PermissionRolesRead))
+
+	readApi.GET("/roles", handler.GetRoles)
+	readApi.GET("/permissions", handler.GetPermissions)
+	readApi.GET("/accounts/:id/roles", handler.GetAccountRoles)
+
+	writeApi := adminApi.Group("", middleware.RequirePermission(constant.PermissionRolesWrite))
+
+	writeApi.POST("/roles", handler.CreateRole)
+	writeApi.PUT("/roles/:id", handler.UpdateRole)
+	writeApi.DELETE("/roles/:id", handler.DeleteRole)
+	writeApi.POST("/permissions", handler.CreatePermission)
+	writeApi.DELETE("/permissions/:id", handler.DeletePermission)
+	writeApi.PUT("/accounts/:id/roles/:role_id", handler.AssignRole)
+	writeApi.DELETE("/accounts/:id/roles/:role_id", handler.UnassignRole)
+}
+
+func organizationRouting(router *gin.Engine, handler *handler.OrganizationHandler, authMiddleware gin.HandlerFunc) {
+	api := router.Group("v1/organizations", authMiddleware)
+
+	api.POST("", handler.CreateOrganization)
+	api.GET("", handler.GetOrganizations)
+	api.POST("/invitations/accept", handler.AcceptInvitation)
+	api.GET("/:id/members", handler.GetMembers)
+	api.PUT("/:id/members/:account_id", handler.UpdateMemberRole)
+	api.DELETE("/:id/members/:account_id", handler.RemoveMember)
+	api.POST("/:id/invitations", handler.InviteMember)
+	api.GET("/:id/invitations", handler.GetInvitations)
+	api.DELETE("/:id/invitations/:invitation_id", handler.RevokeInvitation)
+}
+
+func oauthRouting(router *gin.Engine, handler *handler.OAuthHandler, authMiddleware gin.HandlerFunc) {
+	api := router.Group("oauth")
+
+	api.GET("/authorize", handler.AuthorizeRedirect)
+	api.POST("/authorize", authMiddleware, handler.Authorize)
+	api.POST("/token", handler.Token)
+
+	adminApi := router.Group("v1/admin/oauth/clients", authMiddleware)
+
+	adminApi.GET("", middleware.RequirePermission(constant.PermissionOAuthClientsRead), handler.GetClients)
+
+	writeApi := adminApi.Group("", middleware.RequirePermission(constant.PermissionOAuthClientsWrite))
+
+	writeApi.POST("", handler.CreateClient)
+	writeApi.PUT("/:id", handler.UpdateClient)
+	writeApi.DELETE("/:id", handler.DeleteClient)
 }
@@ -17,7 +17,7 @@ func Init() {
 
 	config := config.Init(log)
 
-	router := createRouter(log, &config)
+	router, drain := createRouter(log, &config)
 
 	srv := http.Server{
 		Handler: router,
@@ -45,7 +45,16 @@ func Init() {
 
 	<-ctx.Done()
 
-	if err := srv.Shutdown(ctx); err != nil {
+	err := srv.Shutdown(ctx)
+
+	// Requests still in flight may have queued work, so drain even when the
+	// shutdown timed out.
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), time.Duration(config.SubRoutineContextTimeout))
+	defer drainCancel()
+
+	drain(drainCtx)
+
+	if err != nil {
 		log.Fatalf("Server shut down: %s", err.Error())
 	}
 
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/michaelyusak/go-auth/config"
+	"github.com/michaelyusak/go-auth/helper"
+	"github.com/michaelyusak/go-auth/tenant"
+	"github.com/sirupsen/logrus"
+)
+
+// tenantRegistry builds the tenants from the config, each with the service
+// wide settings it does not override.
+func tenantRegistry(config *config.ServiceConfig, log *logrus.Logger) tenant.Registry {
+	defaults := tenant.Settings{
+		PasswordPolicy:       helper.DefaultPasswordPolicy,
+		AccessTokenDuration:  time.Duration(config.Jwt.AccessTokenDuration),
+		RefreshTokenDuration: time.Duration(config.Jwt.RefreshTokenDuration),
+		AllowedOrigins:       config.AllowedOrigins,
+	}
+
+	if config.PasswordPolicy != nil {
+		defaults.PasswordPolicy = *config.PasswordPolicy
+	}
+
+	tenants := make([]tenant.Tenant, 0, len(config.Tenants))
+
+	for _, t := range config.Tenants {
+		settings := defaults
+
+		if t.PasswordPolicy != nil {
+			settings.PasswordPolicy = *t.PasswordPolicy
+		}
+		if t.AccessTokenDuration != nil {
+			settings.AccessTokenDuration = time.Duration(*t.AccessTokenDuration)
+		}
+		if t.RefreshTokenDuration != nil {
+			settings.RefreshTokenDuration = time.Duration(*t.RefreshTokenDuration)
+		}
+		if t.AllowedOrigins != nil {
+			settings.AllowedOrigins = t.AllowedOrigins
+		}
+
+		tenants = append(tenants, tenant.Tenant{
+			Id:        t.Id,
+			Hosts:     t.Hosts,
+			ClientIds: t.ClientIds,
+			Settings:  settings,
+		})
+	}
+
+	registry, err := tenant.NewRegistry(defaults, tenants)
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error": fmt.Sprintf("[server][tenantRegistry][tenant.NewRegistry] error: %s", err.Error()),
+		}).Fatal("error initiating tenants")
+	}
+
+	return registry
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"os"
+
+	"github.com/michaelyusak/go-auth/adaptor"
+	"github.com/michaelyusak/go-auth/audit"
+	"github.com/michaelyusak/go-auth/config"
+	"github.com/michaelyusak/go-auth/repository"
+	hHelper "github.com/michaelyusak/go-helper/helper"
+	"github.com/sirupsen/logrus"
+)
+
+// VerifyAudit runs the verify-audit subcommand. It walks the auth event
+// chain and exits with status 1 at the first broken link.
+func VerifyAudit() {
+	log := hHelper.NewLogrus()
+
+	config := config.Init(log)
+
+	db := adaptor.ConnectPostgres(config.Postgres, log)
+	defer db.Close()
+
+	verifier := audit.NewVerifier(repository.NewAuthEventRepositoryPostgres(repository.NewDB(db)), config.Audit.CheckpointKey)
+
+	report, err := verifier.Verify(context.Background())
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Fatal("error verifying audit log")
+	}
+
+	if report.Broken != nil {
+		log.WithFields(logrus.Fields{
+			"auth_event_id": report.Broken.AuthEventId,
+			"reason":        report.Broken.Reason,
+			"verified":      report.Events,
+		}).Error("audit log chain is broken")
+
+		db.Close()
+		os.Exit(1)
+	}
+
+	log.Infof("audit log chain intact: %d event(s), %d recorded before chaining, %d checkpoint(s)", report.Events, report.Unchained, report.Checkpoints)
+}
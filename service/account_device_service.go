@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/michaelyusak/go-auth/audit"
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/helper"
+	"github.com/michaelyusak/go-auth/repository"
+	"github.com/michaelyusak/go-helper/apperror"
+)
+
+type accountDeviceServiceImpl struct {
+	accountDeviceRepo repository.AccountDeviceRepository
+	transaction       repository.Transaction
+	tokenHasher       helper.TokenHasher
+	auditRecorder     audit.Recorder
+}
+
+type AccountDeviceServiceOpt struct {
+	AccountDeviceRepo repository.AccountDeviceRepository
+	Transaction       repository.Transaction
+	TokenHasher       helper.TokenHasher
+	AuditRecorder     audit.Recorder
+}
+
+func NewAccountDeviceService(opt AccountDeviceServiceOpt) *accountDeviceServiceImpl {
+	return &accountDeviceServiceImpl{
+		accountDeviceRepo: opt.AccountDeviceRepo,
+		transaction:       opt.Transaction,
+		tokenHasher:       opt.TokenHasher,
+		auditRecorder:     opt.AuditRecorder,
+	}
+}
+
+func (s *accountDeviceServiceImpl) GetDevices(ctx context.Context) ([]entity.AccountDeviceRes, error) {
+	accountId := ctx.Value(constant.AccountIdCtxKey).(int64)
+	currentDeviceId := ctx.Value(constant.DeviceIdCtxKey).(int64)
+
+	accountDevices, err := s.accountDeviceRepo.GetDevicesByAccountId(ctx, accountId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[account_device_service][GetDevices][accountDeviceRepo.GetDevicesByAccountId] Error: %s | account_id: %v", err.Error(), accountId),
+		})
+	}
+
+	res := make([]entity.AccountDeviceRes, 0, len(accountDevices))
+
+	for _, accountDevice := range accountDevices {
+		res = append(res, accountDeviceRes(accountDevice, currentDeviceId))
+	}
+
+	return res, nil
+}
+
+func (s *accountDeviceServiceImpl) RenameDevice(ctx context.Context, deviceId int64, req entity.RenameDeviceReq) error {
+	accountId := ctx.Value(constant.AccountIdCtxKey).(int64)
+
+	accountDevice, err := s.accountDeviceRepo.GetDeviceById(ctx, accountId, deviceId)
+	if err != nil {
+		return apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[account_device_service][RenameDevice][accountDeviceRepo.GetDeviceById] Error: %s | account_id: %v | device_id: %v", err.Error(), accountId, deviceId),
+		})
+	}
+
+	if accountDevice == nil {
+		return apperror.NewAppError(apperror.AppErrorOpt{
+			Code:            http.StatusNotFound,
+			Message:         fmt.Sprintf("[account_device_service][RenameDevice] device not found | account_id: %v | device_id: %v", accountId, deviceId),
+			ResponseMessage: constant.MsgDeviceNotFound,
+		})
+	}
+
+	err = s.accountDeviceRepo.UpdateDeviceName(ctx, deviceId, req.DeviceName)
+	if err != nil {
+		return apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[account_device_service][RenameDevice][accountDeviceRepo.UpdateDeviceName] Error: %s | account_id: %v | device_id: %v", err.Error(), accountId, deviceId),
+		})
+	}
+
+	return nil
+}
+
+// DeleteDevice soft deletes one of the account's devices and revokes every
+// refresh token issued to it.
+func (s *accountDeviceServiceImpl) DeleteDevice(ctx context.Context, deviceId int64) error {
+	accountId := ctx.Value(constant.AccountIdCtxKey).(int64)
+
+	err := s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		accountDevice, err := repos.AccountDevice.GetDeviceById(ctx, accountId, deviceId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_device_service][DeleteDevice][accountDeviceRepo.GetDeviceById] Error: %s | account_id: %v | device_id: %v", err.Error(), accountId, deviceId),
+			})
+		}
+
+		if accountDevice == nil {
+			return apperror.NewAppError(apperror.AppErrorOpt{
+				Code:            http.StatusNotFound,
+				Message:         fmt.Sprintf("[account_device_service][DeleteDevice] device not found | account_id: %v | device_id: %v", accountId, deviceId),
+				ResponseMessage: constant.MsgDeviceNotFound,
+			})
+		}
+
+		err = repos.AccountDevice.SoftDeleteDevice(ctx, deviceId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_device_service][DeleteDevice][accountDeviceRepo.SoftDeleteDevice] Error: %s | account_id: %v | device_id: %v", err.Error(), accountId, deviceId),
+			})
+		}
+
+		err = repos.RefreshToken.DeleteTokenByDeviceId(ctx, deviceId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_device_service][DeleteDevice][refreshTokenRepo.DeleteTokenByDeviceId] Error: %s | account_id: %v | device_id: %v", err.Error(), accountId, deviceId),
+			})
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[account_device_service][DeleteDevice]", authEvent(constant.AuthEventDeviceRevoked, accountId, deviceId, constant.AuthEventOutcomeSuccess, constant.AuthEventReasonUserRequest))
+	})
+	if err != nil {
+		return txError("[account_device_service][DeleteDevice]", err)
+	}
+
+	return nil
+}
+
+// ApproveDeviceByToken approves a pending device with the token from the
+// approval link sent to the account owner.
+func (s *accountDeviceServiceImpl) ApproveDeviceByToken(ctx context.Context, req entity.ApproveDeviceReq) error {
+	accountDevice, err := s.accountDeviceRepo.GetDeviceByApprovalTokenHash(ctx, s.tokenHasher.HashToken(req.Token))
+	if err != nil {
+		return apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[account_device_service][ApproveDeviceByToken][accountDeviceRepo.GetDeviceByApprovalTokenHash] Error: %s", err.Error()),
+		})
+	}
+
+	if accountDevice == nil || accountDevice.ApprovalExpiredAt == nil || *accountDevice.ApprovalExpiredAt <= time.Now().UnixMilli() {
+		return apperror.BadRequestError(apperror.AppErrorOpt{
+			Message:         "[account_device_service][ApproveDeviceByToken] approval token not found or expired",
+			ResponseMessage: constant.MsgInvalidApprovalToken,
+		})
+	}
+
+	err = s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		err := repos.AccountDevice.ApproveDevice(ctx, accountDevice.DeviceId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_device_service][ApproveDeviceByToken][accountDeviceRepo.ApproveDevice] Error: %s | account_id: %v | device_id: %v", err.Error(), accountDevice.AccountId, accountDevice.DeviceId),
+			})
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[account_device_service][ApproveDeviceByToken]", authEvent(constant.AuthEventDeviceApproved, accountDevice.AccountId, accountDevice.DeviceId, constant.AuthEventOutcomeSuccess, constant.AuthEventReasonApprovalLink))
+	})
+	if err != nil {
+		return txError("[account_device_service][ApproveDeviceByToken]", err)
+	}
+
+	return nil
+}
+
+// ApproveDevice approves a pending device from one of the account's already
+// trusted devices; only approved devices are issued access tokens.
+func (s *accountDeviceServiceImpl) ApproveDevice(ctx context.Context, deviceId int64) error {
+	accountId := ctx.Value(constant.AccountIdCtxKey).(int64)
+
+	accountDevice, err := s.accountDeviceRepo.GetDeviceById(ctx, accountId, deviceId)
+	if err != nil {
+		return apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[account_device_service][ApproveDevice][accountDeviceRepo.GetDeviceById] Error: %s | account_id: %v | device_id: %v", err.Error(), accountId, deviceId),
+		})
+	}
+
+	if accountDevice == nil {
+		return apperror.NewAppError(apperror.AppErrorOpt{
+			Code:            http.StatusNotFound,
+			Message:         fmt.Sprintf("[account_device_service][ApproveDevice] device not found | account_id: %v | device_id: %v", accountId, deviceId),
+			ResponseMessage: constant.MsgDeviceNotFound,
+		})
+	}
+
+	err = s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		err := repos.AccountDevice.ApproveDevice(ctx, deviceId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_device_service][ApproveDevice][accountDeviceRepo.ApproveDevice] Error: %s | account_id: %v | device_id: %v", err.Error(), accountId, deviceId),
+			})
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[account_device_service][ApproveDevice]", authEvent(constant.AuthEventDeviceApproved, accountId, deviceId, constant.AuthEventOutcomeSuccess, constant.AuthEventReasonUserRequest))
+	})
+	if err != nil {
+		return txError("[account_device_service][ApproveDevice]", err)
+	}
+
+	return nil
+}
+
+func accountDeviceRes(accountDevice entity.AccountDevice, currentDeviceId int64) entity.AccountDeviceRes {
+	// Devices recorded before user agents were classified on login.
+	if accountDevice.Browser == "" {
+		userAgentInfo := helper.ParseUserAgent(accountDevice.UserAgent)
+
+		accountDevice.Browser = userAgentInfo.Browser
+		accountDevice.Os = userAgentInfo.Os
+		accountDevice.DeviceType = userAgentInfo.DeviceType
+	}
+
+	return entity.AccountDeviceRes{
+		DeviceId:    accountDevice.DeviceId,
+		DeviceName:  accountDevice.DeviceName,
+		Browser:     accountDevice.Browser,
+		Os:          accountDevice.Os,
+		DeviceType:  accountDevice.DeviceType,
+		Platform:    accountDevice.Platform,
+		OsVersion:   accountDevice.OsVersion,
+		AppVersion:  accountDevice.AppVersion,
+		DeviceModel: accountDevice.DeviceModel,
+		UserAgent:   accountDevice.UserAgent,
+		LastIp:      accountDevice.LastIp,
+		IsCurrent:   accountDevice.DeviceId == currentDeviceId,
+		IsApproved:  accountDevice.ApprovedAt != nil,
+		LastSeenAt:  accountDevice.LastSeenAt,
+		CreatedAt:   accountDevice.CreatedAt,
+	}
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/repository"
+	"github.com/michaelyusak/go-helper/apperror"
+	"github.com/sirupsen/logrus"
+)
+
+type accountExportServiceImpl struct {
+	accountRepo       repository.AccountRepository
+	accountDeviceRepo repository.AccountDeviceRepository
+	refreshTokenRepo  repository.RefreshTokenRepository
+	authEventRepo     repository.AuthEventRepository
+	log               *logrus.Logger
+}
+
+type AccountExportServiceOpt struct {
+	AccountRepo       repository.AccountRepository
+	AccountDeviceRepo repository.AccountDeviceRepository
+	RefreshTokenRepo  repository.RefreshTokenRepository
+	AuthEventRepo     repository.AuthEventRepository
+	Log               *logrus.Logger
+}
+
+func NewAccountExportService(opt AccountExportServiceOpt) *accountExportServiceImpl {
+	return &accountExportServiceImpl{
+		accountRepo:       opt.AccountRepo,
+		accountDeviceRepo: opt.AccountDeviceRepo,
+		refreshTokenRepo:  opt.RefreshTokenRepo,
+		authEventRepo:     opt.AuthEventRepo,
+		log:               opt.Log,
+	}
+}
+
+// ExportAccount writes the signed in account's data to w as one JSON
+// document. The account, devices and sessions are loaded before anything is
+// written, so failing to load them leaves w untouched; the audit events are
+// then streamed in batches and never held in memory all at once.
+func (s *accountExportServiceImpl) ExportAccount(ctx context.Context, w io.Writer) error {
+	accountId := ctx.Value(constant.AccountIdCtxKey).(int64)
+
+	export, err := s.loadExport(ctx, accountId)
+	if err != nil {
+		return err
+	}
+
+	err = s.writeExport(ctx, w, accountId, *export)
+	if err != nil {
+		s.log.WithFields(logrus.Fields{
+			"error":      err.Error(),
+			"account_id": accountId,
+		}).Error("[account_export_service][ExportAccount][writeExport]")
+
+		return apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[account_export_service][ExportAccount][writeExport] Error: %s | account_id: %v", err.Error(), accountId),
+		})
+	}
+
+	return nil
+}
+
+func (s *accountExportServiceImpl) loadExport(ctx context.Context, accountId int64) (*entity.AccountExport, error) {
+	account, err := s.accountRepo.GetAccountById(ctx, accountId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[account_export_service][loadExport][accountRepo.GetAccountById] Error: %s | account_id: %v", err.Error(), accountId),
+		})
+	}
+
+	if account == nil {
+		return nil, apperror.NewAppError(apperror.AppErrorOpt{
+			Code:            http.StatusNotFound,
+			Message:         fmt.Sprintf("[account_export_service][loadExport] account not found | account_id: %v", accountId),
+			ResponseMessage: constant.MsgAccountNotFound,
+		})
+	}
+
+	accountDevices, err := s.accountDeviceRepo.GetDevicesByAccountId(ctx, accountId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[account_export_service][loadExport][accountDeviceRepo.GetDevicesByAccountId] Error: %s | account_id: %v", err.Error(), accountId),
+		})
+	}
+
+	refreshTokens, err := s.refreshTokenRepo.GetTokensByAccountId(ctx, accountId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[account_export_service][loadExport][refreshTokenRepo.GetTokensByAccountId] Error: %s | account_id: %v", err.Error(), accountId),
+		})
+	}
+
+	export := &entity.AccountExport{
+		ExportedAt: time.Now().UnixMilli(),
+		Account:    *accountProfile(*account),
+		// Multi-factor authentication is not supported yet, so no account
+		// is enrolled.
+		Mfa: entity.AccountExportMfa{
+			Enrolled: false,
+			Methods:  []string{},
+		},
+		Devices:  make([]entity.AccountExportDevice, 0, len(accountDevices)),
+		Sessions: make([]entity.AccountExportSession, 0, len(refreshTokens)),
+	}
+
+	for _, accountDevice := range accountDevices {
+		pushToken := ""
+		if accountDevice.PushToken != "" {
+			pushToken = constant.ExportRedacted
+		}
+
+		export.Devices = append(export.Devices, entity.AccountExportDevice{
+			DeviceId:    accountDevice.DeviceId,
+			DeviceName:  accountDevice.DeviceName,
+			Browser:     accountDevice.Browser,
+			Os:          accountDevice.Os,
+			DeviceType:  accountDevice.DeviceType,
+			Platform:    accountDevice.Platform,
+			OsVersion:   accountDevice.OsVersion,
+			AppVersion:  accountDevice.AppVersion,
+			DeviceModel: accountDevice.DeviceModel,
+			PushToken:   pushToken,
+			UserAgent:   accountDevice.UserAgent,
+			DeviceInfo:  accountDevice.DeviceInfo,
+			CreatedIp:   accountDevice.CreatedIp,
+			LastIp:      accountDevice.LastIp,
+			ApprovedAt:  accountDevice.ApprovedAt,
+			LastSeenAt:  accountDevice.LastSeenAt,
+			CreatedAt:   accountDevice.CreatedAt,
+		})
+	}
+
+	for _, refreshToken := range refreshTokens {
+		export.Sessions = append(export.Sessions, entity.AccountExportSession{
+			SessionId:  refreshToken.RefreshTokenId,
+			DeviceId:   refreshToken.DeviceId,
+			IpAddress:  refreshToken.IpAddress,
+			ExpiredAt:  refreshToken.ExpiredAt,
+			LastUsedAt: refreshToken.LastUsedAt,
+			RevokedAt:  refreshToken.RevokedAt,
+			CreatedAt:  refreshToken.CreatedAt,
+		})
+	}
+
+	return export, nil
+}
+
+// writeExport writes export with an "auth_events" array appended to it,
+// fetching the events newest first, one batch at a time.
+func (s *accountExportServiceImpl) writeExport(ctx context.Context, w io.Writer, accountId int64, export entity.AccountExport) error {
+	bw := bufio.NewWriter(w)
+
+	head, err := json.Marshal(export)
+	if err != nil {
+		return fmt.Errorf("[account_export_service][writeExport][json.Marshal] Error: %w", err)
+	}
+
+	// Drop the closing brace so the events can follow as one more field.
+	bw.Write(head[:len(head)-1])
+	bw.WriteString(`,"auth_events":[`)
+
+	var (
+		before int64
+		first  = true
+	)
+
+	for {
+		events, err := s.authEventRepo.GetEvents(ctx, entity.AuthEventFilter{
+			AccountId: &accountId,
+			Before:    before,
+			Limit:     constant.ExportAuthEventBatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("[account_export_service][writeExport][authEventRepo.GetEvents] Error: %w", err)
+		}
+
+		for _, event := range events {
+			b, err := json.Marshal(entity.AuthEventRes{
+				AuthEventId:    event.AuthEventId,
+				EventType:      event.EventType,
+				AccountId:      event.AccountId,
+				DeviceId:       event.DeviceId,
+				ActorAccountId: event.ActorAccountId,
+				IpAddress:      event.IpAddress,
+				UserAgent:      event.UserAgent,
+				RequestId:      event.RequestId,
+				Outcome:        event.Outcome,
+				Reason:         event.Reason,
+				CreatedAt:      event.CreatedAt,
+			})
+			if err != nil {
+				return fmt.Errorf("[account_export_service][writeExport][json.Marshal] Error: %w", err)
+			}
+
+			if !first {
+				bw.WriteByte(',')
+			}
+			first = false
+
+			bw.Write(b)
+		}
+
+		if len(events) < constant.ExportAuthEventBatchSize {
+			break
+		}
+
+		before = events[len(events)-1].AuthEventId
+
+		// Hand each batch on instead of buffering the whole history.
+		err = bw.Flush()
+		if err != nil {
+			return fmt.Errorf("[account_export_service][writeExport][Flush] Error: %w", err)
+		}
+	}
+
+	bw.WriteString("]}")
+
+	err = bw.Flush()
+	if err != nil {
+		return fmt.Errorf("[account_export_service][writeExport][Flush] Error: %w", err)
+	}
+
+	return nil
+}
@@ -5,163 +5,231 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/michaelyusak/go-auth/audit"
 	"github.com/michaelyusak/go-auth/constant"
 	"github.com/michaelyusak/go-auth/entity"
 	"github.com/michaelyusak/go-auth/helper"
+	"github.com/michaelyusak/go-auth/notifier"
 	"github.com/michaelyusak/go-auth/repository"
+	"github.com/michaelyusak/go-auth/tenant"
 	"github.com/michaelyusak/go-helper/apperror"
 	hHelper "github.com/michaelyusak/go-helper/helper"
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	refreshTokenBytes  = 32
+	familyIdBytes      = 16
+	approvalTokenBytes = 32
+)
+
 type accountServiceImpl struct {
-	accountRepo       repository.AccountRepository
-	refreshTokenRepo  repository.RefreshTokenRepository
-	accountDeviceRepo repository.AccountDeviceRepository
-	transaction       repository.Transaction
-	hash              hHelper.HashHelper
-	jwt               hHelper.JWTHelper
-	log               *logrus.Logger
-	subRoutineTimeout time.Duration
+	accountRepo           repository.AccountRepository
+	refreshTokenRepo      repository.RefreshTokenRepository
+	accountDeviceRepo     repository.AccountDeviceRepository
+	transaction           repository.Transaction
+	hash                  hHelper.HashHelper
+	jwt                   hHelper.JWTHelper
+	tokenHasher           helper.TokenHasher
+	log                   *logrus.Logger
+	subRoutineTimeout     time.Duration
+	tenants               tenant.Registry
+	maxSessionsPerAccount int
+	singleSession         bool
+	newDeviceNotifier     notifier.NewDeviceNotifier
+	requireDeviceApproval bool
+	deviceApprovalTtl     time.Duration
+	deviceApprovalUrl     string
+	legacyDeviceHash      bool
+	deletionGracePeriod   time.Duration
+	auditRecorder         audit.Recorder
 }
 
 type AccountServiceOpt struct {
-	AccountRepo       repository.AccountRepository
-	RefreshTokenRepo  repository.RefreshTokenRepository
-	AccountDeviceRepo repository.AccountDeviceRepository
-	Transaction       repository.Transaction
-	Hash              hHelper.HashHelper
-	Jwt               hHelper.JWTHelper
-	Log               *logrus.Logger
-	SubRoutineTimeout time.Duration
+	AccountRepo           repository.AccountRepository
+	RefreshTokenRepo      repository.RefreshTokenRepository
+	AccountDeviceRepo     repository.AccountDeviceRepository
+	Transaction           repository.Transaction
+	Hash                  hHelper.HashHelper
+	Jwt                   hHelper.JWTHelper
+	TokenHasher           helper.TokenHasher
+	Log                   *logrus.Logger
+	SubRoutineTimeout     time.Duration
+	Tenants               tenant.Registry
+	MaxSessionsPerAccount int
+	SingleSession         bool
+	NewDeviceNotifier     notifier.NewDeviceNotifier
+	RequireDeviceApproval bool
+	DeviceApprovalTtl     time.Duration
+	DeviceApprovalUrl     string
+	LegacyDeviceHash      bool
+	DeletionGracePeriod   time.Duration
+	AuditRecorder         audit.Recorder
 }
 
 func NewAccountService(opt AccountServiceOpt) *accountServiceImpl {
 	return &accountServiceImpl{
-		accountRepo:       opt.AccountRepo,
-		refreshTokenRepo:  opt.RefreshTokenRepo,
-		accountDeviceRepo: opt.AccountDeviceRepo,
-		transaction:       opt.Transaction,
-		hash:              opt.Hash,
-		jwt:               opt.Jwt,
-		log:               opt.Log,
-		subRoutineTimeout: opt.SubRoutineTimeout,
+		accountRepo:           opt.AccountRepo,
+		refreshTokenRepo:      opt.RefreshTokenRepo,
+		accountDeviceRepo:     opt.AccountDeviceRepo,
+		transaction:           opt.Transaction,
+		hash:                  opt.Hash,
+		jwt:                   opt.Jwt,
+		tokenHasher:           opt.TokenHasher,
+		log:                   opt.Log,
+		subRoutineTimeout:     opt.SubRoutineTimeout,
+		tenants:               opt.Tenants,
+		maxSessionsPerAccount: opt.MaxSessionsPerAccount,
+		singleSession:         opt.SingleSession,
+		newDeviceNotifier:     opt.NewDeviceNotifier,
+		requireDeviceApproval: opt.RequireDeviceApproval,
+		deviceApprovalTtl:     opt.DeviceApprovalTtl,
+		deviceApprovalUrl:     opt.DeviceApprovalUrl,
+		legacyDeviceHash:      opt.LegacyDeviceHash,
+		deletionGracePeriod:   opt.DeletionGracePeriod,
+		auditRecorder:         opt.AuditRecorder,
 	}
 }
 
+// Register creates the account in the tenant of the request. Email, phone
+// number and name only need to be unique within the tenant. With an
+// invitation token the account joins the organization it was invited to, or
+// is not created at all.
 func (s *accountServiceImpl) Register(ctx context.Context, newAccount entity.Account) error {
-	if !helper.ValidatePassword(newAccount.Password) {
+	newAccount.TenantId = requestTenant(ctx)
+
+	if !s.tenants.Settings(newAccount.TenantId).PasswordPolicy.Validate(newAccount.Password) {
+		s.auditRecorder.Record(ctx, authEvent(constant.AuthEventRegister, 0, 0, constant.AuthEventOutcomeFailure, constant.AuthEventReasonWeakPassword))
+
 		return apperror.BadRequestError(apperror.AppErrorOpt{
 			Message:         constant.MsgInvalidPassword,
 			ResponseMessage: constant.MsgInvalidPassword,
 		})
 	}
 
-	err := s.transaction.Begin()
-	if err != nil {
-		return apperror.InternalServerError(apperror.AppErrorOpt{
-			Message: fmt.Sprintf("[account_service][Register][transaction.Begin] Error: %s", err.Error()),
-		})
-	}
+	var failReason string
 
-	accountRepo := s.transaction.AccounPostgrestTx()
+	err := s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		failReason = constant.AuthEventReasonInternalError
 
-	defer func() {
+		err := repos.Account.Lock(ctx)
 		if err != nil {
-			s.transaction.Rollback()
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][Register][accountRepo.Lock] Error: %s", err.Error()),
+			})
 		}
 
-		s.transaction.Commit()
-	}()
+		existing, err := repos.Account.GetAccountByEmail(ctx, newAccount.TenantId, newAccount.Email)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][Register][accountRepo.GetAccountByEmail] Error: %s", err.Error()),
+			})
+		}
+		if existing != nil {
+			failReason = constant.AuthEventReasonEmailTaken
 
-	err = accountRepo.Lock(ctx)
-	if err != nil {
-		return apperror.InternalServerError(apperror.AppErrorOpt{
-			Message: fmt.Sprintf("[account_service][Register][transaction.AccounPostgrestTx] Error: %s", err.Error()),
-		})
-	}
+			return apperror.BadRequestError(apperror.AppErrorOpt{
+				Message:         "[account_service][Register] email already registered",
+				ResponseMessage: "email already registered",
+			})
+		}
 
-	existing, err := accountRepo.GetAccountByEmail(ctx, newAccount.Email)
-	if err != nil {
-		return apperror.InternalServerError(apperror.AppErrorOpt{
-			Message: fmt.Sprintf("[account_service][Register][accountRepo.GetAccountByEmail] Error: %s", err.Error()),
-		})
-	}
-	if existing != nil {
-		return apperror.BadRequestError(apperror.AppErrorOpt{
-			Message:         "[account_service][Register] email already registered",
-			ResponseMessage: "email already registered",
-		})
-	}
+		existing, err = repos.Account.GetAccountByPhoneNumber(ctx, newAccount.TenantId, newAccount.PhoneNumber)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][Register][accountRepo.GetAccountByPhoneNumber] Error: %s", err.Error()),
+			})
+		}
+		if existing != nil {
+			failReason = constant.AuthEventReasonPhoneNumberTaken
 
-	existing, err = accountRepo.GetAccountByPhoneNumber(ctx, newAccount.PhoneNumber)
-	if err != nil {
-		return apperror.InternalServerError(apperror.AppErrorOpt{
-			Message: fmt.Sprintf("[account_service][Register][accountRepo.GetAccountByPhoneNumber] Error: %s", err.Error()),
-		})
-	}
-	if existing != nil {
-		return apperror.BadRequestError(apperror.AppErrorOpt{
-			Message:         "[account_service][Register] phone number already registered",
-			ResponseMessage: "phone number already registered",
-		})
-	}
+			return apperror.BadRequestError(apperror.AppErrorOpt{
+				Message:         "[account_service][Register] phone number already registered",
+				ResponseMessage: "phone number already registered",
+			})
+		}
 
-	existing, err = accountRepo.GetAccountByName(ctx, newAccount.Name)
-	if err != nil {
-		return apperror.InternalServerError(apperror.AppErrorOpt{
-			Message: fmt.Sprintf("[account_service][Register][accountRepo.GetAccountByName] Error: %s", err.Error()),
-		})
-	}
-	if existing != nil {
-		return apperror.BadRequestError(apperror.AppErrorOpt{
-			Message:         "[account_service][Register] name already registered",
-			ResponseMessage: "name already registered",
-		})
-	}
+		existing, err = repos.Account.GetAccountByName(ctx, newAccount.TenantId, newAccount.Name)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][Register][accountRepo.GetAccountByName] Error: %s", err.Error()),
+			})
+		}
+		if existing != nil {
+			failReason = constant.AuthEventReasonNameTaken
 
-	hash, err := s.hash.Hash(newAccount.Password)
-	if err != nil {
-		return apperror.InternalServerError(apperror.AppErrorOpt{
-			Message: fmt.Sprintf("[account_service][Register][hash.Hash] passwordHash | Error: %s", err.Error()),
-		})
-	}
+			return apperror.BadRequestError(apperror.AppErrorOpt{
+				Message:         "[account_service][Register] name already registered",
+				ResponseMessage: "name already registered",
+			})
+		}
+
+		hash, err := s.hash.Hash(newAccount.Password)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][Register][hash.Hash] passwordHash | Error: %s", err.Error()),
+			})
+		}
+
+		account := newAccount
+		account.Password = hash
+
+		accountId, err := repos.Account.Register(ctx, account)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][Register][accountRepo.Register] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
 
-	newAccount.Password = hash
+		newAccount.Id = accountId
 
-	accountId, err := accountRepo.Register(ctx, newAccount)
+		err = recordTx(ctx, s.auditRecorder, repos, "[account_service][Register]", authEvent(constant.AuthEventRegister, accountId, 0, constant.AuthEventOutcomeSuccess, ""))
+		if err != nil {
+			return err
+		}
+
+		if newAccount.InvitationToken == "" {
+			return nil
+		}
+
+		account.Id = accountId
+
+		membership, err := acceptInvitation(ctx, repos, "[account_service][Register]", s.tokenHasher.HashToken(newAccount.InvitationToken), account)
+		if err != nil {
+			failReason = constant.AuthEventReasonInvalidInvitation
+
+			return err
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[account_service][Register]", memberEvent(constant.AuthEventOrganizationMemberAdded, accountId, accountId, membership.Organization.OrganizationId))
+	})
 	if err != nil {
-		return apperror.InternalServerError(apperror.AppErrorOpt{
-			Message: fmt.Sprintf("[account_service][Register][accountRepo.Register] Error: %s | account_id: %v", err.Error(), accountId),
-		})
+		s.auditRecorder.Record(ctx, authEvent(constant.AuthEventRegister, 0, 0, constant.AuthEventOutcomeFailure, failReason))
+
+		return txError("[account_service][Register]", err)
 	}
 
-	newAccount.Id = accountId
+	approvedAt := time.Now().UnixMilli()
 
-	userAgent := ctx.Value(constant.UserAgentCtxKey).(string)
-	deviceInfo := ctx.Value(constant.DeviceInfoCtxKey).(string)
+	// The device an account registers from is trusted from the start.
+	accountDevice := s.requestDevice(ctx, newAccount.Id)
+	accountDevice.ApprovedAt = &approvedAt
 
 	go func() {
 		ctx, cancel := context.WithTimeout(context.Background(), s.subRoutineTimeout)
 		defer cancel()
 
-		deviceHash := s.hash.HashSHA512(fmt.Sprintf("%v%s%s", newAccount.Id, userAgent, deviceInfo))
-
-		accountDevice := entity.AccountDevice{
-			AccountId:  newAccount.Id,
-			DeviceHash: deviceHash,
-			UserAgent:  userAgent,
-			DeviceInfo: deviceInfo,
-		}
-
-		_, err = s.accountDeviceRepo.InsertDevice(ctx, accountDevice)
+		_, err := s.accountDeviceRepo.InsertDevice(ctx, accountDevice)
 		if err != nil {
 			s.log.WithFields(logrus.Fields{
 				"error":       err.Error(),
 				"account_id":  newAccount.Id,
-				"device_hash": deviceHash,
+				"device_hash": accountDevice.DeviceHash,
 			}).Error("[account_service][Register][accountDeviceRepo.InsertDevice][sub-routine]")
 		}
 	}()
@@ -177,154 +245,762 @@ func (s *accountServiceImpl) Login(ctx context.Context, req entity.LoginReq) (*e
 		})
 	}
 
-	err := s.transaction.Begin()
-	if err != nil {
-		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
-			Message: fmt.Sprintf("[account_service][Login][transaction.Begin] Error: %s", err.Error()),
-		})
-	}
+	var (
+		tokenData      *entity.TokenData
+		newDeviceEvent *entity.NewDeviceEvent
+		pendingErr     error
+		failReason     string
+		accountId      int64
+		deviceId       int64
+	)
 
-	accountRepo := s.transaction.AccounPostgrestTx()
-	refreshTokenRepo := s.transaction.RefreshTokenPostgresTx()
-	accountDeviceRepo := s.transaction.AccountDevicePostgresTx()
+	// The session is only handed out once it is committed.
+	err := s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		newDeviceEvent = nil
+		pendingErr = nil
+		failReason = constant.AuthEventReasonInternalError
+		accountId = 0
+		deviceId = 0
 
-	defer func() {
+		err := repos.Account.Lock(ctx)
 		if err != nil {
-			s.transaction.Rollback()
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][Login][accountRepo.Lock] Error: %s", err.Error()),
+			})
 		}
 
-		s.transaction.Commit()
-	}()
+		var account *entity.Account
 
-	err = accountRepo.Lock(ctx)
-	if err != nil {
-		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
-			Message: fmt.Sprintf("[account_service][Login][transaction.AccounPostgrestTx] Error: %s", err.Error()),
-		})
-	}
+		if req.Email != "" {
+			account, err = repos.Account.GetAccountByEmail(ctx, requestTenant(ctx), req.Email)
+			if err != nil {
+				return apperror.InternalServerError(apperror.AppErrorOpt{
+					Message: fmt.Sprintf("[account_service][Login][accountRepo.GetAccountByEmail] Error: %s | email: %s", err.Error(), req.Email),
+				})
+			}
+		} else if req.Name != "" {
+			account, err = repos.Account.GetAccountByName(ctx, requestTenant(ctx), req.Name)
+			if err != nil {
+				return apperror.InternalServerError(apperror.AppErrorOpt{
+					Message: fmt.Sprintf("[account_service][Login][accountRepo.GetAccountByName] Error: %s | name: %s", err.Error(), req.Name),
+				})
+			}
+		}
 
-	var account *entity.Account
+		// A deleted account can be restored by logging in until its grace
+		// period ends.
+		if account == nil || (account.DeletedAt != nil && s.deletionGraceEnded(*account.DeletedAt)) {
+			failReason = constant.AuthEventReasonAccountNotFound
 
-	if req.Email != "" {
-		account, err = accountRepo.GetAccountByEmail(ctx, req.Email)
+			return apperror.NewAppError(apperror.AppErrorOpt{
+				Code:            http.StatusForbidden,
+				Message:         fmt.Sprintf("[account_service][Login] account not found | email: %s | name: %s", req.Email, req.Name),
+				ResponseMessage: constant.MsgAccountNotFound,
+			})
+		}
+
+		accountId = account.Id
+
+		isValid, err := s.hash.Check(req.Password, []byte(account.Password))
 		if err != nil {
-			return nil, apperror.InternalServerError(apperror.AppErrorOpt{
-				Message: fmt.Sprintf("[account_service][Login][accountRepo.GetAccountByEmail] Error: %s | email: %s", err.Error(), req.Email),
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][Login][hash.Check] Error: %s | account_id: %v", err.Error(), account.Id),
+			})
+		}
+
+		if !isValid {
+			failReason = constant.AuthEventReasonInvalidCredentials
+
+			return apperror.UnauthorizedError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("[account_service][Login] invalid credentials | account_id: %v", account.Id),
+				ResponseMessage: constant.MsgInvalidLogin,
 			})
 		}
-	} else if req.Name != "" {
-		account, err = accountRepo.GetAccountByName(ctx, req.Name)
+
+		err = accountStatusError("[account_service][Login]", *account)
+		if err != nil {
+			failReason = constant.AuthEventReasonAccountInactive
+
+			return err
+		}
+
+		err = passwordResetError("[account_service][Login]", *account)
+		if err != nil {
+			failReason = constant.AuthEventReasonResetRequired
+
+			return err
+		}
+
+		var member *entity.OrganizationMember
+
+		if req.OrganizationId != 0 {
+			member, err = repos.Organization.GetMember(ctx, req.OrganizationId, account.Id)
+			if err != nil {
+				return apperror.InternalServerError(apperror.AppErrorOpt{
+					Message: fmt.Sprintf("[account_service][Login][organizationRepo.GetMember] Error: %s | account_id: %v | organization_id: %v", err.Error(), account.Id, req.OrganizationId),
+				})
+			}
+
+			if member == nil {
+				failReason = constant.AuthEventReasonNotMember
+
+				return apperror.NewAppError(apperror.AppErrorOpt{
+					Code:            http.StatusForbidden,
+					Message:         fmt.Sprintf("[account_service][Login] not an organization member | account_id: %v | organization_id: %v", account.Id, req.OrganizationId),
+					ResponseMessage: constant.MsgNotOrganizationMember,
+				})
+			}
+		}
+
+		if account.DeletedAt != nil {
+			err = repos.Account.RestoreAccount(ctx, account.Id)
+			if err != nil {
+				return apperror.InternalServerError(apperror.AppErrorOpt{
+					Message: fmt.Sprintf("[account_service][Login][accountRepo.RestoreAccount] Error: %s | account_id: %v", err.Error(), account.Id),
+				})
+			}
+
+			err = recordTx(ctx, s.auditRecorder, repos, "[account_service][Login]", authEvent(constant.AuthEventAccountRestored, account.Id, 0, constant.AuthEventOutcomeSuccess, ""))
+			if err != nil {
+				return err
+			}
+		}
+
+		newDevice := s.requestDevice(ctx, account.Id)
+
+		accountDevice, err := repos.AccountDevice.GetDeviceByHash(ctx, newDevice.DeviceHash)
 		if err != nil {
-			return nil, apperror.InternalServerError(apperror.AppErrorOpt{
-				Message: fmt.Sprintf("[account_service][Login][accountRepo.GetAccountByName] Error: %s | name: %s", err.Error(), req.Name),
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][Login][accountDeviceRepo.GetDeviceByHash] Error: %s | account_id: %v", err.Error(), account.Id),
 			})
 		}
+
+		if accountDevice == nil && !s.legacyDeviceHash {
+			accountDevice, err = repos.AccountDevice.GetDeviceByHash(ctx, s.legacyHash(ctx, account.Id))
+			if err != nil {
+				return apperror.InternalServerError(apperror.AppErrorOpt{
+					Message: fmt.Sprintf("[account_service][Login][accountDeviceRepo.GetDeviceByHash] legacy hash | Error: %s | account_id: %v", err.Error(), account.Id),
+				})
+			}
+		}
+
+		if accountDevice != nil {
+			// Versions and the push token change over the life of a device,
+			// and devices found by their legacy hash are rehashed.
+			newDevice.DeviceId = accountDevice.DeviceId
+
+			err = repos.AccountDevice.UpdateDeviceDetails(ctx, newDevice)
+			if err != nil {
+				return apperror.InternalServerError(apperror.AppErrorOpt{
+					Message: fmt.Sprintf("[account_service][Login][accountDeviceRepo.UpdateDeviceDetails] Error: %s | account_id: %v", err.Error(), account.Id),
+				})
+			}
+		}
+
+		if accountDevice == nil {
+			var approvalToken string
+
+			if s.requireDeviceApproval {
+				approvalToken, err = helper.GenerateOpaqueToken(approvalTokenBytes)
+				if err != nil {
+					return apperror.InternalServerError(apperror.AppErrorOpt{
+						Message: fmt.Sprintf("[account_service][Login][helper.GenerateOpaqueToken] approval token | Error: %s | account_id: %v", err.Error(), account.Id),
+					})
+				}
+
+				approvalTokenHash := s.tokenHasher.HashToken(approvalToken)
+				approvalExpiredAt := time.Now().Add(s.deviceApprovalTtl).UnixMilli()
+
+				newDevice.ApprovalTokenHash = &approvalTokenHash
+				newDevice.ApprovalExpiredAt = &approvalExpiredAt
+			} else {
+				approvedAt := time.Now().UnixMilli()
+				newDevice.ApprovedAt = &approvedAt
+			}
+
+			newDeviceId, err := repos.AccountDevice.InsertDevice(ctx, newDevice)
+			if err != nil {
+				return apperror.InternalServerError(apperror.AppErrorOpt{
+					Message: fmt.Sprintf("[account_service][Login][accountDeviceRepo.InsertDevice] Error: %s | account_id: %v", err.Error(), account.Id),
+				})
+			}
+
+			accountDevice = &newDevice
+			accountDevice.DeviceId = newDeviceId
+
+			newDeviceEvent = s.newDeviceEvent(ctx, *account, *accountDevice, approvalToken)
+		} else if accountDevice.ApprovedAt == nil && (accountDevice.ApprovalExpiredAt == nil || *accountDevice.ApprovalExpiredAt <= time.Now().UnixMilli()) {
+			// The approval link expired, so a fresh one is sent.
+			approvalToken, err := helper.GenerateOpaqueToken(approvalTokenBytes)
+			if err != nil {
+				return apperror.InternalServerError(apperror.AppErrorOpt{
+					Message: fmt.Sprintf("[account_service][Login][helper.GenerateOpaqueToken] approval token | Error: %s | account_id: %v", err.Error(), account.Id),
+				})
+			}
+
+			err = repos.AccountDevice.UpdateApprovalToken(ctx, accountDevice.DeviceId, s.tokenHasher.HashToken(approvalToken), time.Now().Add(s.deviceApprovalTtl).UnixMilli())
+			if err != nil {
+				return apperror.InternalServerError(apperror.AppErrorOpt{
+					Message: fmt.Sprintf("[account_service][Login][accountDeviceRepo.UpdateApprovalToken] Error: %s | account_id: %v", err.Error(), account.Id),
+				})
+			}
+
+			newDeviceEvent = s.newDeviceEvent(ctx, *account, *accountDevice, approvalToken)
+		} else {
+			err = repos.AccountDevice.UpdateLastSeen(ctx, accountDevice.DeviceId, newDevice.LastIp)
+			if err != nil {
+				return apperror.InternalServerError(apperror.AppErrorOpt{
+					Message: fmt.Sprintf("[account_service][Login][accountDeviceRepo.UpdateLastSeen] Error: %s | account_id: %v", err.Error(), account.Id),
+				})
+			}
+
+			// A device holds one session; logging in again replaces it.
+			err = repos.RefreshToken.DeleteDeviceSession(ctx, accountDevice.DeviceId)
+			if err != nil {
+				return apperror.InternalServerError(apperror.AppErrorOpt{
+					Message: fmt.Sprintf("[account_service][Login][refreshTokenRepo.DeleteDeviceSession] Error: %s | account_id: %v", err.Error(), account.Id),
+				})
+			}
+		}
+
+		deviceId = accountDevice.DeviceId
+
+		// A device waiting for approval is recorded, but gets no session.
+		if accountDevice.ApprovedAt == nil {
+			pendingErr = apperror.NewAppError(apperror.AppErrorOpt{
+				Code:            http.StatusForbidden,
+				Message:         fmt.Sprintf("[account_service][Login] device pending approval | account_id: %v | device_id: %v", account.Id, accountDevice.DeviceId),
+				ResponseMessage: constant.MsgDeviceApprovalRequired,
+			})
+
+			return recordTx(ctx, s.auditRecorder, repos, "[account_service][Login]", authEvent(constant.AuthEventLogin, account.Id, accountDevice.DeviceId, constant.AuthEventOutcomeFailure, constant.AuthEventReasonDevicePending))
+		}
+
+		data, newToken, err := s.issueTokens(ctx, repos.Role, *account, accountDevice.DeviceId, "", member, nil)
+		if err != nil {
+			return err
+		}
+
+		err = repos.RefreshToken.InsertToken(ctx, *newToken)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][Login][refreshTokenRepo.InsertToken] Error: %s | account_id: %v", err.Error(), account.Id),
+			})
+		}
+
+		reason := ""
+		if newDeviceEvent != nil {
+			reason = constant.AuthEventReasonNewDevice
+		}
+
+		err = recordTx(ctx, s.auditRecorder, repos, "[account_service][Login]", authEvent(constant.AuthEventLogin, account.Id, accountDevice.DeviceId, constant.AuthEventOutcomeSuccess, reason))
+		if err != nil {
+			return err
+		}
+
+		if maxSessions := s.maxSessions(); maxSessions > 0 {
+			evicted, err := repos.RefreshToken.DeleteLeastRecentlyUsedSessions(ctx, account.Id, maxSessions)
+			if err != nil {
+				return apperror.InternalServerError(apperror.AppErrorOpt{
+					Message: fmt.Sprintf("[account_service][Login][refreshTokenRepo.DeleteLeastRecentlyUsedSessions] Error: %s | account_id: %v", err.Error(), account.Id),
+				})
+			}
+
+			if evicted > 0 {
+				s.log.WithFields(logrus.Fields{
+					"account_id":   account.Id,
+					"device_id":    accountDevice.DeviceId,
+					"max_sessions": maxSessions,
+					"evicted":      evicted,
+				}).Info("[account_service][Login] evicted least recently used sessions")
+
+				err = recordTx(ctx, s.auditRecorder, repos, "[account_service][Login]", authEvent(constant.AuthEventSessionRevoked, account.Id, 0, constant.AuthEventOutcomeSuccess, constant.AuthEventReasonSessionLimit))
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		tokenData = data
+
+		return nil
+	})
+	if err != nil {
+		s.auditRecorder.Record(ctx, authEvent(constant.AuthEventLogin, accountId, deviceId, constant.AuthEventOutcomeFailure, failReason))
+
+		return nil, txError("[account_service][Login]", err)
 	}
 
-	if account == nil {
-		return nil, apperror.NewAppError(apperror.AppErrorOpt{
-			Code:            http.StatusForbidden,
-			Message:         fmt.Sprintf("[account_service][Login] account not found | email: %s | name: %s", req.Email, req.Name),
-			ResponseMessage: constant.MsgAccountNotFound,
-		})
+	if newDeviceEvent != nil {
+		s.notifyNewDevice(*newDeviceEvent)
 	}
 
-	isValid, err := s.hash.Check(req.Password, []byte(account.Password))
-	if err != nil {
-		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
-			Message: fmt.Sprintf("[account_service][Login][hash.Check] Error: %s | account_id: %v", err.Error(), account.Id),
-		})
+	if pendingErr != nil {
+		return nil, pendingErr
 	}
 
-	if !isValid {
-		return nil, apperror.UnauthorizedError(apperror.AppErrorOpt{
-			Message:         fmt.Sprintf("[account_service][Login] invalid credentials | account_id: %v", account.Id),
-			ResponseMessage: constant.MsgInvalidLogin,
-		})
+	return tokenData, nil
+}
+
+// requestDevice describes the device the request comes from, as it would be
+// stored for the account.
+func (s *accountServiceImpl) requestDevice(ctx context.Context, accountId int64) entity.AccountDevice {
+	userAgent := ctx.Value(constant.UserAgentCtxKey).(string)
+	deviceInfo := ctx.Value(constant.DeviceInfoCtxKey).(string)
+	deviceDetails := ctx.Value(constant.DeviceDetailsCtxKey).(entity.DeviceInfo)
+	clientIp := ctx.Value(constant.ClientIpCtxKey).(string)
+	userAgentInfo := helper.ParseUserAgent(userAgent)
+
+	return entity.AccountDevice{
+		AccountId:   accountId,
+		DeviceHash:  s.deviceHash(ctx, accountId, userAgentInfo, deviceDetails),
+		UserAgent:   userAgent,
+		DeviceInfo:  deviceInfo,
+		Browser:     userAgentInfo.Browser,
+		Os:          userAgentInfo.Os,
+		DeviceType:  userAgentInfo.DeviceType,
+		Platform:    deviceDetails.Platform,
+		OsVersion:   deviceDetails.OsVersion,
+		AppVersion:  deviceDetails.AppVersion,
+		DeviceModel: deviceDetails.DeviceModel,
+		PushToken:   deviceDetails.PushToken,
+		CreatedIp:   clientIp,
+		LastIp:      clientIp,
 	}
+}
+
+// deviceHash identifies the device an account signs in from. It combines
+// the parts of the request headers that stay the same across app and OS
+// updates with the device key from the server issued device token, so
+// copying a known device's headers is not enough to pass as it. With
+// legacyDeviceHash the raw headers alone are used, as before device tokens.
+func (s *accountServiceImpl) deviceHash(ctx context.Context, accountId int64, userAgentInfo entity.UserAgentInfo, deviceDetails entity.DeviceInfo) string {
+	if s.legacyDeviceHash {
+		return s.legacyHash(ctx, accountId)
+	}
+
+	deviceKey := ctx.Value(constant.DeviceKeyCtxKey).(string)
 
+	return s.hash.HashSHA512(fmt.Sprintf("%v%s%s%s%s%s%s", accountId, userAgentInfo.Browser, userAgentInfo.Os, userAgentInfo.DeviceType,
+		deviceDetails.Platform, deviceDetails.DeviceModel, deviceKey))
+}
+
+// legacyHash is the device hash devices were recorded with before device
+// tokens: the raw headers alone. Login still finds devices by it and moves
+// them to deviceHash, so they are not seen as new.
+func (s *accountServiceImpl) legacyHash(ctx context.Context, accountId int64) string {
 	userAgent := ctx.Value(constant.UserAgentCtxKey).(string)
 	deviceInfo := ctx.Value(constant.DeviceInfoCtxKey).(string)
 
-	accountDeviceHash := s.hash.HashSHA512(fmt.Sprintf("%v%s%s", account.Id, userAgent, deviceInfo))
+	return s.hash.HashSHA512(fmt.Sprintf("%v%s%s", accountId, userAgent, deviceInfo))
+}
+
+func (s *accountServiceImpl) newDeviceEvent(ctx context.Context, account entity.Account, accountDevice entity.AccountDevice, approvalToken string) *entity.NewDeviceEvent {
+	event := &entity.NewDeviceEvent{
+		AccountId:   account.Id,
+		Email:       account.Email,
+		Name:        account.Name,
+		DeviceId:    accountDevice.DeviceId,
+		UserAgent:   accountDevice.UserAgent,
+		Browser:     accountDevice.Browser,
+		Os:          accountDevice.Os,
+		DeviceType:  accountDevice.DeviceType,
+		Platform:    accountDevice.Platform,
+		DeviceModel: accountDevice.DeviceModel,
+		DeviceInfo:  accountDevice.DeviceInfo,
+		IpAddress:   ctx.Value(constant.ClientIpCtxKey).(string),
+		OccurredAt:  time.Now().UnixMilli(),
+	}
+
+	if approvalToken != "" {
+		event.ApprovalUrl = fmt.Sprintf("%s?token=%s", s.deviceApprovalUrl, url.QueryEscape(approvalToken))
+	}
+
+	return event
+}
+
+// notifyNewDevice sends the notification in the background; a failed
+// notification does not fail the login.
+func (s *accountServiceImpl) notifyNewDevice(event entity.NewDeviceEvent) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), s.subRoutineTimeout)
+		defer cancel()
+
+		err := s.newDeviceNotifier.NotifyNewDevice(ctx, event)
+		if err != nil {
+			s.log.WithFields(logrus.Fields{
+				"error":      err.Error(),
+				"account_id": event.AccountId,
+				"device_id":  event.DeviceId,
+			}).Error("[account_service][notifyNewDevice][newDeviceNotifier.NotifyNewDevice][sub-routine]")
+		}
+	}()
+}
+
+// IssueClientTokens starts a session for an OAuth client the account
+// authorized from the device. The account must belong to the tenant of the
+// request, which is the client's.
+func (s *accountServiceImpl) IssueClientTokens(ctx context.Context, accountId, deviceId int64, grant entity.ClientGrant) (*entity.TokenData, error) {
+	var (
+		tokenData  *entity.TokenData
+		failReason string
+	)
+
+	err := s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		failReason = constant.AuthEventReasonInternalError
+
+		account, err := repos.Account.GetAccountById(ctx, accountId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][IssueClientTokens][accountRepo.GetAccountById] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		if account == nil || account.DeletedAt != nil || account.TenantId != requestTenant(ctx) {
+			failReason = constant.AuthEventReasonAccountNotFound
+
+			return apperror.UnauthorizedError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("[account_service][IssueClientTokens] account not found | account_id: %v | client_id: %s", accountId, grant.ClientId),
+				ResponseMessage: constant.MsgUnauthorized,
+			})
+		}
+
+		err = accountStatusError("[account_service][IssueClientTokens]", *account)
+		if err != nil {
+			failReason = constant.AuthEventReasonAccountInactive
+
+			return err
+		}
+
+		err = passwordResetError("[account_service][IssueClientTokens]", *account)
+		if err != nil {
+			failReason = constant.AuthEventReasonResetRequired
+
+			return err
+		}
+
+		data, newToken, err := s.issueTokens(ctx, repos.Role, *account, deviceId, "", nil, &grant)
+		if err != nil {
+			return err
+		}
+
+		err = repos.RefreshToken.InsertToken(ctx, *newToken)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][IssueClientTokens][refreshTokenRepo.InsertToken] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		tokenData = data
 
-	accountDevice, err := accountDeviceRepo.GetDeviceByHash(ctx, accountDeviceHash)
+		return recordTx(ctx, s.auditRecorder, repos, "[account_service][IssueClientTokens]", authEvent(constant.AuthEventOAuthTokenIssued, accountId, deviceId, constant.AuthEventOutcomeSuccess, grant.ClientId))
+	})
 	if err != nil {
-		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
-			Message: fmt.Sprintf("[account_service][Login][accountDeviceRepo.GetDeviceByHash] Error: %s | account_id: %v", err.Error(), account.Id),
-		})
+		s.auditRecorder.Record(ctx, authEvent(constant.AuthEventOAuthTokenIssued, accountId, deviceId, constant.AuthEventOutcomeFailure, failReason))
+
+		return nil, txError("[account_service][IssueClientTokens]", err)
 	}
 
-	if accountDevice == nil {
-		err = refreshTokenRepo.DeleteTokenByAccountId(ctx, account.Id)
+	return tokenData, nil
+}
+
+func (s *accountServiceImpl) RefreshToken(ctx context.Context, req entity.RefreshTokenReq) (*entity.TokenData, error) {
+	return s.refreshToken(ctx, "[account_service][RefreshToken]", constant.AuthEventTokenRefresh, req.RefreshToken, nil, nil)
+}
+
+// SwitchOrganization rotates the refresh token like RefreshToken, issuing
+// the new tokens for the requested organization instead of the current one.
+func (s *accountServiceImpl) SwitchOrganization(ctx context.Context, req entity.SwitchOrganizationReq) (*entity.TokenData, error) {
+	return s.refreshToken(ctx, "[account_service][SwitchOrganization]", constant.AuthEventOrganizationSwitched, req.RefreshToken, &req.OrganizationId, nil)
+}
+
+// RefreshClientToken rotates a refresh token issued to the OAuth client of
+// the grant. The grant's scope bounds the scope the token was issued with.
+func (s *accountServiceImpl) RefreshClientToken(ctx context.Context, grant entity.ClientGrant, refreshToken string) (*entity.TokenData, error) {
+	return s.refreshToken(ctx, "[account_service][RefreshClientToken]", constant.AuthEventTokenRefresh, refreshToken, nil, &grant)
+}
+
+// refreshToken rotates the refresh token and issues tokens for the
+// organization it was issued for, or for switchTo when given, where 0 means
+// none. A membership that ended since is dropped from the tokens on a plain
+// refresh, but refused when switching to it. Tokens issued to an OAuth
+// client are only refreshed for that client, whose grant is given.
+func (s *accountServiceImpl) refreshToken(ctx context.Context, caller, eventType, token string, switchTo *int64, client *entity.ClientGrant) (*entity.TokenData, error) {
+	var (
+		tokenData  *entity.TokenData
+		reuseErr   error
+		failReason string
+		accountId  int64
+		deviceId   int64
+	)
+
+	err := s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		reuseErr = nil
+		failReason = constant.AuthEventReasonInternalError
+		accountId = 0
+		deviceId = 0
+
+		refreshToken, err := repos.RefreshToken.GetTokenByHash(ctx, s.tokenHasher.HashToken(token))
 		if err != nil {
-			return nil, apperror.InternalServerError(apperror.AppErrorOpt{
-				Message: fmt.Sprintf("[account_service][Login][refreshTokenRepo.DeleteTokenByAccountId] Error: %s | account_id: %v", err.Error(), account.Id),
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("%s[refreshTokenRepo.GetTokenByHash] Error: %s", caller, err.Error()),
+			})
+		}
+
+		if refreshToken == nil {
+			failReason = constant.AuthEventReasonInvalidToken
+
+			return apperror.UnauthorizedError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("%s refresh token not found", caller),
+				ResponseMessage: constant.MsgInvalidRefreshToken,
 			})
 		}
 
-		newDevice := entity.AccountDevice{
-			AccountId:  account.Id,
-			DeviceHash: accountDeviceHash,
-			UserAgent:  userAgent,
-			DeviceInfo: deviceInfo,
+		accountId = refreshToken.AccountId
+		deviceId = refreshToken.DeviceId
+
+		var grant *entity.ClientGrant
+
+		if refreshToken.ClientId != nil || client != nil {
+			if refreshToken.ClientId == nil || client == nil || *refreshToken.ClientId != client.ClientId {
+				failReason = constant.AuthEventReasonInvalidToken
+
+				return apperror.UnauthorizedError(apperror.AppErrorOpt{
+					Message:         fmt.Sprintf("%s refresh token issued to another client | account_id: %v", caller, refreshToken.AccountId),
+					ResponseMessage: constant.MsgInvalidRefreshToken,
+				})
+			}
+
+			grant = &entity.ClientGrant{
+				ClientId: client.ClientId,
+				Scope:    intersectScopes(strings.Fields(*refreshToken.Scope), client.Scope),
+			}
+		}
+
+		if refreshToken.ExpiredAt <= time.Now().UnixMilli() {
+			failReason = constant.AuthEventReasonTokenExpired
+
+			return apperror.UnauthorizedError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("%s refresh token expired | account_id: %v", caller, refreshToken.AccountId),
+				ResponseMessage: constant.MsgInvalidRefreshToken,
+			})
 		}
 
-		newDeviceId, err := accountDeviceRepo.InsertDevice(ctx, newDevice)
+		// A revoked token being presented again means it leaked or was
+		// replayed, so every token rotated from the same login is revoked
+		// with it. The revocation is committed before the error is returned.
+		revoked := false
+		if refreshToken.RevokedAt == nil {
+			revoked, err = repos.RefreshToken.RevokeToken(ctx, refreshToken.RefreshTokenId)
+			if err != nil {
+				return apperror.InternalServerError(apperror.AppErrorOpt{
+					Message: fmt.Sprintf("%s[refreshTokenRepo.RevokeToken] Error: %s | account_id: %v", caller, err.Error(), refreshToken.AccountId),
+				})
+			}
+		}
+
+		if !revoked {
+			err = repos.RefreshToken.DeleteTokenByFamilyId(ctx, refreshToken.FamilyId)
+			if err != nil {
+				return apperror.InternalServerError(apperror.AppErrorOpt{
+					Message: fmt.Sprintf("%s[refreshTokenRepo.DeleteTokenByFamilyId] Error: %s | account_id: %v", caller, err.Error(), refreshToken.AccountId),
+				})
+			}
+
+			reuseErr = apperror.UnauthorizedError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("%s refresh token reused | account_id: %v | family_id: %s", caller, refreshToken.AccountId, refreshToken.FamilyId),
+				ResponseMessage: constant.MsgInvalidRefreshToken,
+			})
+
+			return recordTx(ctx, s.auditRecorder, repos, caller, authEvent(eventType, refreshToken.AccountId, refreshToken.DeviceId, constant.AuthEventOutcomeFailure, constant.AuthEventReasonTokenReused))
+		}
+
+		account, err := repos.Account.GetAccountById(ctx, refreshToken.AccountId)
 		if err != nil {
-			return nil, apperror.InternalServerError(apperror.AppErrorOpt{
-				Message: fmt.Sprintf("[account_service][Login][accountDeviceRepo.InsertDevice] Error: %s | account_id: %v", err.Error(), account.Id),
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("%s[accountRepo.GetAccountById] Error: %s | account_id: %v", caller, err.Error(), refreshToken.AccountId),
 			})
 		}
 
-		accountDevice = &newDevice
-		accountDevice.DeviceId = newDeviceId
+		if account == nil {
+			failReason = constant.AuthEventReasonAccountNotFound
+
+			return apperror.UnauthorizedError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("%s account not found | account_id: %v", caller, refreshToken.AccountId),
+				ResponseMessage: constant.MsgInvalidRefreshToken,
+			})
+		}
+
+		if account.TenantId != requestTenant(ctx) {
+			failReason = constant.AuthEventReasonInvalidToken
+
+			return apperror.UnauthorizedError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("%s refresh token issued for another tenant | account_id: %v | tenant_id: %s", caller, account.Id, account.TenantId),
+				ResponseMessage: constant.MsgInvalidRefreshToken,
+			})
+		}
+
+		err = accountStatusError(caller, *account)
+		if err != nil {
+			failReason = constant.AuthEventReasonAccountInactive
+
+			return err
+		}
+
+		err = repos.AccountDevice.UpdateLastSeen(ctx, refreshToken.DeviceId, ctx.Value(constant.ClientIpCtxKey).(string))
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("%s[accountDeviceRepo.UpdateLastSeen] Error: %s | account_id: %v", caller, err.Error(), account.Id),
+			})
+		}
+
+		organizationId := refreshToken.OrganizationId
+		if switchTo != nil {
+			organizationId = nil
+			if *switchTo != 0 {
+				organizationId = switchTo
+			}
+		}
+
+		var member *entity.OrganizationMember
+
+		if organizationId != nil {
+			member, err = repos.Organization.GetMember(ctx, *organizationId, account.Id)
+			if err != nil {
+				return apperror.InternalServerError(apperror.AppErrorOpt{
+					Message: fmt.Sprintf("%s[organizationRepo.GetMember] Error: %s | account_id: %v | organization_id: %v", caller, err.Error(), account.Id, *organizationId),
+				})
+			}
+
+			if member == nil && switchTo != nil {
+				failReason = constant.AuthEventReasonNotMember
+
+				return apperror.NewAppError(apperror.AppErrorOpt{
+					Code:            http.StatusForbidden,
+					Message:         fmt.Sprintf("%s not an organization member | account_id: %v | organization_id: %v", caller, account.Id, *organizationId),
+					ResponseMessage: constant.MsgNotOrganizationMember,
+				})
+			}
+		}
+
+		data, newToken, err := s.issueTokens(ctx, repos.Role, *account, refreshToken.DeviceId, refreshToken.FamilyId, member, grant)
+		if err != nil {
+			return err
+		}
+
+		err = repos.RefreshToken.InsertToken(ctx, *newToken)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("%s[refreshTokenRepo.InsertToken] Error: %s | account_id: %v", caller, err.Error(), account.Id),
+			})
+		}
+
+		tokenData = data
+
+		reason := ""
+		if switchTo != nil {
+			reason = strconv.FormatInt(*switchTo, 10)
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, caller, authEvent(eventType, account.Id, refreshToken.DeviceId, constant.AuthEventOutcomeSuccess, reason))
+	})
+	if err != nil {
+		s.auditRecorder.Record(ctx, authEvent(eventType, accountId, deviceId, constant.AuthEventOutcomeFailure, failReason))
+
+		return nil, txError(caller, err)
 	}
 
-	customClaims := make(map[string]any)
-	customClaims["account_id"] = account.Id
-	customClaims["email"] = account.Email
-	customClaims["name"] = account.Name
+	if reuseErr != nil {
+		return nil, reuseErr
+	}
 
-	customClaimsBytes, err := json.Marshal(customClaims)
+	return tokenData, nil
+}
+
+// maxSessions is the number of concurrent sessions an account may hold, or
+// 0 for no limit.
+func (s *accountServiceImpl) maxSessions() int {
+	if s.singleSession {
+		return 1
+	}
+
+	return s.maxSessionsPerAccount
+}
+
+// issueTokens signs an access token for the account and mints a new opaque
+// refresh token in the given family, starting a new family when familyId is
+// empty. A non nil member scopes both to that organization, and a non nil
+// grant binds them to an OAuth client. The returned record must be stored
+// for the refresh token to be valid.
+func (s *accountServiceImpl) issueTokens(ctx context.Context, roleRepo repository.RoleRepository, account entity.Account, deviceId int64, familyId string, member *entity.OrganizationMember, grant *entity.ClientGrant) (*entity.TokenData, *entity.RefreshToken, error) {
+	grants, err := roleRepo.GetAccountGrants(ctx, account.Id)
 	if err != nil {
-		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
-			Message: fmt.Sprintf("[account_service][Login][json.Marshal] Error: %s | account_id: %v", err.Error(), account.Id),
+		return nil, nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[account_service][issueTokens][roleRepo.GetAccountGrants] Error: %s | account_id: %v", err.Error(), account.Id),
 		})
 	}
 
-	accessTokenExpiredAt := time.Now().Add(30 * time.Minute).UnixMilli()
+	scope := grants.Permissions
+	if grant != nil {
+		scope = intersectScopes(grant.Scope, grants.Permissions)
+	}
 
-	accessToken, err := s.jwt.CreateAndSign(customClaimsBytes, accessTokenExpiredAt)
+	customClaims := entity.AccessTokenClaims{
+		AccountId: account.Id,
+		DeviceId:  deviceId,
+		Email:     account.Email,
+		Name:      account.Name,
+		Roles:     grants.Roles,
+		Scope:     strings.Join(scope, " "),
+		Tenant:    account.TenantId,
+	}
+
+	if member != nil {
+		customClaims.OrgId = member.OrganizationId
+		customClaims.OrgRole = member.Role
+	}
+
+	if grant != nil {
+		customClaims.ClientId = grant.ClientId
+	}
+
+	settings := s.tenants.Settings(account.TenantId)
+
+	customClaimsBytes, err := json.Marshal(customClaims)
 	if err != nil {
-		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
-			Message: fmt.Sprintf("[account_service][Login][json.Marshal][Access] Error: %s | account_id: %v", err.Error(), account.Id),
+		return nil, nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[account_service][issueTokens][json.Marshal] Error: %s | account_id: %v", err.Error(), account.Id),
 		})
 	}
 
-	refreshTokenExpiredAt := time.Now().Add(24 * time.Hour).UnixMilli()
+	accessTokenExpiredAt := time.Now().Add(settings.AccessTokenDuration).UnixMilli()
 
-	refreshToken, err := s.jwt.CreateAndSign(customClaimsBytes, refreshTokenExpiredAt)
+	accessToken, err := s.jwt.CreateAndSign(customClaimsBytes, accessTokenExpiredAt)
 	if err != nil {
-		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
-			Message: fmt.Sprintf("[account_service][Login][json.Marshal][Refresh] Error: %s | account_id: %v", err.Error(), account.Id),
+		return nil, nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[account_service][issueTokens][jwt.CreateAndSign] Error: %s | account_id: %v", err.Error(), account.Id),
 		})
 	}
 
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), s.subRoutineTimeout)
-		defer cancel()
+	refreshToken, err := helper.GenerateOpaqueToken(refreshTokenBytes)
+	if err != nil {
+		return nil, nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[account_service][issueTokens][helper.GenerateOpaqueToken] refresh token | Error: %s | account_id: %v", err.Error(), account.Id),
+		})
+	}
 
-		err := s.refreshTokenRepo.InsertToken(ctx, refreshToken, account.Id, accountDevice.DeviceId, refreshTokenExpiredAt)
+	if familyId == "" {
+		familyId, err = helper.GenerateOpaqueToken(familyIdBytes)
 		if err != nil {
-			s.log.WithFields(logrus.Fields{
-				"error":      err.Error(),
-				"account_id": account.Id,
-			}).Error("[account_service][Login][refreshTokenRepo.InsertToken][sub-routine]")
+			return nil, nil, apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][issueTokens][helper.GenerateOpaqueToken] family id | Error: %s | account_id: %v", err.Error(), account.Id),
+			})
 		}
-	}()
+	}
+
+	refreshTokenExpiredAt := time.Now().Add(settings.RefreshTokenDuration).UnixMilli()
 
-	return &entity.TokenData{
+	tokenData := &entity.TokenData{
 		AccessToken: entity.Token{
 			Token:     accessToken,
 			ExpiredAt: accessTokenExpiredAt,
@@ -333,5 +1009,293 @@ func (s *accountServiceImpl) Login(ctx context.Context, req entity.LoginReq) (*e
 			Token:     refreshToken,
 			ExpiredAt: refreshTokenExpiredAt,
 		},
-	}, nil
+	}
+
+	newToken := &entity.RefreshToken{
+		TokenHash: s.tokenHasher.HashToken(refreshToken),
+		AccountId: account.Id,
+		DeviceId:  deviceId,
+		FamilyId:  familyId,
+		IpAddress: ctx.Value(constant.ClientIpCtxKey).(string),
+		ExpiredAt: refreshTokenExpiredAt,
+	}
+
+	if member != nil {
+		newToken.OrganizationId = &member.OrganizationId
+	}
+
+	// The granted scope is kept rather than the issued one, so permissions
+	// given to the account later reach the client on refresh.
+	if grant != nil {
+		grantedScope := strings.Join(grant.Scope, " ")
+
+		newToken.ClientId = &grant.ClientId
+		newToken.Scope = &grantedScope
+		tokenData.Scope = customClaims.Scope
+	}
+
+	return tokenData, newToken, nil
+}
+
+func (s *accountServiceImpl) GetProfile(ctx context.Context) (*entity.AccountProfileRes, error) {
+	accountId := ctx.Value(constant.AccountIdCtxKey).(int64)
+
+	account, err := s.accountRepo.GetAccountById(ctx, accountId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[account_service][GetProfile][accountRepo.GetAccountById] Error: %s | account_id: %v", err.Error(), accountId),
+		})
+	}
+
+	if account == nil {
+		return nil, apperror.NewAppError(apperror.AppErrorOpt{
+			Code:            http.StatusNotFound,
+			Message:         fmt.Sprintf("[account_service][GetProfile] account not found | account_id: %v", accountId),
+			ResponseMessage: constant.MsgAccountNotFound,
+		})
+	}
+
+	return accountProfile(*account), nil
+}
+
+// UpdateProfile renames the account. The name is checked for uniqueness
+// under the same table lock Register takes.
+func (s *accountServiceImpl) UpdateProfile(ctx context.Context, req entity.UpdateProfileReq) (*entity.AccountProfileRes, error) {
+	accountId := ctx.Value(constant.AccountIdCtxKey).(int64)
+
+	var profile *entity.AccountProfileRes
+
+	err := s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		err := repos.Account.Lock(ctx)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][UpdateProfile][accountRepo.Lock] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		account, err := repos.Account.GetAccountById(ctx, accountId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][UpdateProfile][accountRepo.GetAccountById] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		if account == nil {
+			return apperror.NewAppError(apperror.AppErrorOpt{
+				Code:            http.StatusNotFound,
+				Message:         fmt.Sprintf("[account_service][UpdateProfile] account not found | account_id: %v", accountId),
+				ResponseMessage: constant.MsgAccountNotFound,
+			})
+		}
+
+		existing, err := repos.Account.GetAccountByName(ctx, account.TenantId, req.Name)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][UpdateProfile][accountRepo.GetAccountByName] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+		if existing != nil && existing.Id != accountId {
+			return apperror.BadRequestError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("[account_service][UpdateProfile] name already registered | account_id: %v", accountId),
+				ResponseMessage: "name already registered",
+			})
+		}
+
+		updated := *account
+		updated.Name = req.Name
+
+		err = repos.Account.UpdateAccount(ctx, updated)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][UpdateProfile][accountRepo.UpdateAccount] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		updated.UpdatedAt = time.Now().UnixMilli()
+		profile = accountProfile(updated)
+
+		return recordTx(ctx, s.auditRecorder, repos, "[account_service][UpdateProfile]", authEvent(constant.AuthEventProfileUpdated, accountId, 0, constant.AuthEventOutcomeSuccess, ""))
+	})
+	if err != nil {
+		return nil, txError("[account_service][UpdateProfile]", err)
+	}
+
+	return profile, nil
+}
+
+// DeleteAccount soft deletes the account after checking its password again,
+// and revokes all of its sessions and devices. Logging in within the
+// deletion grace period restores it; after that the purger erases its
+// personal data.
+func (s *accountServiceImpl) DeleteAccount(ctx context.Context, req entity.DeleteAccountReq) error {
+	accountId := ctx.Value(constant.AccountIdCtxKey).(int64)
+	deviceId := ctx.Value(constant.DeviceIdCtxKey).(int64)
+
+	var failReason string
+
+	err := s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		failReason = constant.AuthEventReasonInternalError
+
+		account, err := repos.Account.GetAccountById(ctx, accountId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][DeleteAccount][accountRepo.GetAccountById] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		if account == nil {
+			failReason = constant.AuthEventReasonAccountNotFound
+
+			return apperror.NewAppError(apperror.AppErrorOpt{
+				Code:            http.StatusNotFound,
+				Message:         fmt.Sprintf("[account_service][DeleteAccount] account not found | account_id: %v", accountId),
+				ResponseMessage: constant.MsgAccountNotFound,
+			})
+		}
+
+		isValid, err := s.hash.Check(req.Password, []byte(account.Password))
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][DeleteAccount][hash.Check] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		if !isValid {
+			failReason = constant.AuthEventReasonInvalidCredentials
+
+			return apperror.UnauthorizedError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("[account_service][DeleteAccount] invalid password | account_id: %v", accountId),
+				ResponseMessage: constant.MsgInvalidPassword,
+			})
+		}
+
+		err = repos.Account.SoftDeleteAccount(ctx, accountId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][DeleteAccount][accountRepo.SoftDeleteAccount] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		err = repos.RefreshToken.DeleteTokenByAccountId(ctx, accountId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][DeleteAccount][refreshTokenRepo.DeleteTokenByAccountId] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		err = repos.AccountDevice.SoftDeleteDevicesByAccountId(ctx, accountId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][DeleteAccount][accountDeviceRepo.SoftDeleteDevicesByAccountId] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[account_service][DeleteAccount]", authEvent(constant.AuthEventAccountDeleted, accountId, deviceId, constant.AuthEventOutcomeSuccess, constant.AuthEventReasonUserRequest))
+	})
+	if err != nil {
+		s.auditRecorder.Record(ctx, authEvent(constant.AuthEventAccountDeleted, accountId, deviceId, constant.AuthEventOutcomeFailure, failReason))
+
+		return txError("[account_service][DeleteAccount]", err)
+	}
+
+	return nil
+}
+
+// ResetPassword completes a password reset forced by an admin with the
+// token that was sent to the account, and signs out every session.
+func (s *accountServiceImpl) ResetPassword(ctx context.Context, req entity.ResetPasswordReq) error {
+	var (
+		accountId  int64
+		failReason string
+	)
+
+	err := s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		accountId = 0
+		failReason = constant.AuthEventReasonInternalError
+
+		account, err := repos.Account.GetAccountByPasswordResetTokenHash(ctx, s.tokenHasher.HashToken(req.Token))
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][ResetPassword][accountRepo.GetAccountByPasswordResetTokenHash] Error: %s", err.Error()),
+			})
+		}
+
+		if account == nil || !account.PasswordResetRequired {
+			failReason = constant.AuthEventReasonInvalidToken
+
+			return apperror.BadRequestError(apperror.AppErrorOpt{
+				Message:         "[account_service][ResetPassword] reset token not found",
+				ResponseMessage: constant.MsgInvalidResetToken,
+			})
+		}
+
+		accountId = account.Id
+
+		if account.PasswordResetExpiredAt == nil || *account.PasswordResetExpiredAt <= time.Now().UnixMilli() {
+			failReason = constant.AuthEventReasonTokenExpired
+
+			return apperror.BadRequestError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("[account_service][ResetPassword] reset token expired | account_id: %v", account.Id),
+				ResponseMessage: constant.MsgInvalidResetToken,
+			})
+		}
+
+		// The password policy is the one of the account's tenant.
+		if !s.tenants.Settings(account.TenantId).PasswordPolicy.Validate(req.Password) {
+			failReason = constant.AuthEventReasonWeakPassword
+
+			return apperror.BadRequestError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("[account_service][ResetPassword] %s | account_id: %v", constant.MsgInvalidPassword, account.Id),
+				ResponseMessage: constant.MsgInvalidPassword,
+			})
+		}
+
+		hash, err := s.hash.Hash(req.Password)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][ResetPassword][hash.Hash] passwordHash | Error: %s | account_id: %v", err.Error(), account.Id),
+			})
+		}
+
+		err = repos.Account.ResetPassword(ctx, account.Id, hash)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][ResetPassword][accountRepo.ResetPassword] Error: %s | account_id: %v", err.Error(), account.Id),
+			})
+		}
+
+		err = repos.RefreshToken.DeleteTokenByAccountId(ctx, account.Id)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_service][ResetPassword][refreshTokenRepo.DeleteTokenByAccountId] Error: %s | account_id: %v", err.Error(), account.Id),
+			})
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[account_service][ResetPassword]", authEvent(constant.AuthEventPasswordReset, account.Id, 0, constant.AuthEventOutcomeSuccess, constant.AuthEventReasonConfirmed))
+	})
+	if err != nil {
+		s.auditRecorder.Record(ctx, authEvent(constant.AuthEventPasswordReset, accountId, 0, constant.AuthEventOutcomeFailure, failReason))
+
+		return txError("[account_service][ResetPassword]", err)
+	}
+
+	return nil
+}
+
+// deletionGraceEnded reports whether an account deleted at deletedAt can no
+// longer be restored.
+func (s *accountServiceImpl) deletionGraceEnded(deletedAt int64) bool {
+	return time.UnixMilli(deletedAt).Add(s.deletionGracePeriod).Before(time.Now())
+}
+
+func accountProfile(account entity.Account) *entity.AccountProfileRes {
+	return &entity.AccountProfileRes{
+		Id:          account.Id,
+		Name:        account.Name,
+		Email:       account.Email,
+		PhoneNumber: account.PhoneNumber,
+		Status:      effectiveAccountStatus(account),
+		CreatedAt:   account.CreatedAt,
+		UpdatedAt:   account.UpdatedAt,
+	}
 }
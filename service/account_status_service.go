@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/michaelyusak/go-auth/audit"
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/repository"
+	"github.com/michaelyusak/go-helper/apperror"
+)
+
+// accountStatusTransitions lists the statuses each status can move to.
+// Staying in the same status, e.g. to change the reason, is always allowed.
+var accountStatusTransitions = map[string][]string{
+	constant.AccountStatusPendingVerification: {constant.AccountStatusActive, constant.AccountStatusDisabled},
+	constant.AccountStatusActive:              {constant.AccountStatusLocked, constant.AccountStatusSuspended, constant.AccountStatusDisabled},
+	constant.AccountStatusLocked:              {constant.AccountStatusActive, constant.AccountStatusSuspended, constant.AccountStatusDisabled},
+	constant.AccountStatusSuspended:           {constant.AccountStatusActive, constant.AccountStatusDisabled},
+	constant.AccountStatusDisabled:            {constant.AccountStatusActive},
+}
+
+// accountStatusDenials is how each status other than active is refused.
+var accountStatusDenials = map[string]struct {
+	code    int
+	message string
+}{
+	constant.AccountStatusPendingVerification: {http.StatusForbidden, constant.MsgAccountPending},
+	constant.AccountStatusLocked:              {http.StatusLocked, constant.MsgAccountLocked},
+	constant.AccountStatusSuspended:           {http.StatusForbidden, constant.MsgAccountSuspended},
+	constant.AccountStatusDisabled:            {http.StatusForbidden, constant.MsgAccountDisabled},
+}
+
+type accountStatusServiceImpl struct {
+	accountRepo   repository.AccountRepository
+	transaction   repository.Transaction
+	auditRecorder audit.Recorder
+}
+
+type AccountStatusServiceOpt struct {
+	AccountRepo   repository.AccountRepository
+	Transaction   repository.Transaction
+	AuditRecorder audit.Recorder
+}
+
+func NewAccountStatusService(opt AccountStatusServiceOpt) *accountStatusServiceImpl {
+	return &accountStatusServiceImpl{
+		accountRepo:   opt.AccountRepo,
+		transaction:   opt.Transaction,
+		auditRecorder: opt.AuditRecorder,
+	}
+}
+
+// CheckAccountStatus fails unless the account exists, is active and has no
+// forced password reset pending. It runs on every authenticated request, so
+// a suspension or forced reset also shuts out access tokens that were
+// issued before it.
+func (s *accountStatusServiceImpl) CheckAccountStatus(ctx context.Context, accountId int64) error {
+	account, err := s.accountRepo.GetAccountById(ctx, accountId)
+	if err != nil {
+		return apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[account_status_service][CheckAccountStatus][accountRepo.GetAccountById] Error: %s | account_id: %v", err.Error(), accountId),
+		})
+	}
+
+	if account == nil {
+		return apperror.UnauthorizedError(apperror.AppErrorOpt{
+			Message:         fmt.Sprintf("[account_status_service][CheckAccountStatus] account not found | account_id: %v", accountId),
+			ResponseMessage: constant.MsgUnauthorized,
+		})
+	}
+
+	err = accountStatusError("[account_status_service][CheckAccountStatus]", *account)
+	if err != nil {
+		return err
+	}
+
+	return passwordResetError("[account_status_service][CheckAccountStatus]", *account)
+}
+
+// UpdateAccountStatus moves an account to another status. Suspending or
+// disabling it revokes all of its sessions.
+func (s *accountStatusServiceImpl) UpdateAccountStatus(ctx context.Context, accountId int64, req entity.UpdateAccountStatusReq) (*entity.AccountStatusRes, error) {
+	if req.Until != nil && (!slices.Contains([]string{constant.AccountStatusLocked, constant.AccountStatusSuspended}, req.Status) || *req.Until <= time.Now().UnixMilli()) {
+		return nil, apperror.BadRequestError(apperror.AppErrorOpt{
+			Message:         fmt.Sprintf("[account_status_service][UpdateAccountStatus] until must be in the future and only set for locked or suspended | account_id: %v", accountId),
+			ResponseMessage: constant.MsgInvalidStatusChange,
+		})
+	}
+
+	var res *entity.AccountStatusRes
+
+	err := s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		account, err := repos.Account.GetAccountById(ctx, accountId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_status_service][UpdateAccountStatus][accountRepo.GetAccountById] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		if !inRequestTenant(ctx, account) {
+			return apperror.NewAppError(apperror.AppErrorOpt{
+				Code:            http.StatusNotFound,
+				Message:         fmt.Sprintf("[account_status_service][UpdateAccountStatus] account not found | account_id: %v", accountId),
+				ResponseMessage: constant.MsgAccountNotFound,
+			})
+		}
+
+		fromStatus := effectiveAccountStatus(*account)
+
+		if req.Status != fromStatus && !slices.Contains(accountStatusTransitions[fromStatus], req.Status) {
+			return apperror.BadRequestError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("[account_status_service][UpdateAccountStatus] invalid transition | account_id: %v | from: %s | to: %s", accountId, fromStatus, req.Status),
+				ResponseMessage: constant.MsgInvalidStatusChange,
+			})
+		}
+
+		err = repos.Account.UpdateStatus(ctx, accountId, req.Status, req.Reason, req.Until)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[account_status_service][UpdateAccountStatus][accountRepo.UpdateStatus] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		if req.Status == constant.AccountStatusSuspended || req.Status == constant.AccountStatusDisabled {
+			err = repos.RefreshToken.DeleteTokenByAccountId(ctx, accountId)
+			if err != nil {
+				return apperror.InternalServerError(apperror.AppErrorOpt{
+					Message: fmt.Sprintf("[account_status_service][UpdateAccountStatus][refreshTokenRepo.DeleteTokenByAccountId] Error: %s | account_id: %v", err.Error(), accountId),
+				})
+			}
+		}
+
+		res = &entity.AccountStatusRes{
+			AccountId:    accountId,
+			Status:       req.Status,
+			StatusReason: req.Reason,
+			StatusUntil:  req.Until,
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[account_status_service][UpdateAccountStatus]", authEvent(constant.AuthEventAccountStatus, accountId, 0, constant.AuthEventOutcomeSuccess, req.Status))
+	})
+	if err != nil {
+		return nil, txError("[account_status_service][UpdateAccountStatus]", err)
+	}
+
+	return res, nil
+}
+
+// effectiveAccountStatus is the account's status, taking into account that
+// a lock or suspension with an until time lifts once it has passed.
+func effectiveAccountStatus(account entity.Account) string {
+	switch account.Status {
+	case constant.AccountStatusLocked, constant.AccountStatusSuspended:
+		if account.StatusUntil != nil && *account.StatusUntil <= time.Now().UnixMilli() {
+			return constant.AccountStatusActive
+		}
+	}
+
+	return account.Status
+}
+
+// accountStatusError refuses accounts that are not active, with a distinct
+// status code and message for each status.
+func accountStatusError(caller string, account entity.Account) error {
+	status := effectiveAccountStatus(account)
+	if status == constant.AccountStatusActive {
+		return nil
+	}
+
+	denial, ok := accountStatusDenials[status]
+	if !ok {
+		denial = accountStatusDenials[constant.AccountStatusDisabled]
+	}
+
+	return apperror.NewAppError(apperror.AppErrorOpt{
+		Code:            denial.code,
+		Message:         fmt.Sprintf("%s account %s | account_id: %v | reason: %s", caller, status, account.Id, account.StatusReason),
+		ResponseMessage: denial.message,
+	})
+}
+
+// passwordResetError refuses accounts an admin forced to reset their
+// password until they have.
+func passwordResetError(caller string, account entity.Account) error {
+	if !account.PasswordResetRequired {
+		return nil
+	}
+
+	return apperror.NewAppError(apperror.AppErrorOpt{
+		Code:            http.StatusForbidden,
+		Message:         fmt.Sprintf("%s password reset required | account_id: %v", caller, account.Id),
+		ResponseMessage: constant.MsgPasswordResetRequired,
+	})
+}
@@ -0,0 +1,346 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/michaelyusak/go-auth/audit"
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/helper"
+	"github.com/michaelyusak/go-auth/notifier"
+	"github.com/michaelyusak/go-auth/repository"
+	"github.com/michaelyusak/go-helper/apperror"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	passwordResetTokenBytes = 32
+	defaultAccountPageSize  = 20
+)
+
+// adminAccountServiceImpl serves the support tools. The acting admin is put
+// into the request context by the permission middleware, so every audit event
+// recorded here, and by the status service it delegates to, carries it.
+type adminAccountServiceImpl struct {
+	accountRepo          repository.AccountRepository
+	accountDeviceRepo    repository.AccountDeviceRepository
+	roleRepo             repository.RoleRepository
+	transaction          repository.Transaction
+	tokenHasher          helper.TokenHasher
+	accountStatusService AccountStatusService
+	notifier             notifier.PasswordResetNotifier
+	log                  *logrus.Logger
+	subRoutineTimeout    time.Duration
+	resetTokenTtl        time.Duration
+	resetUrl             string
+	auditRecorder        audit.Recorder
+}
+
+type AdminAccountServiceOpt struct {
+	AccountRepo          repository.AccountRepository
+	AccountDeviceRepo    repository.AccountDeviceRepository
+	RoleRepo             repository.RoleRepository
+	Transaction          repository.Transaction
+	TokenHasher          helper.TokenHasher
+	AccountStatusService AccountStatusService
+	Notifier             notifier.PasswordResetNotifier
+	Log                  *logrus.Logger
+	SubRoutineTimeout    time.Duration
+	ResetTokenTtl        time.Duration
+	ResetUrl             string
+	AuditRecorder        audit.Recorder
+}
+
+func NewAdminAccountService(opt AdminAccountServiceOpt) *adminAccountServiceImpl {
+	return &adminAccountServiceImpl{
+		accountRepo:          opt.AccountRepo,
+		accountDeviceRepo:    opt.AccountDeviceRepo,
+		roleRepo:             opt.RoleRepo,
+		transaction:          opt.Transaction,
+		tokenHasher:          opt.TokenHasher,
+		accountStatusService: opt.AccountStatusService,
+		notifier:             opt.Notifier,
+		log:                  opt.Log,
+		subRoutineTimeout:    opt.SubRoutineTimeout,
+		resetTokenTtl:        opt.ResetTokenTtl,
+		resetUrl:             opt.ResetUrl,
+		auditRecorder:        opt.AuditRecorder,
+	}
+}
+
+// SearchAccounts fetches one page past the cursor. One extra account is
+// read to tell whether there is a next page.
+func (s *adminAccountServiceImpl) SearchAccounts(ctx context.Context, query entity.AdminAccountQuery) (*entity.AdminAccountPage, error) {
+	filter := entity.AccountFilter{
+		TenantId:          requestTenant(ctx),
+		EmailPrefix:       query.Email,
+		PhoneNumberPrefix: query.PhoneNumber,
+		NamePrefix:        query.Name,
+	}
+
+	if query.Cursor != "" {
+		before, err := strconv.ParseInt(query.Cursor, 10, 64)
+		if err != nil || before <= 0 {
+			return nil, apperror.BadRequestError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("[admin_account_service][SearchAccounts] invalid cursor | cursor: %s", query.Cursor),
+				ResponseMessage: constant.MsgInvalidCursor,
+			})
+		}
+
+		filter.Before = before
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultAccountPageSize
+	}
+
+	filter.Limit = limit + 1
+
+	accounts, err := s.accountRepo.SearchAccounts(ctx, filter)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[admin_account_service][SearchAccounts][accountRepo.SearchAccounts] Error: %s", err.Error()),
+		})
+	}
+
+	page := &entity.AdminAccountPage{
+		Accounts: make([]entity.AdminAccountRes, 0, min(len(accounts), limit)),
+	}
+
+	if len(accounts) > limit {
+		accounts = accounts[:limit]
+		page.NextCursor = strconv.FormatInt(accounts[limit-1].Id, 10)
+	}
+
+	for _, account := range accounts {
+		page.Accounts = append(page.Accounts, adminAccountRes(account))
+	}
+
+	return page, nil
+}
+
+func (s *adminAccountServiceImpl) GetAccount(ctx context.Context, accountId int64) (*entity.AdminAccountDetailRes, error) {
+	account, err := s.getAccount(ctx, "[admin_account_service][GetAccount]", accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	accountDevices, err := s.accountDeviceRepo.GetDevicesByAccountId(ctx, accountId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[admin_account_service][GetAccount][accountDeviceRepo.GetDevicesByAccountId] Error: %s | account_id: %v", err.Error(), accountId),
+		})
+	}
+
+	grants, err := s.roleRepo.GetAccountGrants(ctx, accountId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[admin_account_service][GetAccount][roleRepo.GetAccountGrants] Error: %s | account_id: %v", err.Error(), accountId),
+		})
+	}
+
+	res := &entity.AdminAccountDetailRes{
+		Account: adminAccountRes(*account),
+		Roles:   grants.Roles,
+		Devices: make([]entity.AccountDeviceRes, 0, len(accountDevices)),
+	}
+
+	for _, accountDevice := range accountDevices {
+		res.Devices = append(res.Devices, accountDeviceRes(accountDevice, 0))
+	}
+
+	return res, nil
+}
+
+func (s *adminAccountServiceImpl) SuspendAccount(ctx context.Context, accountId int64, req entity.SuspendAccountReq) (*entity.AccountStatusRes, error) {
+	return s.accountStatusService.UpdateAccountStatus(ctx, accountId, entity.UpdateAccountStatusReq{
+		Status: constant.AccountStatusSuspended,
+		Reason: req.Reason,
+		Until:  req.Until,
+	})
+}
+
+func (s *adminAccountServiceImpl) UnsuspendAccount(ctx context.Context, accountId int64) (*entity.AccountStatusRes, error) {
+	return s.reactivate(ctx, "[admin_account_service][UnsuspendAccount]", accountId, constant.AccountStatusSuspended, constant.MsgAccountNotSuspended)
+}
+
+func (s *adminAccountServiceImpl) UnlockAccount(ctx context.Context, accountId int64) (*entity.AccountStatusRes, error) {
+	return s.reactivate(ctx, "[admin_account_service][UnlockAccount]", accountId, constant.AccountStatusLocked, constant.MsgAccountNotLocked)
+}
+
+// reactivate makes the account active again, provided it currently has the
+// given status, so unlocking cannot lift a suspension by accident.
+func (s *adminAccountServiceImpl) reactivate(ctx context.Context, caller string, accountId int64, status, notInStatusMessage string) (*entity.AccountStatusRes, error) {
+	account, err := s.getAccount(ctx, caller, accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	if effectiveAccountStatus(*account) != status {
+		return nil, apperror.BadRequestError(apperror.AppErrorOpt{
+			Message:         fmt.Sprintf("%s account is not %s | account_id: %v | status: %s", caller, status, accountId, effectiveAccountStatus(*account)),
+			ResponseMessage: notInStatusMessage,
+		})
+	}
+
+	return s.accountStatusService.UpdateAccountStatus(ctx, accountId, entity.UpdateAccountStatusReq{
+		Status: constant.AccountStatusActive,
+	})
+}
+
+// RevokeSessions signs the account out of every session. Access tokens
+// already issued stay valid until they expire.
+func (s *adminAccountServiceImpl) RevokeSessions(ctx context.Context, accountId int64) (*entity.RevokeSessionsRes, error) {
+	_, err := s.getAccount(ctx, "[admin_account_service][RevokeSessions]", accountId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		err := repos.RefreshToken.DeleteTokenByAccountId(ctx, accountId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[admin_account_service][RevokeSessions][refreshTokenRepo.DeleteTokenByAccountId] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[admin_account_service][RevokeSessions]", authEvent(constant.AuthEventSessionRevoked, accountId, 0, constant.AuthEventOutcomeSuccess, constant.AuthEventReasonAdminAction))
+	})
+	if err != nil {
+		return nil, txError("[admin_account_service][RevokeSessions]", err)
+	}
+
+	return &entity.RevokeSessionsRes{
+		AccountId: accountId,
+	}, nil
+}
+
+// ForcePasswordReset shuts the account out until a new password is set with
+// the link sent to its email. Its sessions are revoked and, as the status
+// check refuses it on every request, so are its access tokens. Forcing it
+// again replaces the earlier link.
+func (s *adminAccountServiceImpl) ForcePasswordReset(ctx context.Context, accountId int64) (*entity.ForcePasswordResetRes, error) {
+	resetToken, err := helper.GenerateOpaqueToken(passwordResetTokenBytes)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[admin_account_service][ForcePasswordReset][helper.GenerateOpaqueToken] Error: %s | account_id: %v", err.Error(), accountId),
+		})
+	}
+
+	var (
+		event     entity.PasswordResetEvent
+		expiredAt int64
+	)
+
+	err = s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		account, err := repos.Account.GetAccountById(ctx, accountId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[admin_account_service][ForcePasswordReset][accountRepo.GetAccountById] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		if !inRequestTenant(ctx, account) {
+			return apperror.NewAppError(apperror.AppErrorOpt{
+				Code:            http.StatusNotFound,
+				Message:         fmt.Sprintf("[admin_account_service][ForcePasswordReset] account not found | account_id: %v", accountId),
+				ResponseMessage: constant.MsgAccountNotFound,
+			})
+		}
+
+		expiredAt = time.Now().Add(s.resetTokenTtl).UnixMilli()
+
+		err = repos.Account.RequirePasswordReset(ctx, accountId, s.tokenHasher.HashToken(resetToken), expiredAt)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[admin_account_service][ForcePasswordReset][accountRepo.RequirePasswordReset] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		err = repos.RefreshToken.DeleteTokenByAccountId(ctx, accountId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[admin_account_service][ForcePasswordReset][refreshTokenRepo.DeleteTokenByAccountId] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		event = entity.PasswordResetEvent{
+			AccountId: accountId,
+			Name:      account.Name,
+			Email:     account.Email,
+			ResetUrl:  fmt.Sprintf("%s?token=%s", s.resetUrl, url.QueryEscape(resetToken)),
+			ExpiredAt: expiredAt,
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[admin_account_service][ForcePasswordReset]", authEvent(constant.AuthEventPasswordReset, accountId, 0, constant.AuthEventOutcomeSuccess, constant.AuthEventReasonAdminAction))
+	})
+	if err != nil {
+		return nil, txError("[admin_account_service][ForcePasswordReset]", err)
+	}
+
+	s.notifyPasswordReset(event)
+
+	return &entity.ForcePasswordResetRes{
+		AccountId: accountId,
+		ExpiredAt: expiredAt,
+	}, nil
+}
+
+// notifyPasswordReset sends the reset link in the background; a failed
+// notification does not fail the request, and the reset can be forced
+// again.
+func (s *adminAccountServiceImpl) notifyPasswordReset(event entity.PasswordResetEvent) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), s.subRoutineTimeout)
+		defer cancel()
+
+		err := s.notifier.NotifyPasswordReset(ctx, event)
+		if err != nil {
+			s.log.WithFields(logrus.Fields{
+				"error":      err.Error(),
+				"account_id": event.AccountId,
+			}).Error("[admin_account_service][notifyPasswordReset][notifier.NotifyPasswordReset][sub-routine]")
+		}
+	}()
+}
+
+func (s *adminAccountServiceImpl) getAccount(ctx context.Context, caller string, accountId int64) (*entity.Account, error) {
+	account, err := s.accountRepo.GetAccountById(ctx, accountId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("%s[accountRepo.GetAccountById] Error: %s | account_id: %v", caller, err.Error(), accountId),
+		})
+	}
+
+	if !inRequestTenant(ctx, account) {
+		return nil, apperror.NewAppError(apperror.AppErrorOpt{
+			Code:            http.StatusNotFound,
+			Message:         fmt.Sprintf("%s account not found | account_id: %v", caller, accountId),
+			ResponseMessage: constant.MsgAccountNotFound,
+		})
+	}
+
+	return account, nil
+}
+
+func adminAccountRes(account entity.Account) entity.AdminAccountRes {
+	return entity.AdminAccountRes{
+		Id:                    account.Id,
+		TenantId:              account.TenantId,
+		Name:                  account.Name,
+		Email:                 account.Email,
+		PhoneNumber:           account.PhoneNumber,
+		Status:                effectiveAccountStatus(account),
+		StatusReason:          account.StatusReason,
+		StatusUntil:           account.StatusUntil,
+		PasswordResetRequired: account.PasswordResetRequired,
+		CreatedAt:             account.CreatedAt,
+		UpdatedAt:             account.UpdatedAt,
+	}
+}
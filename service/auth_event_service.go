@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/repository"
+	"github.com/michaelyusak/go-helper/apperror"
+)
+
+type authEventServiceImpl struct {
+	authEventRepo repository.AuthEventRepository
+}
+
+type AuthEventServiceOpt struct {
+	AuthEventRepo repository.AuthEventRepository
+}
+
+func NewAuthEventService(opt AuthEventServiceOpt) *authEventServiceImpl {
+	return &authEventServiceImpl{
+		authEventRepo: opt.AuthEventRepo,
+	}
+}
+
+// GetActivity lists the signed in account's own auth events, newest first.
+func (s *authEventServiceImpl) GetActivity(ctx context.Context, query entity.AuthEventQuery) (*entity.AuthEventPage, error) {
+	accountId := ctx.Value(constant.AccountIdCtxKey).(int64)
+
+	return s.getEvents(ctx, "[auth_event_service][GetActivity]", query, entity.AuthEventFilter{
+		AccountId: &accountId,
+	})
+}
+
+// SearchEvents searches the events of the request's tenant. Events of no
+// known account, e.g. logins with an unknown email, belong to no tenant and
+// are left out.
+func (s *authEventServiceImpl) SearchEvents(ctx context.Context, query entity.AdminAuthEventQuery) (*entity.AuthEventPage, error) {
+	return s.getEvents(ctx, "[auth_event_service][SearchEvents]", query.AuthEventQuery, entity.AuthEventFilter{
+		TenantId:       requestTenant(ctx),
+		AccountId:      query.AccountId,
+		ActorAccountId: query.ActorAccountId,
+		EventType:      query.EventType,
+		Outcome:        query.Outcome,
+		IpAddress:      query.IpAddress,
+		From:           query.From,
+		To:             query.To,
+	})
+}
+
+// getEvents fetches one page past the cursor. One extra event is read to
+// tell whether there is a next page.
+func (s *authEventServiceImpl) getEvents(ctx context.Context, caller string, query entity.AuthEventQuery, filter entity.AuthEventFilter) (*entity.AuthEventPage, error) {
+	if query.Cursor != "" {
+		before, err := strconv.ParseInt(query.Cursor, 10, 64)
+		if err != nil || before <= 0 {
+			return nil, apperror.BadRequestError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("%s invalid cursor | cursor: %s", caller, query.Cursor),
+				ResponseMessage: constant.MsgInvalidCursor,
+			})
+		}
+
+		filter.Before = before
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = constant.DefaultAuthEventPageSize
+	}
+
+	filter.Limit = limit + 1
+
+	events, err := s.authEventRepo.GetEvents(ctx, filter)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("%s[authEventRepo.GetEvents] Error: %s", caller, err.Error()),
+		})
+	}
+
+	page := &entity.AuthEventPage{
+		Events: make([]entity.AuthEventRes, 0, min(len(events), limit)),
+	}
+
+	if len(events) > limit {
+		events = events[:limit]
+		page.NextCursor = strconv.FormatInt(events[limit-1].AuthEventId, 10)
+	}
+
+	for _, event := range events {
+		page.Events = append(page.Events, entity.AuthEventRes{
+			AuthEventId:    event.AuthEventId,
+			EventType:      event.EventType,
+			AccountId:      event.AccountId,
+			DeviceId:       event.DeviceId,
+			ActorAccountId: event.ActorAccountId,
+			IpAddress:      event.IpAddress,
+			UserAgent:      event.UserAgent,
+			RequestId:      event.RequestId,
+			Outcome:        event.Outcome,
+			Reason:         event.Reason,
+			CreatedAt:      event.CreatedAt,
+		})
+	}
+
+	return page, nil
+}
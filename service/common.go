@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/michaelyusak/go-auth/audit"
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/repository"
+	"github.com/michaelyusak/go-helper/apperror"
+)
+
+// txError turns a begin or commit failure from Transaction.WithinTx into an
+// internal server error. Errors returned by the callback pass through as is.
+func txError(caller string, err error) error {
+	if errors.Is(err, repository.ErrTransaction) {
+		return apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("%s[transaction.WithinTx] Error: %s", caller, err.Error()),
+		})
+	}
+
+	return err
+}
+
+// authEvent builds an audit event. A zero account or device id is recorded
+// as unknown.
+func authEvent(eventType string, accountId, deviceId int64, outcome, reason string) entity.AuthEvent {
+	event := entity.AuthEvent{
+		EventType: eventType,
+		Outcome:   outcome,
+		Reason:    reason,
+	}
+
+	if accountId != 0 {
+		event.AccountId = &accountId
+	}
+	if deviceId != 0 {
+		event.DeviceId = &deviceId
+	}
+
+	return event
+}
+
+// recordTx records the event in the transaction of the change it records,
+// so a change is never committed without its event.
+func recordTx(ctx context.Context, recorder audit.Recorder, repos repository.TxRepositories, caller string, event entity.AuthEvent) error {
+	err := recorder.RecordTx(ctx, repos, event)
+	if err != nil {
+		return apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("%s[auditRecorder.RecordTx] Error: %s", caller, err.Error()),
+		})
+	}
+
+	return nil
+}
+
+// requestTenant is the tenant the request is made for, the default tenant
+// when none was resolved.
+func requestTenant(ctx context.Context) string {
+	tenantId, _ := ctx.Value(constant.TenantCtxKey).(string)
+	if tenantId == "" {
+		return constant.DefaultTenantId
+	}
+
+	return tenantId
+}
+
+// inRequestTenant tells whether the account exists in the tenant the
+// request is made for. Admin routes treat accounts of other tenants as not
+// found.
+func inRequestTenant(ctx context.Context, account *entity.Account) bool {
+	return account != nil && account.TenantId == requestTenant(ctx)
+}
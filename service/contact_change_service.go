@@ -0,0 +1,434 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/michaelyusak/go-auth/audit"
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/helper"
+	"github.com/michaelyusak/go-auth/notifier"
+	"github.com/michaelyusak/go-auth/repository"
+	"github.com/michaelyusak/go-helper/apperror"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	contactChangeCodeDigits       = 6
+	contactChangeCancelTokenBytes = 32
+)
+
+type contactChangeServiceImpl struct {
+	contactChangeRepo repository.AccountContactChangeRepository
+	transaction       repository.Transaction
+	tokenHasher       helper.TokenHasher
+	notifier          notifier.ContactChangeNotifier
+	log               *logrus.Logger
+	subRoutineTimeout time.Duration
+	codeTtl           time.Duration
+	maxAttempts       int
+	cancelUrl         string
+	auditRecorder     audit.Recorder
+}
+
+type ContactChangeServiceOpt struct {
+	ContactChangeRepo repository.AccountContactChangeRepository
+	Transaction       repository.Transaction
+	TokenHasher       helper.TokenHasher
+	Notifier          notifier.ContactChangeNotifier
+	Log               *logrus.Logger
+	SubRoutineTimeout time.Duration
+	CodeTtl           time.Duration
+	MaxAttempts       int
+	CancelUrl         string
+	AuditRecorder     audit.Recorder
+}
+
+func NewContactChangeService(opt ContactChangeServiceOpt) *contactChangeServiceImpl {
+	return &contactChangeServiceImpl{
+		contactChangeRepo: opt.ContactChangeRepo,
+		transaction:       opt.Transaction,
+		tokenHasher:       opt.TokenHasher,
+		notifier:          opt.Notifier,
+		log:               opt.Log,
+		subRoutineTimeout: opt.SubRoutineTimeout,
+		codeTtl:           opt.CodeTtl,
+		maxAttempts:       opt.MaxAttempts,
+		cancelUrl:         opt.CancelUrl,
+		auditRecorder:     opt.AuditRecorder,
+	}
+}
+
+func (s *contactChangeServiceImpl) RequestEmailChange(ctx context.Context, req entity.ChangeEmailReq) (*entity.ContactChangeRes, error) {
+	return s.requestChange(ctx, constant.ContactTypeEmail, req.Email)
+}
+
+func (s *contactChangeServiceImpl) RequestPhoneNumberChange(ctx context.Context, req entity.ChangePhoneNumberReq) (*entity.ContactChangeRes, error) {
+	return s.requestChange(ctx, constant.ContactTypePhoneNumber, req.PhoneNumber)
+}
+
+// requestChange records a pending change, replacing any earlier pending
+// change of the same contact type, and sends the code to the new value and
+// the cancel link to the old one. The account is not touched until the code
+// is confirmed.
+func (s *contactChangeServiceImpl) requestChange(ctx context.Context, contactType, newValue string) (*entity.ContactChangeRes, error) {
+	accountId := ctx.Value(constant.AccountIdCtxKey).(int64)
+
+	code, err := helper.GenerateNumericCode(contactChangeCodeDigits)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[contact_change_service][requestChange][helper.GenerateNumericCode] Error: %s | account_id: %v", err.Error(), accountId),
+		})
+	}
+
+	cancelToken, err := helper.GenerateOpaqueToken(contactChangeCancelTokenBytes)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[contact_change_service][requestChange][helper.GenerateOpaqueToken] Error: %s | account_id: %v", err.Error(), accountId),
+		})
+	}
+
+	var (
+		res   *entity.ContactChangeRes
+		event *entity.ContactChangeEvent
+	)
+
+	err = s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		account, err := repos.Account.GetAccountById(ctx, accountId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[contact_change_service][requestChange][accountRepo.GetAccountById] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		if account == nil {
+			return apperror.NewAppError(apperror.AppErrorOpt{
+				Code:            http.StatusNotFound,
+				Message:         fmt.Sprintf("[contact_change_service][requestChange] account not found | account_id: %v", accountId),
+				ResponseMessage: constant.MsgAccountNotFound,
+			})
+		}
+
+		oldValue := contactValue(*account, contactType)
+		if oldValue == newValue {
+			return apperror.BadRequestError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("[contact_change_service][requestChange] %s unchanged | account_id: %v", contactType, accountId),
+				ResponseMessage: constant.MsgContactUnchanged,
+			})
+		}
+
+		err = checkContactAvailable(ctx, repos.Account, *account, contactType, newValue)
+		if err != nil {
+			return err
+		}
+
+		err = repos.ContactChange.CancelPendingChanges(ctx, accountId, contactType)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[contact_change_service][requestChange][contactChangeRepo.CancelPendingChanges] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		contactChange := entity.AccountContactChange{
+			AccountId:       accountId,
+			ContactType:     contactType,
+			OldValue:        oldValue,
+			NewValue:        newValue,
+			CodeHash:        s.tokenHasher.HashToken(code),
+			CancelTokenHash: s.tokenHasher.HashToken(cancelToken),
+			ExpiredAt:       time.Now().Add(s.codeTtl).UnixMilli(),
+		}
+
+		contactChangeId, err := repos.ContactChange.InsertChange(ctx, contactChange)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[contact_change_service][requestChange][contactChangeRepo.InsertChange] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		res = &entity.ContactChangeRes{
+			ContactChangeId: contactChangeId,
+			ContactType:     contactType,
+			NewValue:        newValue,
+			ExpiredAt:       contactChange.ExpiredAt,
+		}
+
+		event = &entity.ContactChangeEvent{
+			AccountId:   accountId,
+			Name:        account.Name,
+			ContactType: contactType,
+			OldValue:    oldValue,
+			NewValue:    newValue,
+			Code:        code,
+			CancelUrl:   fmt.Sprintf("%s?token=%s", s.cancelUrl, url.QueryEscape(cancelToken)),
+			ExpiredAt:   contactChange.ExpiredAt,
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[contact_change_service][requestChange]", authEvent(constant.AuthEventContactChange, accountId, 0, constant.AuthEventOutcomeSuccess, constant.AuthEventReasonRequested))
+	})
+	if err != nil {
+		return nil, txError("[contact_change_service][requestChange]", err)
+	}
+
+	s.notifyContactChange(*event)
+
+	return res, nil
+}
+
+// ConfirmContactChange applies a pending change once its code is confirmed.
+// The new value is checked against other accounts under the same table lock
+// Register takes, and every session but the current one is revoked. Wrong
+// codes are counted; after maxAttempts the change can no longer be
+// confirmed.
+func (s *contactChangeServiceImpl) ConfirmContactChange(ctx context.Context, req entity.ConfirmContactChangeReq) (*entity.AccountProfileRes, error) {
+	accountId := ctx.Value(constant.AccountIdCtxKey).(int64)
+	deviceId := ctx.Value(constant.DeviceIdCtxKey).(int64)
+
+	var (
+		profile    *entity.AccountProfileRes
+		codeErr    error
+		failReason string
+	)
+
+	err := s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		codeErr = nil
+		failReason = constant.AuthEventReasonInternalError
+
+		err := repos.Account.Lock(ctx)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[contact_change_service][ConfirmContactChange][accountRepo.Lock] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		contactChange, err := repos.ContactChange.GetPendingChangeById(ctx, accountId, req.ContactChangeId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[contact_change_service][ConfirmContactChange][contactChangeRepo.GetPendingChangeById] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		if contactChange == nil || contactChange.ExpiredAt <= time.Now().UnixMilli() || contactChange.Attempts >= s.maxAttempts {
+			failReason = constant.AuthEventReasonInvalidCode
+
+			return apperror.BadRequestError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("[contact_change_service][ConfirmContactChange] change not found, expired or out of attempts | account_id: %v | contact_change_id: %v", accountId, req.ContactChangeId),
+				ResponseMessage: constant.MsgInvalidContactCode,
+			})
+		}
+
+		codeHash := s.tokenHasher.HashToken(req.Code)
+		if subtle.ConstantTimeCompare([]byte(codeHash), []byte(contactChange.CodeHash)) != 1 {
+			err = repos.ContactChange.IncrementAttempts(ctx, contactChange.ContactChangeId)
+			if err != nil {
+				return apperror.InternalServerError(apperror.AppErrorOpt{
+					Message: fmt.Sprintf("[contact_change_service][ConfirmContactChange][contactChangeRepo.IncrementAttempts] Error: %s | account_id: %v", err.Error(), accountId),
+				})
+			}
+
+			// The attempt must be committed, so the error is returned after
+			// the transaction.
+			codeErr = apperror.BadRequestError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("[contact_change_service][ConfirmContactChange] wrong code | account_id: %v | contact_change_id: %v", accountId, req.ContactChangeId),
+				ResponseMessage: constant.MsgInvalidContactCode,
+			})
+
+			return recordTx(ctx, s.auditRecorder, repos, "[contact_change_service][ConfirmContactChange]", authEvent(constant.AuthEventContactChange, accountId, deviceId, constant.AuthEventOutcomeFailure, constant.AuthEventReasonInvalidCode))
+		}
+
+		account, err := repos.Account.GetAccountById(ctx, accountId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[contact_change_service][ConfirmContactChange][accountRepo.GetAccountById] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		if account == nil {
+			failReason = constant.AuthEventReasonAccountNotFound
+
+			return apperror.NewAppError(apperror.AppErrorOpt{
+				Code:            http.StatusNotFound,
+				Message:         fmt.Sprintf("[contact_change_service][ConfirmContactChange] account not found | account_id: %v", accountId),
+				ResponseMessage: constant.MsgAccountNotFound,
+			})
+		}
+
+		err = checkContactAvailable(ctx, repos.Account, *account, contactChange.ContactType, contactChange.NewValue)
+		if err != nil {
+			failReason = contactTakenReason(contactChange.ContactType)
+
+			return err
+		}
+
+		updated := withContactValue(*account, contactChange.ContactType, contactChange.NewValue)
+
+		err = repos.Account.UpdateAccount(ctx, updated)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[contact_change_service][ConfirmContactChange][accountRepo.UpdateAccount] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		err = repos.ContactChange.ConfirmChange(ctx, contactChange.ContactChangeId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[contact_change_service][ConfirmContactChange][contactChangeRepo.ConfirmChange] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		revoked, err := repos.RefreshToken.DeleteOtherSessions(ctx, accountId, deviceId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[contact_change_service][ConfirmContactChange][refreshTokenRepo.DeleteOtherSessions] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		err = recordTx(ctx, s.auditRecorder, repos, "[contact_change_service][ConfirmContactChange]", authEvent(constant.AuthEventContactChange, accountId, deviceId, constant.AuthEventOutcomeSuccess, constant.AuthEventReasonConfirmed))
+		if err != nil {
+			return err
+		}
+
+		if revoked > 0 {
+			err = recordTx(ctx, s.auditRecorder, repos, "[contact_change_service][ConfirmContactChange]", authEvent(constant.AuthEventSessionRevoked, accountId, 0, constant.AuthEventOutcomeSuccess, constant.AuthEventReasonContactChanged))
+			if err != nil {
+				return err
+			}
+		}
+
+		updated.UpdatedAt = time.Now().UnixMilli()
+		profile = accountProfile(updated)
+
+		return nil
+	})
+	if err != nil {
+		s.auditRecorder.Record(ctx, authEvent(constant.AuthEventContactChange, accountId, deviceId, constant.AuthEventOutcomeFailure, failReason))
+
+		return nil, txError("[contact_change_service][ConfirmContactChange]", err)
+	}
+
+	if codeErr != nil {
+		return nil, codeErr
+	}
+
+	return profile, nil
+}
+
+// CancelContactChange cancels a pending change with the token from the link
+// sent to the old value.
+func (s *contactChangeServiceImpl) CancelContactChange(ctx context.Context, req entity.CancelContactChangeReq) error {
+	contactChange, err := s.contactChangeRepo.GetPendingChangeByCancelTokenHash(ctx, s.tokenHasher.HashToken(req.Token))
+	if err != nil {
+		return apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[contact_change_service][CancelContactChange][contactChangeRepo.GetPendingChangeByCancelTokenHash] Error: %s", err.Error()),
+		})
+	}
+
+	if contactChange == nil || contactChange.ExpiredAt <= time.Now().UnixMilli() {
+		return apperror.BadRequestError(apperror.AppErrorOpt{
+			Message:         "[contact_change_service][CancelContactChange] cancel token not found or expired",
+			ResponseMessage: constant.MsgInvalidCancelToken,
+		})
+	}
+
+	err = s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		err := repos.ContactChange.CancelChange(ctx, contactChange.ContactChangeId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[contact_change_service][CancelContactChange][contactChangeRepo.CancelChange] Error: %s | account_id: %v", err.Error(), contactChange.AccountId),
+			})
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[contact_change_service][CancelContactChange]", authEvent(constant.AuthEventContactChange, contactChange.AccountId, 0, constant.AuthEventOutcomeSuccess, constant.AuthEventReasonCancelled))
+	})
+	if err != nil {
+		return txError("[contact_change_service][CancelContactChange]", err)
+	}
+
+	return nil
+}
+
+// notifyContactChange sends the code and cancel link in the background; a
+// failed notification does not fail the request.
+func (s *contactChangeServiceImpl) notifyContactChange(event entity.ContactChangeEvent) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), s.subRoutineTimeout)
+		defer cancel()
+
+		err := s.notifier.NotifyContactChange(ctx, event)
+		if err != nil {
+			s.log.WithFields(logrus.Fields{
+				"error":        err.Error(),
+				"account_id":   event.AccountId,
+				"contact_type": event.ContactType,
+			}).Error("[contact_change_service][notifyContactChange][notifier.NotifyContactChange][sub-routine]")
+		}
+	}()
+}
+
+// checkContactAvailable fails when another account of the same tenant
+// already uses the value.
+func checkContactAvailable(ctx context.Context, accountRepo repository.AccountRepository, account entity.Account, contactType, value string) error {
+	accountId := account.Id
+
+	var (
+		existing *entity.Account
+		err      error
+	)
+
+	switch contactType {
+	case constant.ContactTypeEmail:
+		existing, err = accountRepo.GetAccountByEmail(ctx, account.TenantId, value)
+	default:
+		existing, err = accountRepo.GetAccountByPhoneNumber(ctx, account.TenantId, value)
+	}
+	if err != nil {
+		return apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[contact_change_service][checkContactAvailable][accountRepo.GetAccountBy] Error: %s | account_id: %v | contact_type: %s", err.Error(), accountId, contactType),
+		})
+	}
+
+	if existing != nil && existing.Id != accountId {
+		responseMessage := "email already registered"
+		if contactType == constant.ContactTypePhoneNumber {
+			responseMessage = "phone number already registered"
+		}
+
+		return apperror.BadRequestError(apperror.AppErrorOpt{
+			Message:         fmt.Sprintf("[contact_change_service][checkContactAvailable] %s already registered | account_id: %v", contactType, accountId),
+			ResponseMessage: responseMessage,
+		})
+	}
+
+	return nil
+}
+
+func contactTakenReason(contactType string) string {
+	if contactType == constant.ContactTypePhoneNumber {
+		return constant.AuthEventReasonPhoneNumberTaken
+	}
+
+	return constant.AuthEventReasonEmailTaken
+}
+
+func contactValue(account entity.Account, contactType string) string {
+	if contactType == constant.ContactTypePhoneNumber {
+		return account.PhoneNumber
+	}
+
+	return account.Email
+}
+
+func withContactValue(account entity.Account, contactType, value string) entity.Account {
+	if contactType == constant.ContactTypePhoneNumber {
+		account.PhoneNumber = value
+	} else {
+		account.Email = value
+	}
+
+	return account
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"io"
 
 	"github.com/michaelyusak/go-auth/entity"
 )
@@ -9,4 +10,86 @@ import (
 type AccountService interface {
 	Register(ctx context.Context, newAccount entity.Account) error
 	Login(ctx context.Context, req entity.LoginReq) (*entity.TokenData, error)
+	RefreshToken(ctx context.Context, req entity.RefreshTokenReq) (*entity.TokenData, error)
+	SwitchOrganization(ctx context.Context, req entity.SwitchOrganizationReq) (*entity.TokenData, error)
+	IssueClientTokens(ctx context.Context, accountId, deviceId int64, grant entity.ClientGrant) (*entity.TokenData, error)
+	RefreshClientToken(ctx context.Context, grant entity.ClientGrant, refreshToken string) (*entity.TokenData, error)
+	GetProfile(ctx context.Context) (*entity.AccountProfileRes, error)
+	UpdateProfile(ctx context.Context, req entity.UpdateProfileReq) (*entity.AccountProfileRes, error)
+	DeleteAccount(ctx context.Context, req entity.DeleteAccountReq) error
+	ResetPassword(ctx context.Context, req entity.ResetPasswordReq) error
+}
+
+type AccountDeviceService interface {
+	GetDevices(ctx context.Context) ([]entity.AccountDeviceRes, error)
+	RenameDevice(ctx context.Context, deviceId int64, req entity.RenameDeviceReq) error
+	DeleteDevice(ctx context.Context, deviceId int64) error
+	ApproveDeviceByToken(ctx context.Context, req entity.ApproveDeviceReq) error
+	ApproveDevice(ctx context.Context, deviceId int64) error
+}
+
+type AuthEventService interface {
+	GetActivity(ctx context.Context, query entity.AuthEventQuery) (*entity.AuthEventPage, error)
+	SearchEvents(ctx context.Context, query entity.AdminAuthEventQuery) (*entity.AuthEventPage, error)
+}
+
+type ContactChangeService interface {
+	RequestEmailChange(ctx context.Context, req entity.ChangeEmailReq) (*entity.ContactChangeRes, error)
+	RequestPhoneNumberChange(ctx context.Context, req entity.ChangePhoneNumberReq) (*entity.ContactChangeRes, error)
+	ConfirmContactChange(ctx context.Context, req entity.ConfirmContactChangeReq) (*entity.AccountProfileRes, error)
+	CancelContactChange(ctx context.Context, req entity.CancelContactChangeReq) error
+}
+
+type AccountExportService interface {
+	ExportAccount(ctx context.Context, w io.Writer) error
+}
+
+type AccountStatusService interface {
+	CheckAccountStatus(ctx context.Context, accountId int64) error
+	UpdateAccountStatus(ctx context.Context, accountId int64, req entity.UpdateAccountStatusReq) (*entity.AccountStatusRes, error)
+}
+
+type AdminAccountService interface {
+	SearchAccounts(ctx context.Context, query entity.AdminAccountQuery) (*entity.AdminAccountPage, error)
+	GetAccount(ctx context.Context, accountId int64) (*entity.AdminAccountDetailRes, error)
+	SuspendAccount(ctx context.Context, accountId int64, req entity.SuspendAccountReq) (*entity.AccountStatusRes, error)
+	UnsuspendAccount(ctx context.Context, accountId int64) (*entity.AccountStatusRes, error)
+	UnlockAccount(ctx context.Context, accountId int64) (*entity.AccountStatusRes, error)
+	RevokeSessions(ctx context.Context, accountId int64) (*entity.RevokeSessionsRes, error)
+	ForcePasswordReset(ctx context.Context, accountId int64) (*entity.ForcePasswordResetRes, error)
+}
+
+type RoleService interface {
+	GetRoles(ctx context.Context) ([]entity.RoleRes, error)
+	CreateRole(ctx context.Context, req entity.CreateRoleReq) (*entity.RoleRes, error)
+	UpdateRole(ctx context.Context, roleId int64, req entity.UpdateRoleReq) (*entity.RoleRes, error)
+	DeleteRole(ctx context.Context, roleId int64) error
+	GetPermissions(ctx context.Context) ([]entity.PermissionRes, error)
+	CreatePermission(ctx context.Context, req entity.CreatePermissionReq) (*entity.PermissionRes, error)
+	DeletePermission(ctx context.Context, permissionId int64) error
+	GetAccountRoles(ctx context.Context, accountId int64) ([]entity.RoleRes, error)
+	AssignRole(ctx context.Context, accountId, roleId int64) error
+	UnassignRole(ctx context.Context, accountId, roleId int64) error
+}
+
+type OrganizationService interface {
+	CreateOrganization(ctx context.Context, req entity.CreateOrganizationReq) (*entity.OrganizationRes, error)
+	GetOrganizations(ctx context.Context) ([]entity.OrganizationRes, error)
+	GetMembers(ctx context.Context, organizationId int64) ([]entity.OrganizationMemberRes, error)
+	UpdateMemberRole(ctx context.Context, organizationId, accountId int64, req entity.UpdateMemberRoleReq) (*entity.OrganizationMemberRes, error)
+	RemoveMember(ctx context.Context, organizationId, accountId int64) error
+	InviteMember(ctx context.Context, organizationId int64, req entity.InviteMemberReq) (*entity.OrganizationInvitationRes, error)
+	GetInvitations(ctx context.Context, organizationId int64) ([]entity.OrganizationInvitationRes, error)
+	RevokeInvitation(ctx context.Context, organizationId, invitationId int64) error
+	AcceptInvitation(ctx context.Context, req entity.AcceptInvitationReq) (*entity.OrganizationRes, error)
+}
+
+type OAuthService interface {
+	GetClients(ctx context.Context) ([]entity.OAuthClientRes, error)
+	CreateClient(ctx context.Context, req entity.CreateOAuthClientReq) (*entity.OAuthClientRes, error)
+	UpdateClient(ctx context.Context, clientId string, req entity.UpdateOAuthClientReq) (*entity.OAuthClientRes, error)
+	DeleteClient(ctx context.Context, clientId string) error
+	LoginRedirect(ctx context.Context, req entity.AuthorizeReq) (string, error)
+	Authorize(ctx context.Context, req entity.AuthorizeReq) (*entity.AuthorizeRes, error)
+	Token(ctx context.Context, req entity.OAuthTokenReq) (*entity.OAuthTokenRes, error)
 }
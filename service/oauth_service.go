@@ -0,0 +1,638 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/michaelyusak/go-auth/audit"
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/helper"
+	"github.com/michaelyusak/go-auth/repository"
+	"github.com/michaelyusak/go-helper/apperror"
+)
+
+const (
+	clientIdBytes          = 16
+	clientSecretBytes      = 32
+	authorizationCodeBytes = 32
+	minCodeVerifierLength  = 43
+	maxCodeVerifierLength  = 128
+)
+
+// OAuthError is an error of the OAuth protocol itself. It is answered in the
+// format of RFC 6749 rather than with the service's own error response.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+func oauthError(code, description string) *OAuthError {
+	return &OAuthError{
+		Code:        code,
+		Description: description,
+	}
+}
+
+// oauthServiceImpl lets registered clients sign accounts in with the
+// authorization code flow, PKCE being mandatory. The account signs in with
+// the regular login first; sessions are then started through the account
+// service, so tokens are issued and rotated exactly as for a login.
+type oauthServiceImpl struct {
+	oauthRepo        repository.OAuthRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	roleRepo         repository.RoleRepository
+	accountService   AccountService
+	transaction      repository.Transaction
+	tokenHasher      helper.TokenHasher
+	loginUrl         string
+	codeTtl          time.Duration
+	auditRecorder    audit.Recorder
+}
+
+type OAuthServiceOpt struct {
+	OAuthRepo        repository.OAuthRepository
+	RefreshTokenRepo repository.RefreshTokenRepository
+	RoleRepo         repository.RoleRepository
+	AccountService   AccountService
+	Transaction      repository.Transaction
+	TokenHasher      helper.TokenHasher
+	LoginUrl         string
+	CodeTtl          time.Duration
+	AuditRecorder    audit.Recorder
+}
+
+func NewOAuthService(opt OAuthServiceOpt) *oauthServiceImpl {
+	return &oauthServiceImpl{
+		oauthRepo:        opt.OAuthRepo,
+		refreshTokenRepo: opt.RefreshTokenRepo,
+		roleRepo:         opt.RoleRepo,
+		accountService:   opt.AccountService,
+		transaction:      opt.Transaction,
+		tokenHasher:      opt.TokenHasher,
+		loginUrl:         opt.LoginUrl,
+		codeTtl:          opt.CodeTtl,
+		auditRecorder:    opt.AuditRecorder,
+	}
+}
+
+func (s *oauthServiceImpl) GetClients(ctx context.Context) ([]entity.OAuthClientRes, error) {
+	clients, err := s.oauthRepo.GetClients(ctx, requestTenant(ctx))
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[oauth_service][GetClients][oauthRepo.GetClients] Error: %s", err.Error()),
+		})
+	}
+
+	res := make([]entity.OAuthClientRes, 0, len(clients))
+	for _, client := range clients {
+		res = append(res, oauthClientRes(client))
+	}
+
+	return res, nil
+}
+
+// CreateClient registers a client in the tenant of the request. The secret
+// of a confidential client is only ever returned here.
+func (s *oauthServiceImpl) CreateClient(ctx context.Context, req entity.CreateOAuthClientReq) (*entity.OAuthClientRes, error) {
+	redirectUris, scopes, err := s.clientSettings(ctx, "[oauth_service][CreateClient]", req.RedirectUris, req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	clientId, err := helper.GenerateOpaqueToken(clientIdBytes)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[oauth_service][CreateClient][helper.GenerateOpaqueToken] client id | Error: %s", err.Error()),
+		})
+	}
+
+	client := entity.OAuthClient{
+		ClientId:     clientId,
+		TenantId:     requestTenant(ctx),
+		Name:         req.Name,
+		RedirectUris: redirectUris,
+		Scopes:       scopes,
+	}
+
+	var clientSecret string
+
+	if req.Confidential {
+		clientSecret, err = helper.GenerateOpaqueToken(clientSecretBytes)
+		if err != nil {
+			return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[oauth_service][CreateClient][helper.GenerateOpaqueToken] client secret | Error: %s", err.Error()),
+			})
+		}
+
+		secretHash := s.tokenHasher.HashToken(clientSecret)
+		client.SecretHash = &secretHash
+	}
+
+	err = s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		err := repos.OAuth.InsertClient(ctx, client)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[oauth_service][CreateClient][oauthRepo.InsertClient] Error: %s | client_id: %s", err.Error(), clientId),
+			})
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[oauth_service][CreateClient]", authEvent(constant.AuthEventOAuthClientCreated, 0, 0, constant.AuthEventOutcomeSuccess, clientId))
+	})
+	if err != nil {
+		return nil, txError("[oauth_service][CreateClient]", err)
+	}
+
+	now := time.Now().UnixMilli()
+
+	client.CreatedAt = now
+	client.UpdatedAt = now
+
+	res := oauthClientRes(client)
+	res.ClientSecret = clientSecret
+
+	return &res, nil
+}
+
+// UpdateClient replaces the name, redirect URIs and scopes of a client.
+// Sessions already started keep their scope, bounded by the new scopes.
+func (s *oauthServiceImpl) UpdateClient(ctx context.Context, clientId string, req entity.UpdateOAuthClientReq) (*entity.OAuthClientRes, error) {
+	client, err := s.getClient(ctx, "[oauth_service][UpdateClient]", clientId)
+	if err != nil {
+		return nil, err
+	}
+
+	client.RedirectUris, client.Scopes, err = s.clientSettings(ctx, "[oauth_service][UpdateClient]", req.RedirectUris, req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	client.Name = req.Name
+
+	err = s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		err := repos.OAuth.UpdateClient(ctx, *client)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[oauth_service][UpdateClient][oauthRepo.UpdateClient] Error: %s | client_id: %s", err.Error(), clientId),
+			})
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[oauth_service][UpdateClient]", authEvent(constant.AuthEventOAuthClientUpdated, 0, 0, constant.AuthEventOutcomeSuccess, clientId))
+	})
+	if err != nil {
+		return nil, txError("[oauth_service][UpdateClient]", err)
+	}
+
+	client.UpdatedAt = time.Now().UnixMilli()
+	res := oauthClientRes(*client)
+
+	return &res, nil
+}
+
+// DeleteClient deletes a client, ending every session it holds.
+func (s *oauthServiceImpl) DeleteClient(ctx context.Context, clientId string) error {
+	_, err := s.getClient(ctx, "[oauth_service][DeleteClient]", clientId)
+	if err != nil {
+		return err
+	}
+
+	err = s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		deleted, err := repos.OAuth.DeleteClient(ctx, clientId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[oauth_service][DeleteClient][oauthRepo.DeleteClient] Error: %s | client_id: %s", err.Error(), clientId),
+			})
+		}
+
+		if !deleted {
+			return apperror.NewAppError(apperror.AppErrorOpt{
+				Code:            http.StatusNotFound,
+				Message:         fmt.Sprintf("[oauth_service][DeleteClient] client not found | client_id: %s", clientId),
+				ResponseMessage: constant.MsgOAuthClientNotFound,
+			})
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[oauth_service][DeleteClient]", authEvent(constant.AuthEventOAuthClientDeleted, 0, 0, constant.AuthEventOutcomeSuccess, clientId))
+	})
+	if err != nil {
+		return txError("[oauth_service][DeleteClient]", err)
+	}
+
+	return nil
+}
+
+// LoginRedirect answers an authorization request coming straight from the
+// client with where to send the browser: the login page, which completes
+// the request with Authorize once the account is signed in, or back to the
+// client when the request is invalid.
+func (s *oauthServiceImpl) LoginRedirect(ctx context.Context, req entity.AuthorizeReq) (string, error) {
+	client, err := s.authorizationClient(ctx, "[oauth_service][LoginRedirect]", req)
+	if err != nil {
+		return "", err
+	}
+
+	if oauthErr := authorizationError(*client, req); oauthErr != nil {
+		return redirectWithParams("[oauth_service][LoginRedirect]", req.RedirectUri, oauthErrorParams(oauthErr, req.State))
+	}
+
+	params := url.Values{}
+	params.Set("response_type", req.ResponseType)
+	params.Set("client_id", req.ClientId)
+	params.Set("redirect_uri", req.RedirectUri)
+	params.Set("scope", req.Scope)
+	params.Set("state", req.State)
+	params.Set("code_challenge", req.CodeChallenge)
+	params.Set("code_challenge_method", req.CodeChallengeMethod)
+
+	return redirectWithParams("[oauth_service][LoginRedirect]", s.loginUrl, params)
+}
+
+// Authorize issues an authorization code for the signed in account and
+// the device it signed in from. The client must belong to the account's
+// tenant.
+func (s *oauthServiceImpl) Authorize(ctx context.Context, req entity.AuthorizeReq) (*entity.AuthorizeRes, error) {
+	accountId := ctx.Value(constant.AccountIdCtxKey).(int64)
+	deviceId := ctx.Value(constant.DeviceIdCtxKey).(int64)
+
+	client, err := s.authorizationClient(ctx, "[oauth_service][Authorize]", req)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.TenantId != requestTenant(ctx) {
+		return nil, apperror.BadRequestError(apperror.AppErrorOpt{
+			Message:         fmt.Sprintf("[oauth_service][Authorize] client of another tenant | account_id: %v | client_id: %s", accountId, client.ClientId),
+			ResponseMessage: constant.MsgOAuthClientNotFound,
+		})
+	}
+
+	if oauthErr := authorizationError(*client, req); oauthErr != nil {
+		redirectUri, err := redirectWithParams("[oauth_service][Authorize]", req.RedirectUri, oauthErrorParams(oauthErr, req.State))
+		if err != nil {
+			return nil, err
+		}
+
+		return &entity.AuthorizeRes{
+			RedirectUri: redirectUri,
+		}, nil
+	}
+
+	code, err := helper.GenerateOpaqueToken(authorizationCodeBytes)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[oauth_service][Authorize][helper.GenerateOpaqueToken] Error: %s | account_id: %v", err.Error(), accountId),
+		})
+	}
+
+	err = s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		err := repos.OAuth.InsertAuthorizationCode(ctx, entity.OAuthAuthorizationCode{
+			CodeHash:      s.tokenHasher.HashToken(code),
+			ClientId:      client.ClientId,
+			AccountId:     accountId,
+			DeviceId:      deviceId,
+			RedirectUri:   req.RedirectUri,
+			Scope:         strings.Join(strings.Fields(req.Scope), " "),
+			CodeChallenge: req.CodeChallenge,
+			ExpiredAt:     time.Now().Add(s.codeTtl).UnixMilli(),
+		})
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[oauth_service][Authorize][oauthRepo.InsertAuthorizationCode] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[oauth_service][Authorize]", authEvent(constant.AuthEventOAuthAuthorized, accountId, deviceId, constant.AuthEventOutcomeSuccess, client.ClientId))
+	})
+	if err != nil {
+		return nil, txError("[oauth_service][Authorize]", err)
+	}
+
+	params := url.Values{}
+	params.Set("code", code)
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	redirectUri, err := redirectWithParams("[oauth_service][Authorize]", req.RedirectUri, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.AuthorizeRes{
+		RedirectUri: redirectUri,
+	}, nil
+}
+
+// Token serves the token endpoint. Sessions are started and rotated in the
+// client's tenant, whatever tenant the request resolved to.
+func (s *oauthServiceImpl) Token(ctx context.Context, req entity.OAuthTokenReq) (*entity.OAuthTokenRes, error) {
+	client, err := s.authenticateClient(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = context.WithValue(ctx, constant.TenantCtxKey, client.TenantId)
+
+	switch req.GrantType {
+	case constant.OAuthGrantAuthorizationCode:
+		return s.exchangeCode(ctx, *client, req)
+	case constant.OAuthGrantRefreshToken:
+		return s.refreshToken(ctx, *client, req)
+	case "":
+		return nil, oauthError(constant.OAuthErrorInvalidRequest, "grant_type is required")
+	default:
+		return nil, oauthError(constant.OAuthErrorUnsupportedGrantType, fmt.Sprintf("grant type %s is not supported", req.GrantType))
+	}
+}
+
+// exchangeCode redeems an authorization code. The code is used up before
+// the session is started, so a failure afterwards needs a new
+// authorization.
+func (s *oauthServiceImpl) exchangeCode(ctx context.Context, client entity.OAuthClient, req entity.OAuthTokenReq) (*entity.OAuthTokenRes, error) {
+	if req.Code == "" || req.RedirectUri == "" || req.CodeVerifier == "" {
+		return nil, oauthError(constant.OAuthErrorInvalidRequest, "code, redirect_uri and code_verifier are required")
+	}
+
+	code, err := s.oauthRepo.GetAuthorizationCodeByHash(ctx, s.tokenHasher.HashToken(req.Code))
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[oauth_service][exchangeCode][oauthRepo.GetAuthorizationCodeByHash] Error: %s | client_id: %s", err.Error(), client.ClientId),
+		})
+	}
+
+	if code == nil || code.ClientId != client.ClientId || code.UsedAt != nil || code.ExpiredAt <= time.Now().UnixMilli() {
+		return nil, oauthError(constant.OAuthErrorInvalidGrant, "invalid or expired authorization code")
+	}
+
+	if code.RedirectUri != req.RedirectUri {
+		return nil, oauthError(constant.OAuthErrorInvalidGrant, "redirect_uri does not match the authorization request")
+	}
+
+	if !verifyCodeChallenge(req.CodeVerifier, code.CodeChallenge) {
+		return nil, oauthError(constant.OAuthErrorInvalidGrant, "code_verifier does not match the code challenge")
+	}
+
+	used, err := s.oauthRepo.UseAuthorizationCode(ctx, code.AuthorizationCodeId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[oauth_service][exchangeCode][oauthRepo.UseAuthorizationCode] Error: %s | client_id: %s", err.Error(), client.ClientId),
+		})
+	}
+
+	if !used {
+		return nil, oauthError(constant.OAuthErrorInvalidGrant, "invalid or expired authorization code")
+	}
+
+	tokenData, err := s.accountService.IssueClientTokens(ctx, code.AccountId, code.DeviceId, entity.ClientGrant{
+		ClientId: client.ClientId,
+		Scope:    intersectScopes(strings.Fields(code.Scope), client.Scopes),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return oauthTokenRes(*tokenData), nil
+}
+
+// refreshToken checks that the refresh token was issued to the client
+// before handing it to the account service to rotate.
+func (s *oauthServiceImpl) refreshToken(ctx context.Context, client entity.OAuthClient, req entity.OAuthTokenReq) (*entity.OAuthTokenRes, error) {
+	if req.RefreshToken == "" {
+		return nil, oauthError(constant.OAuthErrorInvalidRequest, "refresh_token is required")
+	}
+
+	refreshToken, err := s.refreshTokenRepo.GetTokenByHash(ctx, s.tokenHasher.HashToken(req.RefreshToken))
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[oauth_service][refreshToken][refreshTokenRepo.GetTokenByHash] Error: %s | client_id: %s", err.Error(), client.ClientId),
+		})
+	}
+
+	if refreshToken == nil || refreshToken.ClientId == nil || *refreshToken.ClientId != client.ClientId || refreshToken.ExpiredAt <= time.Now().UnixMilli() {
+		return nil, oauthError(constant.OAuthErrorInvalidGrant, "invalid or expired refresh token")
+	}
+
+	tokenData, err := s.accountService.RefreshClientToken(ctx, entity.ClientGrant{
+		ClientId: client.ClientId,
+		Scope:    client.Scopes,
+	}, req.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return oauthTokenRes(*tokenData), nil
+}
+
+// authenticateClient identifies the client of a token request. Confidential
+// clients must prove it with their secret; public clients are bound by PKCE
+// and the refresh token instead.
+func (s *oauthServiceImpl) authenticateClient(ctx context.Context, req entity.OAuthTokenReq) (*entity.OAuthClient, error) {
+	if req.ClientId == "" {
+		return nil, oauthError(constant.OAuthErrorInvalidClient, "client authentication failed")
+	}
+
+	client, err := s.oauthRepo.GetClientById(ctx, req.ClientId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[oauth_service][authenticateClient][oauthRepo.GetClientById] Error: %s | client_id: %s", err.Error(), req.ClientId),
+		})
+	}
+
+	if client == nil {
+		return nil, oauthError(constant.OAuthErrorInvalidClient, "client authentication failed")
+	}
+
+	if client.SecretHash != nil && !hmac.Equal([]byte(s.tokenHasher.HashToken(req.ClientSecret)), []byte(*client.SecretHash)) {
+		return nil, oauthError(constant.OAuthErrorInvalidClient, "client authentication failed")
+	}
+
+	return client, nil
+}
+
+// authorizationClient checks the client and redirect URI of an
+// authorization request. Their errors are answered to the user rather than
+// sent to a redirect URI that cannot be trusted.
+func (s *oauthServiceImpl) authorizationClient(ctx context.Context, caller string, req entity.AuthorizeReq) (*entity.OAuthClient, error) {
+	client, err := s.oauthRepo.GetClientById(ctx, req.ClientId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("%s[oauthRepo.GetClientById] Error: %s | client_id: %s", caller, err.Error(), req.ClientId),
+		})
+	}
+
+	if client == nil {
+		return nil, apperror.BadRequestError(apperror.AppErrorOpt{
+			Message:         fmt.Sprintf("%s client not found | client_id: %s", caller, req.ClientId),
+			ResponseMessage: constant.MsgOAuthClientNotFound,
+		})
+	}
+
+	if !slices.Contains(client.RedirectUris, req.RedirectUri) {
+		return nil, apperror.BadRequestError(apperror.AppErrorOpt{
+			Message:         fmt.Sprintf("%s redirect uri not registered | client_id: %s | redirect_uri: %s", caller, req.ClientId, req.RedirectUri),
+			ResponseMessage: constant.MsgInvalidRedirectUri,
+		})
+	}
+
+	return client, nil
+}
+
+// getClient fetches a client of the request's tenant for its admins.
+// Clients of other tenants are not found.
+func (s *oauthServiceImpl) getClient(ctx context.Context, caller, clientId string) (*entity.OAuthClient, error) {
+	client, err := s.oauthRepo.GetClientById(ctx, clientId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("%s[oauthRepo.GetClientById] Error: %s | client_id: %s", caller, err.Error(), clientId),
+		})
+	}
+
+	if client == nil || client.TenantId != requestTenant(ctx) {
+		return nil, apperror.NewAppError(apperror.AppErrorOpt{
+			Code:            http.StatusNotFound,
+			Message:         fmt.Sprintf("%s client not found | client_id: %s", caller, clientId),
+			ResponseMessage: constant.MsgOAuthClientNotFound,
+		})
+	}
+
+	return client, nil
+}
+
+// clientSettings deduplicates the redirect URIs and scopes of a client.
+// Redirect URIs are stored space separated and cannot carry fragments, and
+// scopes must be known permissions.
+func (s *oauthServiceImpl) clientSettings(ctx context.Context, caller string, redirectUris, scopes []string) ([]string, []string, error) {
+	for _, redirectUri := range redirectUris {
+		if strings.ContainsAny(redirectUri, " \t\r\n#") {
+			return nil, nil, apperror.BadRequestError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("%s malformed redirect uri | redirect_uri: %s", caller, redirectUri),
+				ResponseMessage: constant.MsgMalformedRedirectUri,
+			})
+		}
+	}
+
+	scopes = slices.Compact(slices.Sorted(slices.Values(scopes)))
+
+	_, err := permissionIdsByName(ctx, s.roleRepo, caller, scopes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return slices.Compact(slices.Clone(redirectUris)), scopes, nil
+}
+
+// authorizationError checks an authorization request whose client and
+// redirect URI are valid, returning the error to send back to the client.
+func authorizationError(client entity.OAuthClient, req entity.AuthorizeReq) *OAuthError {
+	if req.ResponseType != constant.OAuthResponseTypeCode {
+		return oauthError(constant.OAuthErrorUnsupportedResponseType, "response_type must be code")
+	}
+
+	if req.CodeChallengeMethod != constant.OAuthCodeChallengeS256 {
+		return oauthError(constant.OAuthErrorInvalidRequest, "code_challenge_method must be S256")
+	}
+
+	challenge, err := base64.RawURLEncoding.DecodeString(req.CodeChallenge)
+	if err != nil || len(challenge) != sha256.Size {
+		return oauthError(constant.OAuthErrorInvalidRequest, "code_challenge must be a base64url encoded SHA-256 hash")
+	}
+
+	for _, scope := range strings.Fields(req.Scope) {
+		if !slices.Contains(client.Scopes, scope) {
+			return oauthError(constant.OAuthErrorInvalidScope, fmt.Sprintf("scope %s is not allowed for the client", scope))
+		}
+	}
+
+	return nil
+}
+
+// verifyCodeChallenge checks a PKCE code verifier against the S256 code
+// challenge of RFC 7636 section 4.6.
+func verifyCodeChallenge(verifier, challenge string) bool {
+	if len(verifier) < minCodeVerifierLength || len(verifier) > maxCodeVerifierLength {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+
+	return hmac.Equal([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(challenge))
+}
+
+// intersectScopes keeps the scopes that are also in allowed, in order.
+func intersectScopes(scopes, allowed []string) []string {
+	intersection := make([]string, 0, len(scopes))
+
+	for _, scope := range scopes {
+		if slices.Contains(allowed, scope) && !slices.Contains(intersection, scope) {
+			intersection = append(intersection, scope)
+		}
+	}
+
+	return intersection
+}
+
+// redirectWithParams adds the params to the query of the URL, keeping the
+// query it already has.
+func redirectWithParams(caller, rawUrl string, params url.Values) (string, error) {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return "", apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("%s[url.Parse] Error: %s | url: %s", caller, err.Error(), rawUrl),
+		})
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+func oauthErrorParams(oauthErr *OAuthError, state string) url.Values {
+	params := url.Values{}
+	params.Set("error", oauthErr.Code)
+	params.Set("error_description", oauthErr.Description)
+	if state != "" {
+		params.Set("state", state)
+	}
+
+	return params
+}
+
+func oauthTokenRes(tokenData entity.TokenData) *entity.OAuthTokenRes {
+	return &entity.OAuthTokenRes{
+		AccessToken:  tokenData.AccessToken.Token,
+		TokenType:    constant.OAuthTokenTypeBearer,
+		ExpiresIn:    (tokenData.AccessToken.ExpiredAt - time.Now().UnixMilli()) / 1000,
+		RefreshToken: tokenData.RefreshToken.Token,
+		Scope:        tokenData.Scope,
+	}
+}
+
+func oauthClientRes(client entity.OAuthClient) entity.OAuthClientRes {
+	return entity.OAuthClientRes{
+		ClientId:     client.ClientId,
+		TenantId:     client.TenantId,
+		Name:         client.Name,
+		Confidential: client.SecretHash != nil,
+		RedirectUris: client.RedirectUris,
+		Scopes:       client.Scopes,
+		CreatedAt:    client.CreatedAt,
+		UpdatedAt:    client.UpdatedAt,
+	}
+}
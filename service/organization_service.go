@@ -0,0 +1,711 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/michaelyusak/go-auth/audit"
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/helper"
+	"github.com/michaelyusak/go-auth/notifier"
+	"github.com/michaelyusak/go-auth/repository"
+	"github.com/michaelyusak/go-helper/apperror"
+	"github.com/sirupsen/logrus"
+)
+
+const invitationTokenBytes = 32
+
+// organizationServiceImpl manages organizations on behalf of their members.
+// Owners and admins manage members and invitations, but only owners can make
+// or unmake owners, and an organization always keeps at least one owner.
+type organizationServiceImpl struct {
+	organizationRepo  repository.OrganizationRepository
+	transaction       repository.Transaction
+	tokenHasher       helper.TokenHasher
+	notifier          notifier.InvitationNotifier
+	log               *logrus.Logger
+	subRoutineTimeout time.Duration
+	invitationTtl     time.Duration
+	acceptUrl         string
+	auditRecorder     audit.Recorder
+}
+
+type OrganizationServiceOpt struct {
+	OrganizationRepo  repository.OrganizationRepository
+	Transaction       repository.Transaction
+	TokenHasher       helper.TokenHasher
+	Notifier          notifier.InvitationNotifier
+	Log               *logrus.Logger
+	SubRoutineTimeout time.Duration
+	InvitationTtl     time.Duration
+	AcceptUrl         string
+	AuditRecorder     audit.Recorder
+}
+
+func NewOrganizationService(opt OrganizationServiceOpt) *organizationServiceImpl {
+	return &organizationServiceImpl{
+		organizationRepo:  opt.OrganizationRepo,
+		transaction:       opt.Transaction,
+		tokenHasher:       opt.TokenHasher,
+		notifier:          opt.Notifier,
+		log:               opt.Log,
+		subRoutineTimeout: opt.SubRoutineTimeout,
+		invitationTtl:     opt.InvitationTtl,
+		acceptUrl:         opt.AcceptUrl,
+		auditRecorder:     opt.AuditRecorder,
+	}
+}
+
+// CreateOrganization creates an organization in the tenant of the request
+// with the caller as its owner.
+func (s *organizationServiceImpl) CreateOrganization(ctx context.Context, req entity.CreateOrganizationReq) (*entity.OrganizationRes, error) {
+	accountId := ctx.Value(constant.AccountIdCtxKey).(int64)
+
+	organization := entity.Organization{
+		TenantId:  requestTenant(ctx),
+		Name:      req.Name,
+		CreatedBy: accountId,
+	}
+
+	err := s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		var err error
+
+		organization.OrganizationId, err = repos.Organization.InsertOrganization(ctx, organization)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[organization_service][CreateOrganization][organizationRepo.InsertOrganization] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		err = repos.Organization.InsertMember(ctx, entity.OrganizationMember{
+			OrganizationId: organization.OrganizationId,
+			AccountId:      accountId,
+			Role:           constant.OrganizationRoleOwner,
+		})
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[organization_service][CreateOrganization][organizationRepo.InsertMember] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[organization_service][CreateOrganization]", authEvent(constant.AuthEventOrganizationCreated, accountId, 0, constant.AuthEventOutcomeSuccess, strconv.FormatInt(organization.OrganizationId, 10)))
+	})
+	if err != nil {
+		return nil, txError("[organization_service][CreateOrganization]", err)
+	}
+
+	return &entity.OrganizationRes{
+		OrganizationId: organization.OrganizationId,
+		Name:           organization.Name,
+		Role:           constant.OrganizationRoleOwner,
+		CreatedAt:      time.Now().UnixMilli(),
+	}, nil
+}
+
+// GetOrganizations lists the organizations the caller belongs to.
+func (s *organizationServiceImpl) GetOrganizations(ctx context.Context) ([]entity.OrganizationRes, error) {
+	accountId := ctx.Value(constant.AccountIdCtxKey).(int64)
+
+	memberships, err := s.organizationRepo.GetMembershipsByAccountId(ctx, accountId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[organization_service][GetOrganizations][organizationRepo.GetMembershipsByAccountId] Error: %s | account_id: %v", err.Error(), accountId),
+		})
+	}
+
+	res := make([]entity.OrganizationRes, 0, len(memberships))
+	for _, membership := range memberships {
+		res = append(res, organizationRes(membership))
+	}
+
+	return res, nil
+}
+
+// GetMembers lists the members of an organization the caller belongs to.
+func (s *organizationServiceImpl) GetMembers(ctx context.Context, organizationId int64) ([]entity.OrganizationMemberRes, error) {
+	accountId := ctx.Value(constant.AccountIdCtxKey).(int64)
+
+	_, _, err := callerMembership(ctx, s.organizationRepo, "[organization_service][GetMembers]", organizationId, accountId, false)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.organizationRepo.GetMembers(ctx, organizationId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[organization_service][GetMembers][organizationRepo.GetMembers] Error: %s | organization_id: %v", err.Error(), organizationId),
+		})
+	}
+
+	res := make([]entity.OrganizationMemberRes, 0, len(members))
+	for _, member := range members {
+		res = append(res, organizationMemberRes(member))
+	}
+
+	return res, nil
+}
+
+// UpdateMemberRole changes the role of a member. The organization is locked
+// so that two owners cannot demote each other at the same time.
+func (s *organizationServiceImpl) UpdateMemberRole(ctx context.Context, organizationId, memberAccountId int64, req entity.UpdateMemberRoleReq) (*entity.OrganizationMemberRes, error) {
+	accountId := ctx.Value(constant.AccountIdCtxKey).(int64)
+
+	var res *entity.OrganizationMemberRes
+
+	err := s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		_, caller, err := callerMembership(ctx, repos.Organization, "[organization_service][UpdateMemberRole]", organizationId, accountId, true)
+		if err != nil {
+			return err
+		}
+
+		member, err := getMember(ctx, repos.Organization, "[organization_service][UpdateMemberRole]", organizationId, memberAccountId)
+		if err != nil {
+			return err
+		}
+
+		if !canManageRole(caller.Role, member.Role) || !canManageRole(caller.Role, req.Role) {
+			return apperror.NewAppError(apperror.AppErrorOpt{
+				Code:            http.StatusForbidden,
+				Message:         fmt.Sprintf("[organization_service][UpdateMemberRole] role not manageable | organization_id: %v | account_id: %v | role: %s", organizationId, accountId, caller.Role),
+				ResponseMessage: constant.MsgForbidden,
+			})
+		}
+
+		if member.Role == constant.OrganizationRoleOwner && req.Role != constant.OrganizationRoleOwner {
+			err = ensureAnotherOwner(ctx, repos.Organization, "[organization_service][UpdateMemberRole]", organizationId)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = repos.Organization.UpdateMemberRole(ctx, organizationId, memberAccountId, req.Role)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[organization_service][UpdateMemberRole][organizationRepo.UpdateMemberRole] Error: %s | organization_id: %v | account_id: %v", err.Error(), organizationId, memberAccountId),
+			})
+		}
+
+		member.Role = req.Role
+		res = new(entity.OrganizationMemberRes)
+		*res = organizationMemberRes(*member)
+
+		return recordTx(ctx, s.auditRecorder, repos, "[organization_service][UpdateMemberRole]", memberEvent(constant.AuthEventOrganizationMemberUpdated, memberAccountId, accountId, organizationId))
+	})
+	if err != nil {
+		return nil, txError("[organization_service][UpdateMemberRole]", err)
+	}
+
+	return res, nil
+}
+
+// RemoveMember removes a member from the organization. Any member can remove
+// themselves, which is how an organization is left.
+func (s *organizationServiceImpl) RemoveMember(ctx context.Context, organizationId, memberAccountId int64) error {
+	accountId := ctx.Value(constant.AccountIdCtxKey).(int64)
+
+	err := s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		_, caller, err := callerMembership(ctx, repos.Organization, "[organization_service][RemoveMember]", organizationId, accountId, true)
+		if err != nil {
+			return err
+		}
+
+		member, err := getMember(ctx, repos.Organization, "[organization_service][RemoveMember]", organizationId, memberAccountId)
+		if err != nil {
+			return err
+		}
+
+		if memberAccountId != accountId && !canManageRole(caller.Role, member.Role) {
+			return apperror.NewAppError(apperror.AppErrorOpt{
+				Code:            http.StatusForbidden,
+				Message:         fmt.Sprintf("[organization_service][RemoveMember] role not manageable | organization_id: %v | account_id: %v | role: %s", organizationId, accountId, caller.Role),
+				ResponseMessage: constant.MsgForbidden,
+			})
+		}
+
+		if member.Role == constant.OrganizationRoleOwner {
+			err = ensureAnotherOwner(ctx, repos.Organization, "[organization_service][RemoveMember]", organizationId)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = repos.Organization.DeleteMember(ctx, organizationId, memberAccountId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[organization_service][RemoveMember][organizationRepo.DeleteMember] Error: %s | organization_id: %v | account_id: %v", err.Error(), organizationId, memberAccountId),
+			})
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[organization_service][RemoveMember]", memberEvent(constant.AuthEventOrganizationMemberRemoved, memberAccountId, accountId, organizationId))
+	})
+	if err != nil {
+		return txError("[organization_service][RemoveMember]", err)
+	}
+
+	return nil
+}
+
+// InviteMember sends an invitation to join the organization to an email.
+// Inviting the same email again replaces its earlier invitations.
+func (s *organizationServiceImpl) InviteMember(ctx context.Context, organizationId int64, req entity.InviteMemberReq) (*entity.OrganizationInvitationRes, error) {
+	accountId := ctx.Value(constant.AccountIdCtxKey).(int64)
+	email := strings.ToLower(req.Email)
+
+	invitationToken, err := helper.GenerateOpaqueToken(invitationTokenBytes)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[organization_service][InviteMember][helper.GenerateOpaqueToken] Error: %s | organization_id: %v", err.Error(), organizationId),
+		})
+	}
+
+	var (
+		invitation entity.OrganizationInvitation
+		event      entity.InvitationEvent
+	)
+
+	err = s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		organization, caller, err := callerMembership(ctx, repos.Organization, "[organization_service][InviteMember]", organizationId, accountId, true)
+		if err != nil {
+			return err
+		}
+
+		if !canManageRole(caller.Role, req.Role) {
+			return apperror.NewAppError(apperror.AppErrorOpt{
+				Code:            http.StatusForbidden,
+				Message:         fmt.Sprintf("[organization_service][InviteMember] role not manageable | organization_id: %v | account_id: %v | role: %s", organizationId, accountId, caller.Role),
+				ResponseMessage: constant.MsgForbidden,
+			})
+		}
+
+		invitee, err := repos.Account.GetAccountByEmail(ctx, organization.TenantId, email)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[organization_service][InviteMember][accountRepo.GetAccountByEmail] Error: %s | organization_id: %v", err.Error(), organizationId),
+			})
+		}
+
+		if invitee != nil {
+			member, err := repos.Organization.GetMember(ctx, organizationId, invitee.Id)
+			if err != nil {
+				return apperror.InternalServerError(apperror.AppErrorOpt{
+					Message: fmt.Sprintf("[organization_service][InviteMember][organizationRepo.GetMember] Error: %s | organization_id: %v | account_id: %v", err.Error(), organizationId, invitee.Id),
+				})
+			}
+
+			if member != nil {
+				return apperror.BadRequestError(apperror.AppErrorOpt{
+					Message:         fmt.Sprintf("[organization_service][InviteMember] already a member | organization_id: %v | account_id: %v", organizationId, invitee.Id),
+					ResponseMessage: constant.MsgAlreadyMember,
+				})
+			}
+		}
+
+		err = repos.Organization.RevokeInvitationsByEmail(ctx, organizationId, email)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[organization_service][InviteMember][organizationRepo.RevokeInvitationsByEmail] Error: %s | organization_id: %v", err.Error(), organizationId),
+			})
+		}
+
+		now := time.Now()
+
+		invitation = entity.OrganizationInvitation{
+			OrganizationId: organizationId,
+			Email:          email,
+			Role:           req.Role,
+			TokenHash:      s.tokenHasher.HashToken(invitationToken),
+			InvitedBy:      accountId,
+			ExpiredAt:      now.Add(s.invitationTtl).UnixMilli(),
+			CreatedAt:      now.UnixMilli(),
+		}
+
+		invitation.InvitationId, err = repos.Organization.InsertInvitation(ctx, invitation)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[organization_service][InviteMember][organizationRepo.InsertInvitation] Error: %s | organization_id: %v", err.Error(), organizationId),
+			})
+		}
+
+		event = entity.InvitationEvent{
+			OrganizationId:   organizationId,
+			OrganizationName: organization.Name,
+			InviterName:      caller.Name,
+			Email:            email,
+			Role:             req.Role,
+			AcceptUrl:        fmt.Sprintf("%s?token=%s", s.acceptUrl, url.QueryEscape(invitationToken)),
+			ExpiredAt:        invitation.ExpiredAt,
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[organization_service][InviteMember]", authEvent(constant.AuthEventOrganizationInvited, accountId, 0, constant.AuthEventOutcomeSuccess, strconv.FormatInt(organizationId, 10)))
+	})
+	if err != nil {
+		return nil, txError("[organization_service][InviteMember]", err)
+	}
+
+	s.notifyInvitation(event)
+
+	res := invitationRes(invitation)
+
+	return &res, nil
+}
+
+// GetInvitations lists the invitations of the organization that can still
+// be accepted.
+func (s *organizationServiceImpl) GetInvitations(ctx context.Context, organizationId int64) ([]entity.OrganizationInvitationRes, error) {
+	accountId := ctx.Value(constant.AccountIdCtxKey).(int64)
+
+	_, caller, err := callerMembership(ctx, s.organizationRepo, "[organization_service][GetInvitations]", organizationId, accountId, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canManageMembers(caller.Role) {
+		return nil, apperror.NewAppError(apperror.AppErrorOpt{
+			Code:            http.StatusForbidden,
+			Message:         fmt.Sprintf("[organization_service][GetInvitations] role not allowed | organization_id: %v | account_id: %v | role: %s", organizationId, accountId, caller.Role),
+			ResponseMessage: constant.MsgForbidden,
+		})
+	}
+
+	invitations, err := s.organizationRepo.GetPendingInvitations(ctx, organizationId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[organization_service][GetInvitations][organizationRepo.GetPendingInvitations] Error: %s | organization_id: %v", err.Error(), organizationId),
+		})
+	}
+
+	res := make([]entity.OrganizationInvitationRes, 0, len(invitations))
+	for _, invitation := range invitations {
+		res = append(res, invitationRes(invitation))
+	}
+
+	return res, nil
+}
+
+func (s *organizationServiceImpl) RevokeInvitation(ctx context.Context, organizationId, invitationId int64) error {
+	accountId := ctx.Value(constant.AccountIdCtxKey).(int64)
+
+	_, caller, err := callerMembership(ctx, s.organizationRepo, "[organization_service][RevokeInvitation]", organizationId, accountId, false)
+	if err != nil {
+		return err
+	}
+
+	if !canManageMembers(caller.Role) {
+		return apperror.NewAppError(apperror.AppErrorOpt{
+			Code:            http.StatusForbidden,
+			Message:         fmt.Sprintf("[organization_service][RevokeInvitation] role not allowed | organization_id: %v | account_id: %v | role: %s", organizationId, accountId, caller.Role),
+			ResponseMessage: constant.MsgForbidden,
+		})
+	}
+
+	invitation, err := s.organizationRepo.GetInvitationById(ctx, invitationId)
+	if err != nil {
+		return apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[organization_service][RevokeInvitation][organizationRepo.GetInvitationById] Error: %s | invitation_id: %v", err.Error(), invitationId),
+		})
+	}
+
+	if invitation == nil || invitation.OrganizationId != organizationId {
+		return apperror.NewAppError(apperror.AppErrorOpt{
+			Code:            http.StatusNotFound,
+			Message:         fmt.Sprintf("[organization_service][RevokeInvitation] invitation not found | organization_id: %v | invitation_id: %v", organizationId, invitationId),
+			ResponseMessage: constant.MsgInvitationNotFound,
+		})
+	}
+
+	err = s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		revoked, err := repos.Organization.RevokeInvitation(ctx, invitationId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[organization_service][RevokeInvitation][organizationRepo.RevokeInvitation] Error: %s | invitation_id: %v", err.Error(), invitationId),
+			})
+		}
+
+		if !revoked {
+			return apperror.NewAppError(apperror.AppErrorOpt{
+				Code:            http.StatusNotFound,
+				Message:         fmt.Sprintf("[organization_service][RevokeInvitation] invitation already accepted or revoked | organization_id: %v | invitation_id: %v", organizationId, invitationId),
+				ResponseMessage: constant.MsgInvitationNotFound,
+			})
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[organization_service][RevokeInvitation]", authEvent(constant.AuthEventOrganizationInviteRevoked, accountId, 0, constant.AuthEventOutcomeSuccess, strconv.FormatInt(organizationId, 10)))
+	})
+	if err != nil {
+		return txError("[organization_service][RevokeInvitation]", err)
+	}
+
+	return nil
+}
+
+// AcceptInvitation adds the caller to the organization of the invitation.
+// An account that does not exist yet accepts by registering with the token
+// instead.
+func (s *organizationServiceImpl) AcceptInvitation(ctx context.Context, req entity.AcceptInvitationReq) (*entity.OrganizationRes, error) {
+	accountId := ctx.Value(constant.AccountIdCtxKey).(int64)
+
+	var membership *entity.OrganizationMembership
+
+	err := s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		account, err := repos.Account.GetAccountById(ctx, accountId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[organization_service][AcceptInvitation][accountRepo.GetAccountById] Error: %s | account_id: %v", err.Error(), accountId),
+			})
+		}
+
+		if account == nil {
+			return apperror.NewAppError(apperror.AppErrorOpt{
+				Code:            http.StatusNotFound,
+				Message:         fmt.Sprintf("[organization_service][AcceptInvitation] account not found | account_id: %v", accountId),
+				ResponseMessage: constant.MsgAccountNotFound,
+			})
+		}
+
+		membership, err = acceptInvitation(ctx, repos, "[organization_service][AcceptInvitation]", s.tokenHasher.HashToken(req.Token), *account)
+		if err != nil {
+			return err
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[organization_service][AcceptInvitation]", memberEvent(constant.AuthEventOrganizationMemberAdded, accountId, accountId, membership.Organization.OrganizationId))
+	})
+	if err != nil {
+		return nil, txError("[organization_service][AcceptInvitation]", err)
+	}
+
+	res := organizationRes(*membership)
+
+	return &res, nil
+}
+
+// notifyInvitation sends the accept link in the background; a failed
+// notification does not fail the request, and the email can be invited
+// again.
+func (s *organizationServiceImpl) notifyInvitation(event entity.InvitationEvent) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), s.subRoutineTimeout)
+		defer cancel()
+
+		err := s.notifier.NotifyInvitation(ctx, event)
+		if err != nil {
+			s.log.WithFields(logrus.Fields{
+				"error":           err.Error(),
+				"organization_id": event.OrganizationId,
+			}).Error("[organization_service][notifyInvitation][notifier.NotifyInvitation][sub-routine]")
+		}
+	}()
+}
+
+// acceptInvitation makes the account a member of the organization it was
+// invited to. The invitation must be open, addressed to the account's email
+// and for an organization in the account's tenant.
+func acceptInvitation(ctx context.Context, repos repository.TxRepositories, caller, tokenHash string, account entity.Account) (*entity.OrganizationMembership, error) {
+	invitation, err := repos.Organization.GetInvitationByTokenHash(ctx, tokenHash)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("%s[organizationRepo.GetInvitationByTokenHash] Error: %s | account_id: %v", caller, err.Error(), account.Id),
+		})
+	}
+
+	if invitation == nil || invitation.AcceptedAt != nil || invitation.RevokedAt != nil || invitation.ExpiredAt <= time.Now().UnixMilli() {
+		return nil, apperror.BadRequestError(apperror.AppErrorOpt{
+			Message:         fmt.Sprintf("%s invitation not open | account_id: %v", caller, account.Id),
+			ResponseMessage: constant.MsgInvalidInvitation,
+		})
+	}
+
+	organization, err := repos.Organization.GetOrganizationForUpdate(ctx, invitation.OrganizationId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("%s[organizationRepo.GetOrganizationForUpdate] Error: %s | organization_id: %v", caller, err.Error(), invitation.OrganizationId),
+		})
+	}
+
+	if organization == nil || organization.TenantId != account.TenantId || !strings.EqualFold(invitation.Email, account.Email) {
+		return nil, apperror.BadRequestError(apperror.AppErrorOpt{
+			Message:         fmt.Sprintf("%s invitation not for account | account_id: %v | invitation_id: %v", caller, account.Id, invitation.InvitationId),
+			ResponseMessage: constant.MsgInvalidInvitation,
+		})
+	}
+
+	accepted, err := repos.Organization.AcceptInvitation(ctx, invitation.InvitationId, account.Id)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("%s[organizationRepo.AcceptInvitation] Error: %s | account_id: %v | invitation_id: %v", caller, err.Error(), account.Id, invitation.InvitationId),
+		})
+	}
+
+	if !accepted {
+		return nil, apperror.BadRequestError(apperror.AppErrorOpt{
+			Message:         fmt.Sprintf("%s invitation accepted concurrently | account_id: %v | invitation_id: %v", caller, account.Id, invitation.InvitationId),
+			ResponseMessage: constant.MsgInvalidInvitation,
+		})
+	}
+
+	err = repos.Organization.InsertMember(ctx, entity.OrganizationMember{
+		OrganizationId: organization.OrganizationId,
+		AccountId:      account.Id,
+		Role:           invitation.Role,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrMemberAlreadyExists) {
+			return nil, apperror.BadRequestError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("%s already a member | account_id: %v | organization_id: %v", caller, account.Id, organization.OrganizationId),
+				ResponseMessage: constant.MsgAlreadyMember,
+			})
+		}
+
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("%s[organizationRepo.InsertMember] Error: %s | account_id: %v | organization_id: %v", caller, err.Error(), account.Id, organization.OrganizationId),
+		})
+	}
+
+	return &entity.OrganizationMembership{
+		Organization: *organization,
+		Role:         invitation.Role,
+	}, nil
+}
+
+// callerMembership loads an organization of the request's tenant and the
+// caller's membership of it, locking the organization when lock is set.
+func callerMembership(ctx context.Context, organizationRepo repository.OrganizationRepository, caller string, organizationId, accountId int64, lock bool) (*entity.Organization, *entity.OrganizationMember, error) {
+	getOrganization := organizationRepo.GetOrganizationById
+	if lock {
+		getOrganization = organizationRepo.GetOrganizationForUpdate
+	}
+
+	organization, err := getOrganization(ctx, organizationId)
+	if err != nil {
+		return nil, nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("%s[organizationRepo.GetOrganization] Error: %s | organization_id: %v", caller, err.Error(), organizationId),
+		})
+	}
+
+	if organization == nil || organization.TenantId != requestTenant(ctx) {
+		return nil, nil, apperror.NewAppError(apperror.AppErrorOpt{
+			Code:            http.StatusNotFound,
+			Message:         fmt.Sprintf("%s organization not found | organization_id: %v", caller, organizationId),
+			ResponseMessage: constant.MsgOrganizationNotFound,
+		})
+	}
+
+	member, err := organizationRepo.GetMember(ctx, organizationId, accountId)
+	if err != nil {
+		return nil, nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("%s[organizationRepo.GetMember] Error: %s | organization_id: %v | account_id: %v", caller, err.Error(), organizationId, accountId),
+		})
+	}
+
+	if member == nil {
+		return nil, nil, apperror.NewAppError(apperror.AppErrorOpt{
+			Code:            http.StatusForbidden,
+			Message:         fmt.Sprintf("%s not a member | organization_id: %v | account_id: %v", caller, organizationId, accountId),
+			ResponseMessage: constant.MsgNotOrganizationMember,
+		})
+	}
+
+	return organization, member, nil
+}
+
+func getMember(ctx context.Context, organizationRepo repository.OrganizationRepository, caller string, organizationId, accountId int64) (*entity.OrganizationMember, error) {
+	member, err := organizationRepo.GetMember(ctx, organizationId, accountId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("%s[organizationRepo.GetMember] Error: %s | organization_id: %v | account_id: %v", caller, err.Error(), organizationId, accountId),
+		})
+	}
+
+	if member == nil {
+		return nil, apperror.NewAppError(apperror.AppErrorOpt{
+			Code:            http.StatusNotFound,
+			Message:         fmt.Sprintf("%s member not found | organization_id: %v | account_id: %v", caller, organizationId, accountId),
+			ResponseMessage: constant.MsgMemberNotFound,
+		})
+	}
+
+	return member, nil
+}
+
+// ensureAnotherOwner refuses to take away an owner when it is the
+// organization's last one. The organization must be locked.
+func ensureAnotherOwner(ctx context.Context, organizationRepo repository.OrganizationRepository, caller string, organizationId int64) error {
+	owners, err := organizationRepo.CountOwners(ctx, organizationId)
+	if err != nil {
+		return apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("%s[organizationRepo.CountOwners] Error: %s | organization_id: %v", caller, err.Error(), organizationId),
+		})
+	}
+
+	if owners <= 1 {
+		return apperror.BadRequestError(apperror.AppErrorOpt{
+			Message:         fmt.Sprintf("%s last owner | organization_id: %v", caller, organizationId),
+			ResponseMessage: constant.MsgLastOwner,
+		})
+	}
+
+	return nil
+}
+
+func canManageMembers(role string) bool {
+	return role == constant.OrganizationRoleOwner || role == constant.OrganizationRoleAdmin
+}
+
+// canManageRole reports whether a member with the given role may grant,
+// take away or remove the other role. Only owners manage owners.
+func canManageRole(role, otherRole string) bool {
+	if role == constant.OrganizationRoleOwner {
+		return true
+	}
+
+	return canManageMembers(role) && otherRole != constant.OrganizationRoleOwner
+}
+
+// memberEvent builds the audit event of a change to a membership, recording
+// who made it when it is not the member themselves.
+func memberEvent(eventType string, accountId, actorAccountId, organizationId int64) entity.AuthEvent {
+	event := authEvent(eventType, accountId, 0, constant.AuthEventOutcomeSuccess, strconv.FormatInt(organizationId, 10))
+
+	if actorAccountId != accountId {
+		event.ActorAccountId = &actorAccountId
+	}
+
+	return event
+}
+
+func organizationRes(membership entity.OrganizationMembership) entity.OrganizationRes {
+	return entity.OrganizationRes{
+		OrganizationId: membership.Organization.OrganizationId,
+		Name:           membership.Organization.Name,
+		Role:           membership.Role,
+		CreatedAt:      membership.Organization.CreatedAt,
+	}
+}
+
+func organizationMemberRes(member entity.OrganizationMember) entity.OrganizationMemberRes {
+	return entity.OrganizationMemberRes{
+		AccountId: member.AccountId,
+		Name:      member.Name,
+		Email:     member.Email,
+		Role:      member.Role,
+		JoinedAt:  member.CreatedAt,
+	}
+}
+
+func invitationRes(invitation entity.OrganizationInvitation) entity.OrganizationInvitationRes {
+	return entity.OrganizationInvitationRes{
+		InvitationId:   invitation.InvitationId,
+		OrganizationId: invitation.OrganizationId,
+		Email:          invitation.Email,
+		Role:           invitation.Role,
+		InvitedBy:      invitation.InvitedBy,
+		ExpiredAt:      invitation.ExpiredAt,
+		CreatedAt:      invitation.CreatedAt,
+	}
+}
@@ -0,0 +1,542 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/michaelyusak/go-auth/audit"
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/entity"
+	"github.com/michaelyusak/go-auth/repository"
+	"github.com/michaelyusak/go-helper/apperror"
+)
+
+// roleServiceImpl manages the roles and permissions of the request's tenant.
+// The builtin ones are shared by every tenant and cannot be changed. Changes
+// reach an account's access tokens when they are next issued, on login or
+// refresh.
+type roleServiceImpl struct {
+	roleRepo      repository.RoleRepository
+	accountRepo   repository.AccountRepository
+	transaction   repository.Transaction
+	auditRecorder audit.Recorder
+}
+
+type RoleServiceOpt struct {
+	RoleRepo      repository.RoleRepository
+	AccountRepo   repository.AccountRepository
+	Transaction   repository.Transaction
+	AuditRecorder audit.Recorder
+}
+
+func NewRoleService(opt RoleServiceOpt) *roleServiceImpl {
+	return &roleServiceImpl{
+		roleRepo:      opt.RoleRepo,
+		accountRepo:   opt.AccountRepo,
+		transaction:   opt.Transaction,
+		auditRecorder: opt.AuditRecorder,
+	}
+}
+
+func (s *roleServiceImpl) GetRoles(ctx context.Context) ([]entity.RoleRes, error) {
+	roles, err := s.roleRepo.GetRoles(ctx, requestTenant(ctx))
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[role_service][GetRoles][roleRepo.GetRoles] Error: %s", err.Error()),
+		})
+	}
+
+	return s.roleResList(ctx, "[role_service][GetRoles]", roles)
+}
+
+func (s *roleServiceImpl) CreateRole(ctx context.Context, req entity.CreateRoleReq) (*entity.RoleRes, error) {
+	permissionNames := slices.Compact(slices.Sorted(slices.Values(req.Permissions)))
+
+	var res *entity.RoleRes
+
+	err := s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		permissionIds, err := permissionIdsByName(ctx, repos.Role, "[role_service][CreateRole]", permissionNames)
+		if err != nil {
+			return err
+		}
+
+		role := entity.Role{
+			TenantId:    requestTenant(ctx),
+			Name:        req.Name,
+			Description: req.Description,
+		}
+
+		// The unique index only covers the tenant's own roles, not the
+		// builtin ones.
+		existing, err := repos.Role.GetRoleByName(ctx, role.TenantId, role.Name)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[role_service][CreateRole][roleRepo.GetRoleByName] Error: %s | name: %s", err.Error(), req.Name),
+			})
+		}
+
+		if existing != nil {
+			return apperror.BadRequestError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("[role_service][CreateRole] role name taken | name: %s", req.Name),
+				ResponseMessage: constant.MsgRoleNameTaken,
+			})
+		}
+
+		role.RoleId, err = repos.Role.InsertRole(ctx, role)
+		if err != nil {
+			if errors.Is(err, repository.ErrRoleNameAlreadyExists) {
+				return apperror.BadRequestError(apperror.AppErrorOpt{
+					Message:         fmt.Sprintf("[role_service][CreateRole] role name taken | name: %s", req.Name),
+					ResponseMessage: constant.MsgRoleNameTaken,
+				})
+			}
+
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[role_service][CreateRole][roleRepo.InsertRole] Error: %s | name: %s", err.Error(), req.Name),
+			})
+		}
+
+		err = repos.Role.SetRolePermissions(ctx, role.RoleId, permissionIds)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[role_service][CreateRole][roleRepo.SetRolePermissions] Error: %s | role_id: %v", err.Error(), role.RoleId),
+			})
+		}
+
+		now := time.Now().UnixMilli()
+
+		role.CreatedAt = now
+		role.UpdatedAt = now
+		res = roleRes(role, permissionNames)
+
+		return recordTx(ctx, s.auditRecorder, repos, "[role_service][CreateRole]", authEvent(constant.AuthEventRoleCreated, 0, 0, constant.AuthEventOutcomeSuccess, role.Name))
+	})
+	if err != nil {
+		return nil, txError("[role_service][CreateRole]", err)
+	}
+
+	return res, nil
+}
+
+// UpdateRole replaces the description and permissions of a role that is not
+// built in.
+func (s *roleServiceImpl) UpdateRole(ctx context.Context, roleId int64, req entity.UpdateRoleReq) (*entity.RoleRes, error) {
+	permissionNames := slices.Compact(slices.Sorted(slices.Values(req.Permissions)))
+
+	var res *entity.RoleRes
+
+	err := s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		role, err := getEditableRole(ctx, repos.Role, "[role_service][UpdateRole]", roleId)
+		if err != nil {
+			return err
+		}
+
+		permissionIds, err := permissionIdsByName(ctx, repos.Role, "[role_service][UpdateRole]", permissionNames)
+		if err != nil {
+			return err
+		}
+
+		role.Description = req.Description
+
+		err = repos.Role.UpdateRole(ctx, *role)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[role_service][UpdateRole][roleRepo.UpdateRole] Error: %s | role_id: %v", err.Error(), roleId),
+			})
+		}
+
+		err = repos.Role.SetRolePermissions(ctx, roleId, permissionIds)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[role_service][UpdateRole][roleRepo.SetRolePermissions] Error: %s | role_id: %v", err.Error(), roleId),
+			})
+		}
+
+		role.UpdatedAt = time.Now().UnixMilli()
+		res = roleRes(*role, permissionNames)
+
+		return recordTx(ctx, s.auditRecorder, repos, "[role_service][UpdateRole]", authEvent(constant.AuthEventRoleUpdated, 0, 0, constant.AuthEventOutcomeSuccess, role.Name))
+	})
+	if err != nil {
+		return nil, txError("[role_service][UpdateRole]", err)
+	}
+
+	return res, nil
+}
+
+// DeleteRole deletes a role that is not built in, taking it away from every
+// account holding it.
+func (s *roleServiceImpl) DeleteRole(ctx context.Context, roleId int64) error {
+	err := s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		role, err := getEditableRole(ctx, repos.Role, "[role_service][DeleteRole]", roleId)
+		if err != nil {
+			return err
+		}
+
+		err = repos.Role.DeleteRole(ctx, roleId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[role_service][DeleteRole][roleRepo.DeleteRole] Error: %s | role_id: %v", err.Error(), roleId),
+			})
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[role_service][DeleteRole]", authEvent(constant.AuthEventRoleDeleted, 0, 0, constant.AuthEventOutcomeSuccess, role.Name))
+	})
+	if err != nil {
+		return txError("[role_service][DeleteRole]", err)
+	}
+
+	return nil
+}
+
+func (s *roleServiceImpl) GetPermissions(ctx context.Context) ([]entity.PermissionRes, error) {
+	permissions, err := s.roleRepo.GetPermissions(ctx, requestTenant(ctx))
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[role_service][GetPermissions][roleRepo.GetPermissions] Error: %s", err.Error()),
+		})
+	}
+
+	res := make([]entity.PermissionRes, 0, len(permissions))
+
+	for _, permission := range permissions {
+		res = append(res, permissionRes(permission))
+	}
+
+	return res, nil
+}
+
+// CreatePermission adds a permission for roles to grant. Its name ends up
+// in the space separated scope claim, so it cannot contain whitespace.
+func (s *roleServiceImpl) CreatePermission(ctx context.Context, req entity.CreatePermissionReq) (*entity.PermissionRes, error) {
+	if strings.ContainsAny(req.Name, " \t\r\n") {
+		return nil, apperror.BadRequestError(apperror.AppErrorOpt{
+			Message:         fmt.Sprintf("[role_service][CreatePermission] invalid permission name | name: %q", req.Name),
+			ResponseMessage: constant.MsgInvalidPermissionName,
+		})
+	}
+
+	permission := entity.Permission{
+		TenantId:    requestTenant(ctx),
+		Name:        req.Name,
+		Description: req.Description,
+	}
+
+	// The unique index only covers the tenant's own permissions, not the
+	// builtin ones.
+	permissions, err := s.roleRepo.GetPermissions(ctx, permission.TenantId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[role_service][CreatePermission][roleRepo.GetPermissions] Error: %s | name: %s", err.Error(), req.Name),
+		})
+	}
+
+	if slices.ContainsFunc(permissions, func(p entity.Permission) bool { return p.Name == req.Name }) {
+		return nil, apperror.BadRequestError(apperror.AppErrorOpt{
+			Message:         fmt.Sprintf("[role_service][CreatePermission] permission name taken | name: %s", req.Name),
+			ResponseMessage: constant.MsgPermissionNameTaken,
+		})
+	}
+
+	err = s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		var err error
+
+		permission.PermissionId, err = repos.Role.InsertPermission(ctx, permission)
+		if err != nil {
+			if errors.Is(err, repository.ErrPermissionNameAlreadyExists) {
+				return apperror.BadRequestError(apperror.AppErrorOpt{
+					Message:         fmt.Sprintf("[role_service][CreatePermission] permission name taken | name: %s", req.Name),
+					ResponseMessage: constant.MsgPermissionNameTaken,
+				})
+			}
+
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[role_service][CreatePermission][roleRepo.InsertPermission] Error: %s | name: %s", err.Error(), req.Name),
+			})
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[role_service][CreatePermission]", authEvent(constant.AuthEventPermissionCreated, 0, 0, constant.AuthEventOutcomeSuccess, permission.Name))
+	})
+	if err != nil {
+		return nil, txError("[role_service][CreatePermission]", err)
+	}
+
+	now := time.Now().UnixMilli()
+
+	permission.CreatedAt = now
+	permission.UpdatedAt = now
+
+	res := permissionRes(permission)
+
+	return &res, nil
+}
+
+// DeletePermission deletes a permission that is not built in, taking it
+// away from every role granting it.
+func (s *roleServiceImpl) DeletePermission(ctx context.Context, permissionId int64) error {
+	permission, err := s.roleRepo.GetPermissionById(ctx, permissionId)
+	if err != nil {
+		return apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[role_service][DeletePermission][roleRepo.GetPermissionById] Error: %s | permission_id: %v", err.Error(), permissionId),
+		})
+	}
+
+	if permission == nil || (!permission.IsBuiltin && permission.TenantId != requestTenant(ctx)) {
+		return apperror.NewAppError(apperror.AppErrorOpt{
+			Code:            http.StatusNotFound,
+			Message:         fmt.Sprintf("[role_service][DeletePermission] permission not found | permission_id: %v", permissionId),
+			ResponseMessage: constant.MsgPermissionNotFound,
+		})
+	}
+
+	if permission.IsBuiltin {
+		return apperror.BadRequestError(apperror.AppErrorOpt{
+			Message:         fmt.Sprintf("[role_service][DeletePermission] built-in permission | permission_id: %v", permissionId),
+			ResponseMessage: constant.MsgBuiltinPermission,
+		})
+	}
+
+	err = s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		err := repos.Role.DeletePermission(ctx, permissionId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[role_service][DeletePermission][roleRepo.DeletePermission] Error: %s | permission_id: %v", err.Error(), permissionId),
+			})
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[role_service][DeletePermission]", authEvent(constant.AuthEventPermissionDeleted, 0, 0, constant.AuthEventOutcomeSuccess, permission.Name))
+	})
+	if err != nil {
+		return txError("[role_service][DeletePermission]", err)
+	}
+
+	return nil
+}
+
+func (s *roleServiceImpl) GetAccountRoles(ctx context.Context, accountId int64) ([]entity.RoleRes, error) {
+	account, err := s.accountRepo.GetAccountById(ctx, accountId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[role_service][GetAccountRoles][accountRepo.GetAccountById] Error: %s | account_id: %v", err.Error(), accountId),
+		})
+	}
+
+	if !inRequestTenant(ctx, account) {
+		return nil, apperror.NewAppError(apperror.AppErrorOpt{
+			Code:            http.StatusNotFound,
+			Message:         fmt.Sprintf("[role_service][GetAccountRoles] account not found | account_id: %v", accountId),
+			ResponseMessage: constant.MsgAccountNotFound,
+		})
+	}
+
+	roles, err := s.roleRepo.GetAccountRoles(ctx, accountId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("[role_service][GetAccountRoles][roleRepo.GetAccountRoles] Error: %s | account_id: %v", err.Error(), accountId),
+		})
+	}
+
+	return s.roleResList(ctx, "[role_service][GetAccountRoles]", roles)
+}
+
+func (s *roleServiceImpl) AssignRole(ctx context.Context, accountId, roleId int64) error {
+	err := s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		role, err := getAccountAndRole(ctx, repos, "[role_service][AssignRole]", accountId, roleId)
+		if err != nil {
+			return err
+		}
+
+		err = repos.Role.AssignRole(ctx, accountId, roleId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[role_service][AssignRole][roleRepo.AssignRole] Error: %s | account_id: %v | role_id: %v", err.Error(), accountId, roleId),
+			})
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[role_service][AssignRole]", authEvent(constant.AuthEventRoleAssigned, accountId, 0, constant.AuthEventOutcomeSuccess, role.Name))
+	})
+	if err != nil {
+		return txError("[role_service][AssignRole]", err)
+	}
+
+	return nil
+}
+
+func (s *roleServiceImpl) UnassignRole(ctx context.Context, accountId, roleId int64) error {
+	err := s.transaction.WithinTx(ctx, func(repos repository.TxRepositories) error {
+		role, err := getAccountAndRole(ctx, repos, "[role_service][UnassignRole]", accountId, roleId)
+		if err != nil {
+			return err
+		}
+
+		removed, err := repos.Role.UnassignRole(ctx, accountId, roleId)
+		if err != nil {
+			return apperror.InternalServerError(apperror.AppErrorOpt{
+				Message: fmt.Sprintf("[role_service][UnassignRole][roleRepo.UnassignRole] Error: %s | account_id: %v | role_id: %v", err.Error(), accountId, roleId),
+			})
+		}
+
+		if !removed {
+			return nil
+		}
+
+		return recordTx(ctx, s.auditRecorder, repos, "[role_service][UnassignRole]", authEvent(constant.AuthEventRoleUnassigned, accountId, 0, constant.AuthEventOutcomeSuccess, role.Name))
+	})
+	if err != nil {
+		return txError("[role_service][UnassignRole]", err)
+	}
+
+	return nil
+}
+
+func (s *roleServiceImpl) roleResList(ctx context.Context, caller string, roles []entity.Role) ([]entity.RoleRes, error) {
+	permissionNames, err := s.roleRepo.GetRolePermissionNames(ctx, requestTenant(ctx))
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("%s[roleRepo.GetRolePermissionNames] Error: %s", caller, err.Error()),
+		})
+	}
+
+	res := make([]entity.RoleRes, 0, len(roles))
+
+	for _, role := range roles {
+		res = append(res, *roleRes(role, permissionNames[role.RoleId]))
+	}
+
+	return res, nil
+}
+
+// permissionIdsByName resolves permission names, failing on any that does
+// not exist in the request's tenant.
+func permissionIdsByName(ctx context.Context, roleRepo repository.RoleRepository, caller string, names []string) ([]int64, error) {
+	permissions, err := roleRepo.GetPermissions(ctx, requestTenant(ctx))
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("%s[roleRepo.GetPermissions] Error: %s", caller, err.Error()),
+		})
+	}
+
+	ids := map[string]int64{}
+
+	for _, permission := range permissions {
+		ids[permission.Name] = permission.PermissionId
+	}
+
+	permissionIds := make([]int64, 0, len(names))
+
+	for _, name := range names {
+		permissionId, ok := ids[name]
+		if !ok {
+			return nil, apperror.BadRequestError(apperror.AppErrorOpt{
+				Message:         fmt.Sprintf("%s unknown permission | name: %s", caller, name),
+				ResponseMessage: constant.MsgUnknownPermission,
+			})
+		}
+
+		permissionIds = append(permissionIds, permissionId)
+	}
+
+	return permissionIds, nil
+}
+
+// getEditableRole returns the role, failing when it does not exist in the
+// request's tenant or is built in.
+func getEditableRole(ctx context.Context, roleRepo repository.RoleRepository, caller string, roleId int64) (*entity.Role, error) {
+	role, err := roleRepo.GetRoleById(ctx, roleId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("%s[roleRepo.GetRoleById] Error: %s | role_id: %v", caller, err.Error(), roleId),
+		})
+	}
+
+	if !roleInRequestTenant(ctx, role) {
+		return nil, apperror.NewAppError(apperror.AppErrorOpt{
+			Code:            http.StatusNotFound,
+			Message:         fmt.Sprintf("%s role not found | role_id: %v", caller, roleId),
+			ResponseMessage: constant.MsgRoleNotFound,
+		})
+	}
+
+	if role.IsBuiltin {
+		return nil, apperror.BadRequestError(apperror.AppErrorOpt{
+			Message:         fmt.Sprintf("%s built-in role | role_id: %v", caller, roleId),
+			ResponseMessage: constant.MsgBuiltinRole,
+		})
+	}
+
+	return role, nil
+}
+
+// getAccountAndRole fails unless both the account and the role exist in the
+// request's tenant, and returns the role.
+func getAccountAndRole(ctx context.Context, repos repository.TxRepositories, caller string, accountId, roleId int64) (*entity.Role, error) {
+	account, err := repos.Account.GetAccountById(ctx, accountId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("%s[accountRepo.GetAccountById] Error: %s | account_id: %v", caller, err.Error(), accountId),
+		})
+	}
+
+	if !inRequestTenant(ctx, account) {
+		return nil, apperror.NewAppError(apperror.AppErrorOpt{
+			Code:            http.StatusNotFound,
+			Message:         fmt.Sprintf("%s account not found | account_id: %v", caller, accountId),
+			ResponseMessage: constant.MsgAccountNotFound,
+		})
+	}
+
+	role, err := repos.Role.GetRoleById(ctx, roleId)
+	if err != nil {
+		return nil, apperror.InternalServerError(apperror.AppErrorOpt{
+			Message: fmt.Sprintf("%s[roleRepo.GetRoleById] Error: %s | role_id: %v", caller, err.Error(), roleId),
+		})
+	}
+
+	if !roleInRequestTenant(ctx, role) {
+		return nil, apperror.NewAppError(apperror.AppErrorOpt{
+			Code:            http.StatusNotFound,
+			Message:         fmt.Sprintf("%s role not found | role_id: %v", caller, roleId),
+			ResponseMessage: constant.MsgRoleNotFound,
+		})
+	}
+
+	return role, nil
+}
+
+// roleInRequestTenant tells whether the role exists and is builtin or
+// belongs to the tenant the request is made for.
+func roleInRequestTenant(ctx context.Context, role *entity.Role) bool {
+	return role != nil && (role.IsBuiltin || role.TenantId == requestTenant(ctx))
+}
+
+func roleRes(role entity.Role, permissions []string) *entity.RoleRes {
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	return &entity.RoleRes{
+		RoleId:      role.RoleId,
+		Name:        role.Name,
+		Description: role.Description,
+		IsBuiltin:   role.IsBuiltin,
+		Permissions: permissions,
+		CreatedAt:   role.CreatedAt,
+		UpdatedAt:   role.UpdatedAt,
+	}
+}
+
+func permissionRes(permission entity.Permission) entity.PermissionRes {
+	return entity.PermissionRes{
+		PermissionId: permission.PermissionId,
+		Name:         permission.Name,
+		Description:  permission.Description,
+		IsBuiltin:    permission.IsBuiltin,
+		CreatedAt:    permission.CreatedAt,
+		UpdatedAt:    permission.UpdatedAt,
+	}
+}
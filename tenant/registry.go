@@ -0,0 +1,123 @@
+package tenant
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/michaelyusak/go-auth/constant"
+	"github.com/michaelyusak/go-auth/helper"
+)
+
+// Settings are the parts of the service configuration a tenant can
+// override.
+type Settings struct {
+	PasswordPolicy       helper.PasswordPolicy
+	AccessTokenDuration  time.Duration
+	RefreshTokenDuration time.Duration
+	AllowedOrigins       []string
+}
+
+// Tenant is a tenant with its settings already merged over the service
+// wide ones, and the hosts and client ids its requests come with.
+type Tenant struct {
+	Id        string
+	Hosts     []string
+	ClientIds []string
+	Settings  Settings
+}
+
+type Registry interface {
+	// Resolve picks the tenant of a request from, in order, the tenant id
+	// it names, its client id and its host, falling back to the default
+	// tenant. It reports false when the request names a tenant id or client
+	// id that is not configured.
+	Resolve(tenantId, clientId, host string) (string, bool)
+
+	// Settings returns the settings of the tenant, or the default tenant's
+	// for an unknown one.
+	Settings(tenantId string) Settings
+}
+
+type registry struct {
+	settings   map[string]Settings
+	byHost     map[string]string
+	byClientId map[string]string
+}
+
+// NewRegistry indexes the tenants by host and client id. The default
+// tenant always exists and uses the defaults unless it is listed with its
+// own settings. A host or client id may only belong to one tenant.
+func NewRegistry(defaults Settings, tenants []Tenant) (*registry, error) {
+	r := &registry{
+		settings:   map[string]Settings{constant.DefaultTenantId: defaults},
+		byHost:     map[string]string{},
+		byClientId: map[string]string{},
+	}
+
+	seen := map[string]bool{}
+
+	for _, t := range tenants {
+		if t.Id == "" {
+			return nil, fmt.Errorf("tenant without an id")
+		}
+
+		if seen[t.Id] {
+			return nil, fmt.Errorf("tenant %q listed twice", t.Id)
+		}
+		seen[t.Id] = true
+
+		r.settings[t.Id] = t.Settings
+
+		for _, host := range t.Hosts {
+			host = strings.ToLower(host)
+			if owner, ok := r.byHost[host]; ok {
+				return nil, fmt.Errorf("host %q belongs to both tenant %q and %q", host, owner, t.Id)
+			}
+
+			r.byHost[host] = t.Id
+		}
+
+		for _, clientId := range t.ClientIds {
+			if owner, ok := r.byClientId[clientId]; ok {
+				return nil, fmt.Errorf("client id %q belongs to both tenant %q and %q", clientId, owner, t.Id)
+			}
+
+			r.byClientId[clientId] = t.Id
+		}
+	}
+
+	return r, nil
+}
+
+func (r *registry) Resolve(tenantId, clientId, host string) (string, bool) {
+	if tenantId != "" {
+		_, ok := r.settings[tenantId]
+		return tenantId, ok
+	}
+
+	if clientId != "" {
+		tenantId, ok := r.byClientId[clientId]
+		return tenantId, ok
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	if tenantId, ok := r.byHost[strings.ToLower(host)]; ok {
+		return tenantId, true
+	}
+
+	return constant.DefaultTenantId, true
+}
+
+func (r *registry) Settings(tenantId string) Settings {
+	settings, ok := r.settings[tenantId]
+	if !ok {
+		return r.settings[constant.DefaultTenantId]
+	}
+
+	return settings
+}